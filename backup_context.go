@@ -11,6 +11,8 @@ func NewBackupContext(targetDir string, env map[string]string, cryptKey string)
 	backupContext = BackupContext{
 		TargetDir: targetDir,
 	}
+	RedactSecrets(env[SecretAccessKeyVarname], cryptKey)
+
 	if useS3(env) {
 		backupContext.StorageProvider = NewS3Provider(env[S3Domain], env[AccessKeyIDVarname], env[SecretAccessKeyVarname], env[BucketNameVarname])
 		backupContext.IsS3 = true
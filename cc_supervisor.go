@@ -0,0 +1,154 @@
+package cfbackup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/xchapter7x/lo"
+)
+
+//NewCCSupervisor - creates the supervisor which stops and restarts the toggler's cloud controller jobs
+func NewCCSupervisor(toggler CCToggler) *CCSupervisor {
+	return &CCSupervisor{
+		Toggler:    toggler,
+		Retries:    DefaultCCRestartRetries,
+		RetryDelay: CCRestartRetryDelay,
+		Signals:    SupervisedSignals,
+	}
+}
+
+//Stop - stops the cloud controller jobs, up to Concurrency at a time, recording each one bosh accepted a stop for,
+//and restarts them if the process is signalled. No further jobs are stopped once one fails, the ones stopped so far
+//are left for Restart
+func (s *CCSupervisor) Stop(ctx context.Context) error {
+	s.watchSignals()
+
+	return firstJobError(ToggleJobs(s.Toggler.Jobs(), s.Concurrency, true, func(ccjob CCJob) error {
+		if s.isRestarting() {
+			return ErrCCStopAbandoned
+		}
+		issued, err := s.Toggler.ToggleJobWithContext(ctx, ccjob, CCJobStopped)
+
+		if issued {
+			s.mutex.Lock()
+			s.stopped = append(s.stopped, ccjob)
+			restarting := s.restarting
+			s.mutex.Unlock()
+
+			if restarting {
+				s.Restart()
+			}
+		}
+		return err
+	}))
+}
+
+//Stopped - the cloud controller jobs stopped and not started again yet
+func (s *CCSupervisor) Stopped() CloudControllerJobs {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append(CloudControllerJobs{}, s.stopped...)
+}
+
+//Restart - starts the stopped cloud controller jobs again, up to Concurrency at a time, retrying each one which fails.
+//Jobs which still fail are logged as critical and stay recorded, so calling it again retries them. Safe for concurrent use
+func (s *CCSupervisor) Restart() (err error) {
+	var failed CloudControllerJobs
+
+	if s == nil {
+		return
+	}
+	s.restart.Lock()
+	defer s.restart.Unlock()
+	s.mutex.Lock()
+	s.restarting = true
+	stopped := s.stopped
+	s.stopped = nil
+	s.mutex.Unlock()
+
+	errs := ToggleJobs(stopped, s.Concurrency, false, s.start)
+
+	for i, startErr := range errs {
+		if startErr != nil {
+			lo.G.Error(fmt.Sprintf("failed to start cloud controller job %s", stopped[i:i+1]), startErr)
+			failed = append(failed, stopped[i])
+		}
+	}
+
+	if len(failed) > 0 {
+		s.mutex.Lock()
+		s.stopped = append(failed, s.stopped...)
+		s.mutex.Unlock()
+		err = fmt.Errorf("%w: %s", ErrCCRestartFailed, failed)
+		lo.G.Critical(fmt.Sprintf("%s, start them with bosh before the foundation can serve requests again", err))
+	}
+	s.unwatchSignals()
+	return
+}
+
+//RestartOnPanic - deferred by goroutines of a run, restarts the stopped cloud controller jobs before passing a panic on
+func (s *CCSupervisor) RestartOnPanic() {
+	if r := recover(); r != nil {
+		lo.G.Error("run panicked, restarting the cloud controllers", r)
+		s.Restart()
+		panic(r)
+	}
+}
+
+func (s *CCSupervisor) start(ccjob CCJob) (err error) {
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(s.RetryDelay)
+		}
+		startCtx, cancel := CleanupContext(s.Timeout)
+		_, err = s.Toggler.ToggleJobWithContext(startCtx, ccjob, CCJobStarted)
+		cancel()
+
+		if err == nil {
+			return
+		}
+	}
+	return
+}
+
+func (s *CCSupervisor) isRestarting() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.restarting
+}
+
+func (s *CCSupervisor) watchSignals() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.signals != nil || len(s.Signals) == 0 {
+		return
+	}
+	s.signals = make(chan os.Signal, 1)
+	s.done = make(chan struct{})
+	signal.Notify(s.signals, s.Signals...)
+
+	go func(signals chan os.Signal, done chan struct{}) {
+		select {
+		case sig := <-signals:
+			lo.G.Error(fmt.Sprintf("received %s, restarting the cloud controllers before exiting", sig))
+			s.Restart()
+			ReraiseSignal(sig)
+		case <-done:
+		}
+	}(s.signals, s.done)
+}
+
+func (s *CCSupervisor) unwatchSignals() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.signals != nil {
+		signal.Stop(s.signals)
+		close(s.done)
+		s.signals = nil
+	}
+}
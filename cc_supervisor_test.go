@@ -0,0 +1,169 @@
+package cfbackup_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotalservices/cfbackup"
+)
+
+type mockToggler struct {
+	jobs       CloudControllerJobs
+	rejected   map[string]bool
+	unfinished map[string]bool
+	startFails map[string]int
+	toggled    []string
+	mutex      sync.Mutex
+}
+
+func (s *mockToggler) Jobs() CloudControllerJobs {
+	return s.jobs
+}
+
+func (s *mockToggler) ToggleJobWithContext(ctx context.Context, ccjob CCJob, state string) (issued bool, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if state == CCJobStopped && s.rejected[ccjob.Job] {
+		return false, errors.New("bosh rejected the task")
+	}
+	s.toggled = append(s.toggled, fmt.Sprintf("%s %s", state, ccjob.Job))
+
+	if state == CCJobStopped && s.unfinished[ccjob.Job] {
+		return true, errors.New("task did not finish")
+	}
+
+	if state == CCJobStarted && s.startFails[ccjob.Job] > 0 {
+		s.startFails[ccjob.Job]--
+		return true, errors.New("task failed")
+	}
+	return true, nil
+}
+
+func (s *mockToggler) Toggled() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.toggled...)
+}
+
+var _ = Describe("CCSupervisor", func() {
+	var (
+		toggler    *mockToggler
+		supervisor *CCSupervisor
+	)
+
+	BeforeEach(func() {
+		toggler = &mockToggler{
+			jobs:       CloudControllerJobs{{Job: "cloud_controller", Index: 0}, {Job: "cloud_controller_worker", Index: 0}, {Job: "clock_global", Index: 0}},
+			rejected:   make(map[string]bool),
+			unfinished: make(map[string]bool),
+			startFails: make(map[string]int),
+		}
+		supervisor = NewCCSupervisor(toggler)
+		supervisor.RetryDelay = 0
+		supervisor.Signals = nil
+	})
+
+	Context("when a stop fails partway through the job list", func() {
+		BeforeEach(func() {
+			toggler.rejected["clock_global"] = true
+			toggler.unfinished["cloud_controller_worker"] = true
+		})
+
+		It("then it should restart exactly the jobs bosh accepted a stop for", func() {
+			Ω(supervisor.Stop(context.Background())).Should(MatchError("task did not finish"))
+			Ω(supervisor.Stopped().String()).Should(Equal("cloud_controller/0, cloud_controller_worker/0"))
+			Ω(supervisor.Restart()).Should(Succeed())
+			Ω(toggler.Toggled()).Should(Equal([]string{
+				"stopped cloud_controller",
+				"stopped cloud_controller_worker",
+				"started cloud_controller",
+				"started cloud_controller_worker",
+			}))
+			Ω(supervisor.Stopped()).Should(BeEmpty())
+		})
+	})
+
+	Context("when a start fails", func() {
+		BeforeEach(func() {
+			Ω(supervisor.Stop(context.Background())).Should(Succeed())
+		})
+
+		It("then it should retry it", func() {
+			toggler.startFails["cloud_controller"] = DefaultCCRestartRetries
+			Ω(supervisor.Restart()).Should(Succeed())
+			Ω(supervisor.Stopped()).Should(BeEmpty())
+		})
+
+		It("then it should fail naming the jobs left stopped once it runs out of retries, and retry them when asked again", func() {
+			toggler.startFails["clock_global"] = 2 * (DefaultCCRestartRetries + 1)
+			Ω(supervisor.Restart()).Should(MatchError(ErrCCRestartFailed))
+			Ω(supervisor.Restart()).Should(MatchError(ContainSubstring("clock_global/0")))
+			Ω(supervisor.Stopped().String()).Should(Equal("clock_global/0"))
+			Ω(supervisor.Restart()).Should(Succeed())
+		})
+	})
+
+	Context("when jobs are toggled concurrently", func() {
+		BeforeEach(func() {
+			supervisor.Concurrency = 3
+		})
+
+		It("then it should stop and restart every job", func() {
+			Ω(supervisor.Stop(context.Background())).Should(Succeed())
+			Ω(supervisor.Stopped()).Should(ConsistOf(toggler.jobs))
+			Ω(supervisor.Restart()).Should(Succeed())
+			Ω(toggler.Toggled()).Should(ConsistOf(
+				"stopped cloud_controller", "stopped cloud_controller_worker", "stopped clock_global",
+				"started cloud_controller", "started cloud_controller_worker", "started clock_global",
+			))
+			Ω(supervisor.Stopped()).Should(BeEmpty())
+		})
+	})
+
+	Context("when the run panics", func() {
+		It("then it should restart the stopped jobs before passing the panic on", func() {
+			Ω(func() {
+				defer supervisor.RestartOnPanic()
+				supervisor.Stop(context.Background())
+				panic("dump failed")
+			}).Should(Panic())
+			Ω(supervisor.Stopped()).Should(BeEmpty())
+			Ω(toggler.Toggled()).Should(HaveLen(6))
+		})
+	})
+
+	Context("when the process is signalled while the jobs are stopped", func() {
+		var (
+			reraiseOrig = ReraiseSignal
+			reraised    chan os.Signal
+		)
+
+		BeforeEach(func() {
+			reraised = make(chan os.Signal, 1)
+			ReraiseSignal = func(sig os.Signal) {
+				reraised <- sig
+			}
+			supervisor.Signals = []os.Signal{syscall.SIGUSR1}
+		})
+
+		AfterEach(func() {
+			ReraiseSignal = reraiseOrig
+		})
+
+		It("then it should restart them and deliver the signal again", func() {
+			Ω(supervisor.Stop(context.Background())).Should(Succeed())
+			syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+			Eventually(reraised).Should(Receive(Equal(syscall.SIGUSR1)))
+			Ω(supervisor.Stopped()).Should(BeEmpty())
+			Ω(toggler.Toggled()).Should(HaveLen(6))
+			Ω(supervisor.Stop(context.Background())).Should(MatchError(ErrCCStopAbandoned))
+		})
+	})
+})
@@ -0,0 +1,99 @@
+package cfbackup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+//NewCheckpoint - starts the checkpoint of a backup run
+func NewCheckpoint() *Checkpoint {
+	return &Checkpoint{
+		Started:    time.Now(),
+		Components: make(map[string]*ComponentCheckpoint),
+	}
+}
+
+//ReadCheckpoint - reads the checkpoint kept in the backup set
+func ReadCheckpoint(storageProvider StorageProvider, filepath ...string) (checkpoint *Checkpoint, err error) {
+	var reader io.ReadCloser
+
+	if reader, err = storageProvider.Reader(filepath...); err == nil {
+		defer reader.Close()
+		checkpoint = NewCheckpoint()
+		err = json.NewDecoder(reader).Decode(checkpoint)
+	}
+	return
+}
+
+//Complete - records a component whose artifact was written successfully, safe for concurrent use
+func (s *Checkpoint) Complete(component *ComponentReport) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Components[component.Name] = &ComponentCheckpoint{
+		Artifact:  component.Artifact,
+		Bytes:     component.Bytes,
+		SHA256:    component.SHA256,
+		Completed: component.Finished,
+	}
+}
+
+//Completed - the checkpoint of the given component, if the run completed it
+func (s *Checkpoint) Completed(name string) (component *ComponentCheckpoint, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	component, ok = s.Components[name]
+	return
+}
+
+//Write - stores the checkpoint as json in the backup set, safe for concurrent use
+func (s *Checkpoint) Write(storageProvider StorageProvider, filepath ...string) (err error) {
+	var writer io.WriteCloser
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if writer, err = storageProvider.Writer(filepath...); err == nil {
+		defer writer.Close()
+		err = json.NewEncoder(writer).Encode(s)
+	}
+	return
+}
+
+//Verify - checks the checkpointed artifact is still in the backup set exactly as it was written
+func (s *ComponentCheckpoint) Verify(storageProvider StorageProvider) (err error) {
+	var (
+		reader io.ReadCloser
+		size   int64
+	)
+
+	if reader, err = storageProvider.Reader(s.Artifact); err == nil {
+		defer reader.Close()
+		hash := sha256.New()
+
+		if size, err = io.Copy(hash, reader); err == nil && (size != s.Bytes || hex.EncodeToString(hash.Sum(nil)) != s.SHA256) {
+			err = ErrCheckpointMismatch
+		}
+	}
+	return
+}
+
+//ReuseComponent - adds a component whose artifact was reused from the checkpoint of an earlier run
+func (s *RunReport) ReuseComponent(name string, checkpoint *ComponentCheckpoint) *ComponentReport {
+	component := &ComponentReport{
+		Name:     name,
+		Status:   RunStatusSucceeded,
+		Artifact: checkpoint.Artifact,
+		Started:  checkpoint.Completed,
+		Finished: checkpoint.Completed,
+		Bytes:    checkpoint.Bytes,
+		SHA256:   checkpoint.SHA256,
+		Reused:   true,
+	}
+
+	if s != nil {
+		s.Components = append(s.Components, component)
+	}
+	return component
+}
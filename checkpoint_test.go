@@ -0,0 +1,85 @@
+package cfbackup_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	cfenv "github.com/cloudfoundry-community/go-cfenv"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotalservices/cfbackup"
+)
+
+var _ = Describe("Checkpoint", func() {
+	var (
+		target      string
+		backupSet   BackupContext
+		checkpoint  *Checkpoint
+		artifact    string
+		writeString = func(filepath string, contents string) {
+			writer, _ := backupSet.Writer(filepath)
+			writer.Write([]byte(contents))
+			writer.Close()
+		}
+	)
+
+	BeforeEach(func() {
+		target, _ = ioutil.TempDir("", "checkpoint")
+		backupSet = NewBackupContext(target, cfenv.CurrentEnv(), "")
+		artifact = path.Join(target, "ccdb.backup")
+		writeString(artifact, "sometext")
+		component := NewRunReport("elastic-runtime", HookActionBackup).StartComponent("ccdb", artifact)
+		reader, _ := backupSet.Reader(artifact)
+		ioutil.ReadAll(component.Reader(reader))
+		reader.Close()
+		component.Finish(nil)
+		checkpoint = NewCheckpoint()
+		checkpoint.Complete(component)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(target)
+	})
+
+	Context("when it is written to the backup set", func() {
+		It("then it should read back the completed components", func() {
+			Ω(checkpoint.Write(backupSet, target, CheckpointFileName)).Should(Succeed())
+			stored, err := ReadCheckpoint(backupSet, target, CheckpointFileName)
+			Ω(err).ShouldNot(HaveOccurred())
+			completed, ok := stored.Completed("ccdb")
+			Ω(ok).Should(BeTrue())
+			Ω(completed.Bytes).Should(Equal(int64(len("sometext"))))
+			_, ok = stored.Completed("uaadb")
+			Ω(ok).Should(BeFalse())
+		})
+	})
+
+	Context("when the checkpointed artifact is unchanged", func() {
+		It("then it should verify", func() {
+			completed, _ := checkpoint.Completed("ccdb")
+			Ω(completed.Verify(backupSet)).Should(Succeed())
+		})
+	})
+
+	Context("when the checkpointed artifact changed", func() {
+		It("then it should fail verification", func() {
+			writeString(artifact, strings.ToUpper("sometext"))
+			completed, _ := checkpoint.Completed("ccdb")
+			Ω(completed.Verify(backupSet)).Should(Equal(ErrCheckpointMismatch))
+		})
+	})
+
+	Context("when a reused component is added to a run report", func() {
+		It("then it should be reported as succeeded and reused", func() {
+			report := NewRunReport("elastic-runtime", HookActionBackup)
+			completed, _ := checkpoint.Completed("ccdb")
+			component := report.ReuseComponent("ccdb", completed)
+			Ω(component.Status).Should(Equal(RunStatusSucceeded))
+			Ω(component.Reused).Should(BeTrue())
+			Ω(component.SHA256).Should(Equal(completed.SHA256))
+			Ω(report.Components).Should(ConsistOf(component))
+		})
+	})
+})
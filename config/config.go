@@ -0,0 +1,383 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	"gopkg.in/yaml.v1"
+)
+
+var cronField = regexp.MustCompile(`^(\*|[0-9A-Za-z]+(-[0-9A-Za-z]+)?)(/[0-9]+)?$`)
+
+//Load - reads a Config from the yaml or json file at the given path
+func Load(configPath string) (config *Config, err error) {
+	var configFile *os.File
+
+	if configFile, err = os.Open(configPath); err == nil {
+		defer configFile.Close()
+		config, err = Parse(configFile)
+	}
+	return
+}
+
+//Parse - reads a Config from the given yaml or json, rejecting unknown keys, interpolating environment
+//variables into its values and validating the result
+func Parse(configYaml io.Reader) (config *Config, err error) {
+	var (
+		contents []byte
+		node     interface{}
+	)
+
+	if contents, err = ioutil.ReadAll(configYaml); err != nil {
+		return
+	}
+
+	if err = yaml.Unmarshal(contents, &node); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+	}
+
+	if errs := unknownKeys("", node, reflect.TypeOf(Config{})); len(errs) > 0 {
+		return nil, invalid(errs)
+	}
+
+	if node, errs := interpolate("", node, reflect.TypeOf(Config{})); len(errs) > 0 {
+		return nil, invalid(errs)
+
+	} else if contents, err = yaml.Marshal(node); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+	}
+	config = new(Config)
+
+	if err = yaml.Unmarshal(contents, config); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+	}
+
+	if err = config.Validate(); err != nil {
+		config = nil
+	}
+	return
+}
+
+//Validate - checks the config has everything required to back up each of its foundations, reporting every problem found
+func (s *Config) Validate() error {
+	var errs []string
+
+	if s.Version != SupportedVersion {
+		errs = append(errs, fmt.Sprintf("version: %d is not supported, expected %d", s.Version, SupportedVersion))
+	}
+	errs = append(errs, validateEncryption("encryption", s.Encryption)...)
+
+	for _, name := range s.storageNames() {
+		errs = append(errs, validateStorage(joinPath("storage", name), s.Storage[name])...)
+	}
+
+	if len(s.Foundations) == 0 {
+		errs = append(errs, "foundations: at least one foundation is required")
+	}
+
+	for _, name := range s.FoundationNames() {
+		errs = append(errs, s.validateFoundation(joinPath("foundations", name), s.Foundations[name])...)
+	}
+	return invalid(errs)
+}
+
+//FoundationNames - the names of the config's foundations, sorted
+func (s *Config) FoundationNames() (names []string) {
+	for name := range s.Foundations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+//TileSpec - the ready to use tile spec for the named foundation, writing to its storage
+func (s *Config) TileSpec(name string) (tileSpec tileregistry.TileSpec, err error) {
+	var (
+		foundation    Foundation
+		backupContext cfbackup.BackupContext
+	)
+
+	if foundation, _, err = s.foundation(name); err != nil {
+		return
+	}
+
+	if backupContext, err = s.BackupContext(name); err != nil {
+		return
+	}
+	auth := foundation.OpsManager.Auth
+	tileSpec = tileregistry.TileSpec{
+		OpsManagerHost:       foundation.OpsManager.Host,
+		OpsManagerUser:       foundation.OpsManager.SSH.Username,
+		OpsManagerPass:       foundation.OpsManager.SSH.Password,
+		OpsManagerSSHKey:     foundation.OpsManager.SSH.PrivateKey,
+		OpsManagerSSHPort:    foundation.OpsManager.SSH.Port,
+		OpsManagerPassphrase: auth.Passphrase,
+		ArchiveDirectory:     backupContext.TargetDir,
+		CryptKey:             s.encryption(foundation).Key,
+		ClearBoshManifest:    foundation.ClearBoshManifest,
+		NFS:                  foundation.NFS,
+		MergeOnRestore:       foundation.MergeOnRestore,
+		Concurrency:          foundation.Concurrency,
+		CCToggleConcurrency:  foundation.CCToggleConcurrency,
+		CCJobPatterns:        foundation.CCJobPatterns,
+		Online:               foundation.Online,
+		TLS:                  cfbackup.TLSConfig(foundation.TLS),
+		IncludeComponents:    foundation.Components.Include,
+		ExcludeComponents:    foundation.Components.Exclude,
+		BackupContext:        &backupContext,
+	}
+
+	switch auth.Method {
+	case AuthMethodBasic:
+		tileSpec.AdminUser = auth.Username
+		tileSpec.AdminPass = auth.Password
+	case AuthMethodToken:
+		tileSpec.AdminToken = auth.Token
+	case AuthMethodClientCredentials:
+		tileSpec.ClientID = auth.ClientID
+		tileSpec.ClientSecret = auth.ClientSecret
+	}
+	return
+}
+
+//BackupContext - the backup context of the named foundation, targeting its archive directory in its storage
+func (s *Config) BackupContext(name string) (backupContext cfbackup.BackupContext, err error) {
+	var (
+		foundation     Foundation
+		storage        Storage
+		archiveDirName = name
+	)
+
+	if foundation, storage, err = s.foundation(name); err != nil {
+		return
+	}
+
+	if foundation.ArchiveDirectory != "" {
+		archiveDirName = foundation.ArchiveDirectory
+	}
+	backupContext = cfbackup.BackupContext{
+		TargetDir: path.Join(storage.Path, archiveDirName),
+		IsS3:      storage.Type == StorageTypeS3,
+	}
+	backupContext.StorageProvider, err = s.StorageProvider(name)
+	return
+}
+
+//StorageProvider - the storage provider the named foundation writes its archives to, encrypted when it has a key.
+//Secret references in its credentials and key are resolved here
+func (s *Config) StorageProvider(name string) (storageProvider cfbackup.StorageProvider, err error) {
+	var (
+		foundation Foundation
+		storage    Storage
+	)
+
+	if foundation, storage, err = s.foundation(name); err != nil {
+		return
+	}
+
+	switch storage.Type {
+	case StorageTypeS3:
+		var accessKeyID, secretAccessKey string
+
+		if accessKeyID, err = tileregistry.ResolveSecret(storage.AccessKeyID); err != nil {
+			return
+		}
+
+		if secretAccessKey, err = tileregistry.ResolveSecret(storage.SecretAccessKey); err != nil {
+			return
+		}
+		cfbackup.RedactSecrets(secretAccessKey)
+		storageProvider = cfbackup.NewS3Provider(storage.Domain, accessKeyID, secretAccessKey, storage.Bucket)
+	default:
+		storageProvider = cfbackup.NewDiskProvider()
+	}
+
+	if key := s.encryption(foundation).Key; key != "" {
+		if key, err = tileregistry.ResolveSecret(key); err != nil {
+			return
+		}
+		cfbackup.RedactSecrets(key)
+
+		if errs := validateEncryption("encryption", Encryption{Key: key}); len(errs) > 0 {
+			return nil, invalid(errs)
+		}
+		storageProvider, err = cfbackup.NewEncryptedStorageProvider(storageProvider, key)
+	}
+	return
+}
+
+func (s *Config) foundation(name string) (foundation Foundation, storage Storage, err error) {
+	var ok bool
+
+	if foundation, ok = s.Foundations[name]; !ok {
+		err = fmt.Errorf("%w: %s", ErrUnknownFoundation, name)
+
+	} else if storage, ok = s.Storage[foundation.Storage]; !ok {
+		err = fmt.Errorf("%w: %s", ErrUnknownStorage, foundation.Storage)
+	}
+	return
+}
+
+func (s *Config) encryption(foundation Foundation) Encryption {
+	if foundation.Encryption != nil {
+		return *foundation.Encryption
+	}
+	return s.Encryption
+}
+
+func (s *Config) storageNames() (names []string) {
+	for name := range s.Storage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+func (s *Config) validateFoundation(path string, foundation Foundation) (errs []string) {
+	required := func(key, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Sprintf("%s: is required", joinPath(path, key)))
+		}
+	}
+	required("ops_manager.host", foundation.OpsManager.Host)
+	required("ops_manager.ssh.username", foundation.OpsManager.SSH.Username)
+
+	if ssh := foundation.OpsManager.SSH; ssh.Password == "" && ssh.PrivateKey == "" {
+		errs = append(errs, fmt.Sprintf("%s: a password or private_key is required", joinPath(path, "ops_manager.ssh")))
+	}
+
+	if port := foundation.OpsManager.SSH.Port; port < 0 || port > 65535 {
+		errs = append(errs, fmt.Sprintf("%s: must be a tcp port, is %d", joinPath(path, "ops_manager.ssh.port"), port))
+	}
+	required("storage", foundation.Storage)
+
+	switch auth := foundation.OpsManager.Auth; auth.Method {
+	case AuthMethodBasic:
+		required("ops_manager.auth.username", auth.Username)
+		required("ops_manager.auth.password", auth.Password)
+	case AuthMethodToken:
+		required("ops_manager.auth.token", auth.Token)
+	case AuthMethodClientCredentials:
+		required("ops_manager.auth.client_id", auth.ClientID)
+		required("ops_manager.auth.client_secret", auth.ClientSecret)
+	default:
+		errs = append(errs, fmt.Sprintf("%s: must be one of %s, %s or %s, is %q", joinPath(path, "ops_manager.auth.method"),
+			AuthMethodBasic, AuthMethodToken, AuthMethodClientCredentials, auth.Method))
+	}
+
+	if _, ok := s.Storage[foundation.Storage]; foundation.Storage != "" && !ok {
+		errs = append(errs, fmt.Sprintf("%s: no storage named %q", joinPath(path, "storage"), foundation.Storage))
+	}
+
+	if foundation.Encryption != nil {
+		errs = append(errs, validateEncryption(joinPath(path, "encryption"), *foundation.Encryption)...)
+	}
+
+	if !contains(NFSModes, foundation.NFS) {
+		errs = append(errs, fmt.Sprintf("%s: must be one of full, lite or bp, is %q", joinPath(path, "nfs"), foundation.NFS))
+	}
+
+	for _, component := range foundation.Components.Include {
+		if contains(foundation.Components.Exclude, component) {
+			errs = append(errs, fmt.Sprintf("%s: %s is both included and excluded", joinPath(path, "components"), component))
+		}
+	}
+
+	if foundation.Schedule != "" && !validSchedule(foundation.Schedule) {
+		errs = append(errs, fmt.Sprintf("%s: %q is neither 5 cron fields nor a descriptor like @daily or @every 6h", joinPath(path, "schedule"), foundation.Schedule))
+	}
+
+	if foundation.Concurrency < 0 {
+		errs = append(errs, fmt.Sprintf("%s: must not be negative, is %d", joinPath(path, "concurrency"), foundation.Concurrency))
+	}
+
+	if foundation.CCToggleConcurrency < 0 {
+		errs = append(errs, fmt.Sprintf("%s: must not be negative, is %d", joinPath(path, "cc_toggle_concurrency"), foundation.CCToggleConcurrency))
+	}
+
+	if _, err := cfbackup.MatchCCJob(foundation.CCJobPatterns, ""); err != nil {
+		errs = append(errs, fmt.Sprintf("%s: %s", joinPath(path, "cc_job_patterns"), err))
+	}
+
+	if err := cfbackup.TLSConfig(foundation.TLS).Validate(); err != nil {
+		errs = append(errs, fmt.Sprintf("%s: %s", joinPath(path, "tls"), err))
+	}
+	return
+}
+
+func validateStorage(path string, storage Storage) (errs []string) {
+	switch storage.Type {
+	case StorageTypeDisk:
+		if storage.Path == "" {
+			errs = append(errs, fmt.Sprintf("%s: is required", joinPath(path, "path")))
+		}
+	case StorageTypeS3:
+		for key, value := range map[string]string{"access_key_id": storage.AccessKeyID, "secret_access_key": storage.SecretAccessKey, "bucket": storage.Bucket} {
+			if value == "" {
+				errs = append(errs, fmt.Sprintf("%s: is required", joinPath(path, key)))
+			}
+		}
+		sort.Strings(errs)
+	default:
+		errs = append(errs, fmt.Sprintf("%s: must be %s or %s, is %q", joinPath(path, "type"), StorageTypeDisk, StorageTypeS3, storage.Type))
+	}
+	return
+}
+
+func validateEncryption(path string, encryption Encryption) (errs []string) {
+	if l := len(encryption.Key); l != 0 && l != 16 && l != 24 && l != 32 && !tileregistry.IsSecretReference(encryption.Key) {
+		errs = append(errs, fmt.Sprintf("%s: must be 16, 24 or 32 bytes long, is %d", joinPath(path, "key"), l))
+	}
+	return
+}
+
+func validSchedule(schedule string) bool {
+	if contains(ScheduleDescriptors, schedule) {
+		return true
+	}
+
+	if strings.HasPrefix(schedule, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimPrefix(schedule, "@every "))
+		return err == nil && interval > 0
+	}
+	fields := strings.Fields(schedule)
+
+	if len(fields) != 5 {
+		return false
+	}
+
+	for _, field := range fields {
+		for _, item := range strings.Split(field, ",") {
+			if !cronField.MatchString(item) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func invalid(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w:\n  %s", ErrInvalidConfig, strings.Join(errs, "\n  "))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
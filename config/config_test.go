@@ -0,0 +1,261 @@
+package config_test
+
+import (
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
+	. "github.com/pivotalservices/cfbackup/config"
+)
+
+var _ = Describe("Config", func() {
+	var (
+		env        map[string]string
+		lookupOrig = LookupEnv
+	)
+
+	BeforeEach(func() {
+		env = map[string]string{
+			"AWS_ACCESS_KEY_ID":     "key-id",
+			"AWS_SECRET_ACCESS_KEY": "secret",
+			"OPSMAN_ADMIN_PASSWORD": "admin-pass",
+			"OPSMAN_PASSPHRASE":     "passphrase",
+			"OPSMAN_SSH_PASSWORD":   "ssh-pass",
+		}
+		LookupEnv = func(name string) (value string, ok bool) {
+			value, ok = env[name]
+			return
+		}
+	})
+
+	AfterEach(func() {
+		LookupEnv = lookupOrig
+	})
+
+	Describe("given a Load() method", func() {
+		Context("when called with a valid config file", func() {
+			var config *Config
+
+			BeforeEach(func() {
+				var err error
+				config, err = Load("../fixtures/cfbackup-config.yml")
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			It("then it should interpolate the environment and defaults", func() {
+				Ω(config.Foundations["prod"].OpsManager.Auth.Password).Should(Equal("admin-pass"))
+				Ω(config.Storage["offsite"].SecretAccessKey).Should(Equal("secret"))
+				Ω(config.Encryption.Key).Should(Equal("0123456789abcdef"))
+				Ω(config.Foundations["dev"].OpsManager.Auth.ClientSecret).Should(Equal("pa$word"))
+			})
+
+			It("then it should list its foundations", func() {
+				Ω(config.FoundationNames()).Should(Equal([]string{"dev", "prod"}))
+			})
+
+			It("then it should build a tile spec for a foundation", func() {
+				tileSpec, err := config.TileSpec("prod")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(tileSpec.OpsManagerHost).Should(Equal("opsman.prod.example.com"))
+				Ω(tileSpec.AdminUser).Should(Equal("admin"))
+				Ω(tileSpec.AdminPass).Should(Equal("admin-pass"))
+				Ω(tileSpec.OpsManagerPassphrase).Should(Equal("passphrase"))
+				Ω(tileSpec.OpsManagerUser).Should(Equal("ubuntu"))
+				Ω(tileSpec.OpsManagerPass).Should(Equal("ssh-pass"))
+				Ω(tileSpec.ArchiveDirectory).Should(Equal("foundations/prod"))
+				Ω(tileSpec.CryptKey).Should(Equal("0123456789abcdef"))
+				Ω(tileSpec.NFS).Should(Equal("lite"))
+				Ω(tileSpec.ExcludeComponents).Should(Equal([]string{"uaadb"}))
+				Ω(tileSpec.Concurrency).Should(Equal(2))
+				Ω(tileSpec.CCToggleConcurrency).Should(Equal(4))
+				Ω(tileSpec.CCJobPatterns).Should(Equal([]string{"cloud_controller", "cloud_controller_worker", "clock_global"}))
+				Ω(tileSpec.Online).Should(BeTrue())
+				Ω(tileSpec.TLS.Pins).Should(Equal([]string{"sha256/DqQqbSLchnHAQ8btTtuPSYk1t8rN/z6ZfTcjAnhJ854="}))
+				Ω(tileSpec.TLS.Insecure).Should(BeFalse())
+				Ω(tileSpec.BackupContext.IsS3).Should(BeTrue())
+				Ω(tileSpec.BackupContext.TargetDir).Should(Equal("foundations/prod"))
+				Ω(tileSpec.BackupContext.StorageProvider).Should(BeAssignableToTypeOf(&cfbackup.EncryptedStorageProvider{}))
+			})
+
+			It("then it should map client credentials and foundation overrides", func() {
+				tileSpec, err := config.TileSpec("dev")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(tileSpec.AdminUser).Should(BeEmpty())
+				Ω(tileSpec.ClientID).Should(Equal("backup"))
+				Ω(tileSpec.OpsManagerUser).Should(Equal("ubuntu"))
+				Ω(tileSpec.OpsManagerPass).Should(BeEmpty())
+				Ω(tileSpec.OpsManagerSSHKey).Should(Equal("/var/vcap/keys/opsman.pem"))
+				Ω(tileSpec.OpsManagerSSHPort).Should(Equal(2222))
+				Ω(tileSpec.ArchiveDirectory).Should(Equal("/var/backups/cf/development"))
+				Ω(tileSpec.CryptKey).Should(BeEmpty())
+				Ω(tileSpec.TLS.Insecure).Should(BeTrue())
+				Ω(tileSpec.BackupContext.IsS3).Should(BeFalse())
+				Ω(tileSpec.BackupContext.StorageProvider).Should(Equal(cfbackup.NewDiskProvider()))
+			})
+
+			It("then it should fail for a foundation it does not have", func() {
+				_, err := config.TileSpec("staging")
+				Ω(err).Should(MatchError(ContainSubstring("staging")))
+				_, err = config.StorageProvider("staging")
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+
+		Context("when environment variables set values which are not strings", func() {
+			It("then it should read them as their field's type", func() {
+				env["BACKUP_CONCURRENCY"] = "8"
+				env["BACKUP_ONLINE"] = "false"
+				env["OPSMAN_SSH_PORT"] = "22"
+				config, err := Load("../fixtures/cfbackup-config.yml")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(config.Foundations["prod"].Concurrency).Should(Equal(8))
+				Ω(config.Foundations["prod"].Online).Should(BeFalse())
+				Ω(config.Foundations["dev"].OpsManager.SSH.Port).Should(Equal(22))
+			})
+
+			It("then it should name the field a value does not fit", func() {
+				env["BACKUP_CONCURRENCY"] = "lots"
+				_, err := Load("../fixtures/cfbackup-config.yml")
+				Ω(err).Should(MatchError(ContainSubstring(`foundations.prod.concurrency: "lots" is not a valid int`)))
+			})
+
+			It("then it should keep string fields strings", func() {
+				env["OPSMAN_SSH_PASSWORD"] = "12345"
+				config, err := Load("../fixtures/cfbackup-config.yml")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(config.Foundations["prod"].OpsManager.SSH.Password).Should(Equal("12345"))
+			})
+		})
+
+		Context("when an environment variable is not set", func() {
+			It("then it should name the variable and where it is used", func() {
+				delete(env, "OPSMAN_SSH_PASSWORD")
+				_, err := Load("../fixtures/cfbackup-config.yml")
+				Ω(err).Should(MatchError(ContainSubstring("foundations.prod.ops_manager.ssh.password: environment variable OPSMAN_SSH_PASSWORD is not set")))
+			})
+		})
+
+		Context("when called with a file which does not exist", func() {
+			It("then it should return an error", func() {
+				_, err := Load("../fixtures/does-not-exist.yml")
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("given a Parse() method", func() {
+		const valid = `{"version": 1,
+			"storage": {"local": {"type": "disk", "path": "/backups"}},
+			"foundations": {"prod": {
+				"ops_manager": {"host": "opsman", "auth": {"method": "token", "token": "abc"}, "ssh": {"username": "ubuntu", "password": "pass"}},
+				"storage": "local"}}}`
+
+		Context("when given json", func() {
+			It("then it should read it like yaml", func() {
+				config, err := Parse(strings.NewReader(valid))
+				Ω(err).ShouldNot(HaveOccurred())
+				tileSpec, err := config.TileSpec("prod")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(tileSpec.AdminToken).Should(Equal("abc"))
+				Ω(tileSpec.ArchiveDirectory).Should(Equal("/backups/prod"))
+			})
+		})
+
+		Context("when the encryption key references a secret", func() {
+			It("then it should resolve it when building the storage provider", func() {
+				os.Setenv("CFBACKUP_TEST_CRYPT_KEY", "0123456789abcdef")
+				defer os.Unsetenv("CFBACKUP_TEST_CRYPT_KEY")
+				config, err := Parse(strings.NewReader(strings.Replace(valid, `"version": 1,`, `"version": 1, "encryption": {"key": "env://CFBACKUP_TEST_CRYPT_KEY"},`, 1)))
+				Ω(err).ShouldNot(HaveOccurred())
+				storageProvider, err := config.StorageProvider("prod")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(storageProvider.(*cfbackup.EncryptedStorageProvider).EncryptionKey).Should(Equal("0123456789abcdef"))
+			})
+		})
+
+		Context("when the config has unknown keys", func() {
+			It("then it should name every one of them", func() {
+				_, err := Parse(strings.NewReader(strings.Replace(valid, `"storage": "local"`, `"storage": "local", "nfs_mode": "lite", "ops_manager_host": "x"`, 1)))
+				Ω(err).Should(MatchError(ErrInvalidConfig))
+				Ω(err.Error()).Should(ContainSubstring("foundations.prod.nfs_mode: unknown key"))
+				Ω(err.Error()).Should(ContainSubstring("foundations.prod.ops_manager_host: unknown key"))
+			})
+		})
+
+		Context("when the config is invalid", func() {
+			It("then it should report every problem by its path", func() {
+				_, err := Parse(strings.NewReader(`
+version: 2
+encryption:
+  key: short
+storage:
+  remote:
+    type: s3
+    bucket: backups
+foundations:
+  prod:
+    ops_manager:
+      auth:
+        method: basic
+        username: admin
+    storage: missing
+    nfs: partial
+    components:
+      include: [ccdb]
+      exclude: [ccdb]
+    schedule: "every night"
+    concurrency: -1
+    cc_toggle_concurrency: -2
+    cc_job_patterns: ["cloud_controller[", clock_global]
+    tls:
+      ops_manager_ca: /does/not/exist.pem
+`))
+				Ω(err).Should(MatchError(ErrInvalidConfig))
+				for _, problem := range []string{
+					"version: 2 is not supported, expected 1",
+					"encryption.key: must be 16, 24 or 32 bytes long, is 5",
+					"storage.remote.access_key_id: is required",
+					"storage.remote.secret_access_key: is required",
+					"foundations.prod.ops_manager.host: is required",
+					"foundations.prod.ops_manager.ssh.username: is required",
+					"foundations.prod.ops_manager.ssh: a password or private_key is required",
+					"foundations.prod.ops_manager.auth.password: is required",
+					`foundations.prod.storage: no storage named "missing"`,
+					`foundations.prod.nfs: must be one of full, lite or bp, is "partial"`,
+					"foundations.prod.components: ccdb is both included and excluded",
+					`foundations.prod.schedule: "every night" is neither 5 cron fields nor a descriptor`,
+					"foundations.prod.concurrency: must not be negative, is -1",
+					"foundations.prod.cc_toggle_concurrency: must not be negative, is -2",
+					`foundations.prod.cc_job_patterns: cc job pattern "cloud_controller[": syntax error in pattern`,
+					"foundations.prod.tls: invalid tls CA for ops_manager: open /does/not/exist.pem",
+				} {
+					Ω(err.Error()).Should(ContainSubstring(problem))
+				}
+			})
+
+			It("then it should require a foundation", func() {
+				_, err := Parse(strings.NewReader("version: 1\n"))
+				Ω(err).Should(MatchError(ContainSubstring("foundations: at least one foundation is required")))
+			})
+		})
+	})
+
+	Describe("given an Interpolate() function", func() {
+		It("then it should resolve variables, defaults and escapes", func() {
+			env["USER_NAME"] = "admin"
+			Ω(Interpolate("${USER_NAME}@${HOST:-localhost}:$$HOME")).Should(Equal("admin@localhost:$HOME"))
+		})
+
+		It("then it should prefer a set variable over its default", func() {
+			env["HOST"] = ""
+			Ω(Interpolate("${HOST:-localhost}")).Should(Equal(""))
+		})
+
+		It("then it should fail for variables which are not set", func() {
+			_, err := Interpolate("${MISSING}")
+			Ω(err).Should(MatchError("environment variable MISSING is not set"))
+		})
+	})
+})
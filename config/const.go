@@ -0,0 +1,33 @@
+package config
+
+import "errors"
+
+const (
+	//SupportedVersion - the config file format version this loader reads
+	SupportedVersion = 1
+	//StorageTypeDisk - archives are written to a local or mounted directory
+	StorageTypeDisk = "disk"
+	//StorageTypeS3 - archives are written to an s3 compatible bucket
+	StorageTypeS3 = "s3"
+	//AuthMethodBasic - ops manager admin username and password
+	AuthMethodBasic = "basic"
+	//AuthMethodToken - a uaa token for the ops manager admin
+	AuthMethodToken = "token"
+	//AuthMethodClientCredentials - a uaa client id and secret
+	AuthMethodClientCredentials = "client_credentials"
+	//InvalidConfigMsg - error message for a config file which fails validation
+	InvalidConfigMsg = "invalid config"
+)
+
+var (
+	//ErrInvalidConfig - error for a config file which fails validation
+	ErrInvalidConfig = errors.New(InvalidConfigMsg)
+	//ErrUnknownFoundation - error for a foundation name which is not in the config
+	ErrUnknownFoundation = errors.New("unknown foundation")
+	//ErrUnknownStorage - error for a storage name which is not in the config
+	ErrUnknownStorage = errors.New("unknown storage")
+	//NFSModes - the nfs modes a foundation can use, empty being the elastic runtime default
+	NFSModes = []string{"", "full", "lite", "bp"}
+	//ScheduleDescriptors - the cron descriptors a schedule can use in place of its 5 fields
+	ScheduleDescriptors = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+)
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v1"
+)
+
+var envReference = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+//LookupEnv - resolves the environment variables a config references
+var LookupEnv = os.LookupEnv
+
+//Interpolate - replaces ${VAR} with the variable's value and ${VAR:-default} with its value or else the default.
+//$$ is a literal $. A variable which is not set and has no default is an error
+func Interpolate(value string) (interpolated string, err error) {
+	var missing []string
+
+	interpolated = envReference.ReplaceAllStringFunc(value, func(reference string) string {
+		if reference == "$$" {
+			return "$"
+		}
+		match := envReference.FindStringSubmatch(reference)
+
+		if resolved, ok := LookupEnv(match[1]); ok {
+			return resolved
+		}
+
+		if match[2] != "" {
+			return match[3]
+		}
+		missing = append(missing, match[1])
+		return ""
+	})
+
+	if len(missing) > 0 {
+		err = fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return
+}
+
+//interpolate - interpolates the string scalars of the raw node tree. Scalars of non string fields, like concurrency
+//or online, are read as their field's type once interpolated, so they are not left quoted strings in the typed config
+func interpolate(path string, node interface{}, t reflect.Type) (interpolated interface{}, errs []string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	interpolated = node
+
+	switch node := node.(type) {
+	case string:
+		value, err := Interpolate(node)
+
+		if err != nil {
+			return node, []string{fmt.Sprintf("%s: %s", path, err)}
+		}
+		interpolated = value
+
+		if value != node && t.Kind() != reflect.String && t.Kind() != reflect.Interface {
+			typed := reflect.New(t)
+
+			if err = yaml.Unmarshal([]byte(value), typed.Interface()); err != nil {
+				return node, []string{fmt.Sprintf("%s: %q is not a valid %s", path, value, t.Kind())}
+			}
+			interpolated = typed.Elem().Interface()
+		}
+
+	case map[interface{}]interface{}:
+		fields := make(map[string]reflect.Type)
+
+		if t.Kind() == reflect.Struct {
+			for i := 0; i < t.NumField(); i++ {
+				fields[keyName(t.Field(i))] = t.Field(i).Type
+			}
+		}
+
+		for _, rawKey := range sortedKeys(reflect.ValueOf(node)) {
+			key := fmt.Sprint(rawKey.Interface())
+			elemType, ok := fields[key]
+
+			if t.Kind() == reflect.Map {
+				elemType, ok = t.Elem(), true
+			}
+
+			if ok {
+				var elemErrs []string
+				node[rawKey.Interface()], elemErrs = interpolate(joinPath(path, key), node[rawKey.Interface()], elemType)
+				errs = append(errs, elemErrs...)
+			}
+		}
+
+	case []interface{}:
+		if t.Kind() == reflect.Slice {
+			for i := range node {
+				var elemErrs []string
+				node[i], elemErrs = interpolate(fmt.Sprintf("%s[%d]", path, i), node[i], t.Elem())
+				errs = append(errs, elemErrs...)
+			}
+		}
+	}
+	return
+}
+
+func unknownKeys(path string, node interface{}, t reflect.Type) (errs []string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := make(map[string]reflect.Type)
+
+		for i := 0; i < t.NumField(); i++ {
+			fields[keyName(t.Field(i))] = t.Field(i).Type
+		}
+
+		if node, ok := stringKeyed(node); ok {
+			for _, key := range sortedNodeKeys(node) {
+				if field, ok := fields[key]; ok {
+					errs = append(errs, unknownKeys(joinPath(path, key), node[key], field)...)
+				} else {
+					errs = append(errs, fmt.Sprintf("%s: unknown key", joinPath(path, key)))
+				}
+			}
+		}
+
+	case reflect.Map:
+		if node, ok := stringKeyed(node); ok {
+			for _, key := range sortedNodeKeys(node) {
+				errs = append(errs, unknownKeys(joinPath(path, key), node[key], t.Elem())...)
+			}
+		}
+
+	case reflect.Slice:
+		if node, ok := node.([]interface{}); ok {
+			for i, elem := range node {
+				errs = append(errs, unknownKeys(fmt.Sprintf("%s[%d]", path, i), elem, t.Elem())...)
+			}
+		}
+	}
+	return
+}
+
+func keyName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("yaml"), ",")[0]; name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(value reflect.Value) (keys []reflect.Value) {
+	keys = value.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return
+}
+
+func sortedNodeKeys(node map[string]interface{}) (keys []string) {
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func stringKeyed(node interface{}) (keyed map[string]interface{}, ok bool) {
+	var mapping map[interface{}]interface{}
+
+	if mapping, ok = node.(map[interface{}]interface{}); ok {
+		keyed = make(map[string]interface{}, len(mapping))
+
+		for key, value := range mapping {
+			keyed[fmt.Sprint(key)] = value
+		}
+	}
+	return
+}
@@ -0,0 +1,86 @@
+package config
+
+type (
+	//Config - a versioned description of the foundations to back up and where their archives go
+	Config struct {
+		Version     int                   `yaml:"version"`
+		Storage     map[string]Storage    `yaml:"storage"`
+		Encryption  Encryption            `yaml:"encryption"`
+		Foundations map[string]Foundation `yaml:"foundations"`
+	}
+
+	//Storage - a target archives are written to, on disk or in an s3 bucket
+	Storage struct {
+		Type            string `yaml:"type"`
+		Path            string `yaml:"path"`
+		Domain          string `yaml:"domain"`
+		AccessKeyID     string `yaml:"access_key_id"`
+		SecretAccessKey string `yaml:"secret_access_key"`
+		Bucket          string `yaml:"bucket"`
+	}
+
+	//Encryption - the key archives are encrypted with, 16, 24 or 32 bytes long. No key leaves them unencrypted
+	Encryption struct {
+		Key string `yaml:"key"`
+	}
+
+	//Foundation - an ops manager and the backups taken of it
+	Foundation struct {
+		OpsManager          OpsManager  `yaml:"ops_manager"`
+		Storage             string      `yaml:"storage"`
+		ArchiveDirectory    string      `yaml:"archive_directory"`
+		Encryption          *Encryption `yaml:"encryption"`
+		NFS                 string      `yaml:"nfs"`
+		Components          Components  `yaml:"components"`
+		Schedule            string      `yaml:"schedule"`
+		ClearBoshManifest   bool        `yaml:"clear_bosh_manifest"`
+		MergeOnRestore      bool        `yaml:"merge_on_restore"`
+		Concurrency         int         `yaml:"concurrency"`
+		CCToggleConcurrency int         `yaml:"cc_toggle_concurrency"`
+		CCJobPatterns       []string    `yaml:"cc_job_patterns"`
+		Online              bool        `yaml:"online"`
+		TLS                 TLS         `yaml:"tls"`
+	}
+
+	//TLS - how the foundation's ops manager, director and uaa servers are verified. A CA is a pem file path or the
+	//pem itself, a pin the base64 sha256 digest of a server's public key. Without any the system roots verify them
+	TLS struct {
+		OpsManagerCA string   `yaml:"ops_manager_ca"`
+		DirectorCA   string   `yaml:"director_ca"`
+		UAACA        string   `yaml:"uaa_ca"`
+		Pins         []string `yaml:"pins"`
+		Insecure     bool     `yaml:"insecure"`
+	}
+
+	//OpsManager - how to reach the foundation's ops manager
+	OpsManager struct {
+		Host string `yaml:"host"`
+		Auth Auth   `yaml:"auth"`
+		SSH  SSH    `yaml:"ssh"`
+	}
+
+	//Auth - the ops manager credentials for the chosen method
+	Auth struct {
+		Method       string `yaml:"method"`
+		Username     string `yaml:"username"`
+		Password     string `yaml:"password"`
+		Passphrase   string `yaml:"passphrase"`
+		Token        string `yaml:"token"`
+		ClientID     string `yaml:"client_id"`
+		ClientSecret string `yaml:"client_secret"`
+	}
+
+	//SSH - the ops manager vm user, authenticated by its private key or else its password. Port defaults to 22
+	SSH struct {
+		Username   string `yaml:"username"`
+		Password   string `yaml:"password"`
+		PrivateKey string `yaml:"private_key"`
+		Port       int    `yaml:"port"`
+	}
+
+	//Components - the elastic runtime components to back up, all of them when both are empty
+	Components struct {
+		Include []string `yaml:"include"`
+		Exclude []string `yaml:"exclude"`
+	}
+)
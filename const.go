@@ -3,11 +3,20 @@ package cfbackup
 import (
 	"errors"
 	"os"
+	"os/exec"
+	"syscall"
+	"time"
 
 	"github.com/pivotalservices/gtils/command"
 )
 
 const (
+	//RedactedValue - what secrets are replaced with in log output
+	RedactedValue = "********"
+	//RedactMinSecretLength - secrets shorter than this are not masked, they would mask ordinary words in the log output
+	RedactMinSecretLength = 4
+	//RedactLogsEnvVarname - environment variable which turns the redaction of lo.G output off when set to false
+	RedactLogsEnvVarname = "CFBACKUP_REDACT_LOGS"
 	//AccessKeyIDVarname - s3 key flag
 	AccessKeyIDVarname = "S3_ACCESS_KEY_ID"
 	//SecretAccessKeyVarname - s3 secret key
@@ -18,6 +27,8 @@ const (
 	S3Domain = "S3_DOMAIN"
 	//IsS3Varname - s3 persistence true|false
 	IsS3Varname = "S3_ACTIVE"
+	//S3NoSuchKey - code of the error an s3 compatible blobstore returns for an object which does not exist
+	S3NoSuchKey = "NoSuchKey"
 
 	//NfsDirPath - this is where the nfs store lives
 	NfsDirPath string = "/var/vcap/store"
@@ -28,8 +39,32 @@ const (
 	ERDefaultSystemUser = "vcap"
 	//ERDirectorInfoURL - url format for a director info endpoint
 	ERDirectorInfoURL = "https://%s:25555/info"
+	//DirectorAuthTypeUAA - user authentication type of a director which takes uaa tokens
+	DirectorAuthTypeUAA = "uaa"
+	//DirectorUAAGrantType - grant the director uaa token is requested with
+	DirectorUAAGrantType = "client_credentials"
+	//UAAPasswordGrantType - grant of a uaa token requested with a username and password
+	UAAPasswordGrantType = "password"
+	//TLSTargetOpsManager - tls target of calls to ops manager
+	TLSTargetOpsManager = "ops_manager"
+	//TLSTargetDirector - tls target of calls to the bosh director
+	TLSTargetDirector = "director"
+	//TLSTargetUAA - tls target of calls to uaa servers
+	TLSTargetUAA = "uaa"
+	//TLSPinPrefix - optional prefix of a certificate pin, as curl and hpkp write them
+	TLSPinPrefix = "sha256/"
+	//ErrTLSInvalidCAMsg -- error message for a CA which could not be read
+	ErrTLSInvalidCAMsg = "invalid tls CA"
+	//ErrTLSInvalidPinMsg -- error message for a certificate pin which is not a sha256 digest
+	ErrTLSInvalidPinMsg = "invalid tls pin"
+	//ErrTLSPinMismatchMsg -- error message for a server whose certificate chain carries none of the pins
+	ErrTLSPinMismatchMsg = "server certificate matches none of the tls pins"
 	//ERBackupDir - default er backup dir
 	ERBackupDir = "elasticruntime"
+	//ERTaskOutputURL - url format for the event output of a bosh task
+	ERTaskOutputURL = "https://%s:25555/tasks/%d/output?type=event"
+	//CCTaskOutputLimit - how much of a failed bosh task's output is put into its error
+	CCTaskOutputLimit = 64 * 1024
 	//ERVmsURL - url format for a vms url
 	ERVmsURL = "https://%s:25555/deployments/%s/vms"
 	//ERDirector -- key
@@ -54,6 +89,16 @@ const (
 	ERFileDoesNotExist = "file does not exist"
 	//ErrERDBBackupFailure -- error message for backup failure
 	ErrERDBBackupFailure = "failed to backup database"
+	//ErrVerificationFailedMsg -- error message for a backup set which failed verification
+	ErrVerificationFailedMsg = "one or more backup artifacts failed verification"
+	//ErrLeaseHeldMsg -- error message for a run lock held by another run
+	ErrLeaseHeldMsg = "run lock is held by another run"
+	//ErrLeaseLostMsg -- error message for a run lock taken over while the run was holding it
+	ErrLeaseLostMsg = "run lock was lost"
+	//ErrCheckpointMismatchMsg -- error message for a checkpointed artifact which changed since it was written
+	ErrCheckpointMismatchMsg = "artifact does not match its checkpoint"
+	//ErrRoleRemapCustomFormatMsg -- error message for a custom format pg_dump archive whose roles would have to be remapped
+	ErrRoleRemapCustomFormatMsg = "custom format archives can not have their roles remapped"
 	//ERVersionEnvFlag -- env flag from ER version toggle
 	ERVersionEnvFlag = "ER_VERSION"
 	//ERVersion16 -- value for 1.6 toggle
@@ -82,6 +127,76 @@ const (
 	SDVcapPass string = "VcapPass"
 	//SDIdentifier
 	SDIdentifier string = "Identifier"
+
+	//RunStatusSucceeded -- status of a run or component which completed
+	RunStatusSucceeded = "succeeded"
+	//RunStatusFailed -- status of a run or component which returned an error
+	RunStatusFailed = "failed"
+	//RunStatusRunning -- status of a run or component which has not finished yet
+	RunStatusRunning = "running"
+	//RunModeOnline -- mode of a backup taken while the cloud controllers kept running
+	RunModeOnline = "online"
+	//RunModeInconsistent -- mode of a restore run while the cloud controllers kept running
+	RunModeInconsistent = "inconsistent"
+	//CCJobStopped -- bosh job state of a stopped cloud controller
+	CCJobStopped = "stopped"
+	//CCJobStarted -- bosh job state of a started cloud controller
+	CCJobStarted = "started"
+	//RunReportFileFormat -- format of the run report filename, by action
+	RunReportFileFormat = "%s-report.json"
+	//CheckpointFileName -- name of the file recording the components a backup run completed
+	CheckpointFileName = "backup-checkpoint.json"
+	//MysqlDumpBin -- mysqldump on the elastic runtime mysql vms
+	MysqlDumpBin = "/var/vcap/packages/mariadb/bin/mysqldump"
+	//MysqlSQLBin -- mysql client on the elastic runtime mysql vms
+	MysqlSQLBin = "/var/vcap/packages/mariadb/bin/mysql"
+	//LeaseFileFormat -- format of the run lock filename, by key
+	LeaseFileFormat = "%s.lock"
+	//DefaultLeaseDirectory -- where run locks are kept when the tile spec names no lock directory. It is the same for
+	//every run, rather than their dated archive directories, so runs against one foundation always find each other's lock.
+	//On disk storage it is local to the host, so it only keeps apart runs on the same host
+	DefaultLeaseDirectory = "/var/tmp/cfbackup/locks"
+
+	//HookPreTile -- hook point run before a tile's backup or restore
+	HookPreTile = "pre-tile"
+	//HookPostTile -- hook point run after a tile's backup or restore
+	HookPostTile = "post-tile"
+	//HookPreComponent -- hook point run before each component of a tile
+	HookPreComponent = "pre-component"
+	//HookPostComponent -- hook point run after each component of a tile
+	HookPostComponent = "post-component"
+	//HookActionBackup -- action of a hook event for a backup
+	HookActionBackup = "backup"
+	//HookActionRestore -- action of a hook event for a restore
+	HookActionRestore = "restore"
+	//HookEventEnvVarname -- environment variable holding the json hook event for executable hooks
+	HookEventEnvVarname = "CFBACKUP_HOOK_EVENT"
+	//ErrCCRestartFailedMsg -- error message for cloud controller jobs which could not be started again
+	ErrCCRestartFailedMsg = "cloud controller jobs left stopped"
+	//ErrCCStopAbandonedMsg -- error message for a stop given up on because the cloud controllers are being restarted
+	ErrCCStopAbandonedMsg = "cloud controllers are being restarted, stop abandoned"
+	//ErrCCTaskTimeoutMsg -- error message for a bosh task which did not finish within the TaskTimeout
+	ErrCCTaskTimeoutMsg = "bosh task timed out"
+	//ErrCCToggleSkippedMsg -- error message for a cloud controller job left alone because another one failed to change state
+	ErrCCToggleSkippedMsg = "skipped, another cloud controller job failed to change state"
+	//DefaultCCRestartRetries -- how many more times a cloud controller job which fails to start is retried
+	DefaultCCRestartRetries = 3
+	//DefaultCCToggleConcurrency -- how many cloud controller jobs are stopped or started at a time unless configured otherwise.
+	//Bosh runs one task per deployment at a time, so toggling jobs in parallel only queues them on the deployment lock and is opt-in
+	DefaultCCToggleConcurrency = 1
+	//CCDeploymentLockedMsg -- what bosh reports for a task which could not take the deployment lock from a task still running
+	CCDeploymentLockedMsg = "Failed to acquire lock"
+	//ErrRecoveredPanicMsg -- error message for a call which panicked while it ran in the background
+	ErrRecoveredPanicMsg = "recovered from a panic"
+	//ErrPreHookFailedMsg -- error message for a pre hook which aborted the run
+	ErrPreHookFailedMsg = "pre hook failed, aborting"
+
+	//ArtifactFormatMysqlDump -- a plain mysqldump archive
+	ArtifactFormatMysqlDump string = "mysqldump"
+	//ArtifactFormatPgDump -- a plain or custom format pg_dump archive
+	ArtifactFormatPgDump string = "pg_dump"
+	//ArtifactFormatTarGz -- a gzipped tarball
+	ArtifactFormatTarGz string = "tar.gz"
 )
 
 const (
@@ -94,6 +209,23 @@ const (
 var (
 	//NfsNewRemoteExecuter - this is a function which is able to execute a remote command against the nfs server
 	NfsNewRemoteExecuter = command.NewRemoteExecutor
+	//RemoteCommandNewRemoteExecuter - this is a function which is able to execute a remote command for a RemoteCommandBackup
+	RemoteCommandNewRemoteExecuter = command.NewRemoteExecutor
+	//GetUAAToken - requests a token from a uaa server, verifying it with the given clients
+	GetUAAToken = RequestUAAToken
+	//TLSTargets - the servers a tls config verifies
+	TLSTargets = []string{TLSTargetOpsManager, TLSTargetDirector, TLSTargetUAA}
+	//ErrTLSInvalidCA - error for a CA which could not be read
+	ErrTLSInvalidCA = errors.New(ErrTLSInvalidCAMsg)
+	//ErrTLSInvalidPin - error for a certificate pin which is not a sha256 digest
+	ErrTLSInvalidPin = errors.New(ErrTLSInvalidPinMsg)
+	//ErrTLSPinMismatch - error for a server whose certificate chain carries none of the pins
+	ErrTLSPinMismatch = errors.New(ErrTLSPinMismatchMsg)
+	//UAATokenRefreshInterval - how long a director uaa token is used before a new one is requested
+	UAATokenRefreshInterval = 5 * time.Minute
+	//DirectorUAAClientIdentifiers - the director properties in installation settings holding a uaa client for
+	//the director, the first one found is used
+	DirectorUAAClientIdentifiers = []string{"uaa_bbr_client_credentials", "uaa_admin_client_credentials"}
 
 	//ErrERDirectorCreds - error for director creds
 	ErrERDirectorCreds = errors.New(ERInvalidDirectorCredsMsg)
@@ -103,6 +235,48 @@ var (
 	ErrERInvalidPath = &os.PathError{Err: errors.New(ERFileDoesNotExist)}
 	//ErrERDBBackup - error for db backup failures
 	ErrERDBBackup = errors.New(ErrERDBBackupFailure)
+	//ErrVerificationFailed - error for a backup set containing invalid artifacts
+	ErrVerificationFailed = errors.New(ErrVerificationFailedMsg)
+	//ErrCheckpointMismatch - error for a checkpointed artifact which can not be reused
+	ErrCheckpointMismatch = errors.New(ErrCheckpointMismatchMsg)
+	//ErrRoleRemapCustomFormat - error for a custom format pg_dump archive whose roles would have to be remapped
+	ErrRoleRemapCustomFormat = errors.New(ErrRoleRemapCustomFormatMsg)
+	//ErrLeaseHeld - error for a run lock held by another run
+	ErrLeaseHeld = errors.New(ErrLeaseHeldMsg)
+	//ErrLeaseLost - error for a run lock taken over while the run was holding it
+	ErrLeaseLost = errors.New(ErrLeaseLostMsg)
+	//DefaultLeaseTTL - how long a run lock stays valid without a heartbeat
+	DefaultLeaseTTL = 5 * time.Minute
+	//LeaseSettleTime - how long to wait before reading a freshly written lease back, to catch a run which wrote its own at the same time
+	LeaseSettleTime = 2 * time.Second
+	//ErrCCRestartFailed - error for cloud controller jobs which could not be started again
+	ErrCCRestartFailed = errors.New(ErrCCRestartFailedMsg)
+	//ErrCCStopAbandoned - error for a stop given up on because the cloud controllers are being restarted
+	ErrCCStopAbandoned = errors.New(ErrCCStopAbandonedMsg)
+	//ErrCCTaskTimeout - error for a bosh task which did not finish within the TaskTimeout
+	ErrCCTaskTimeout = errors.New(ErrCCTaskTimeoutMsg)
+	//ErrCCToggleSkipped - error for a cloud controller job left alone because another one failed to change state
+	ErrCCToggleSkipped = errors.New(ErrCCToggleSkippedMsg)
+	//DefaultCCJobPatterns - the jobs stopped during backup and restore of installations newer than the legacy
+	//ones, those writing to ccdb and the blobstore, whether or not their names carry a partition suffix
+	DefaultCCJobPatterns = []string{"cloud_controller", "cloud_controller-*", "cloud_controller_worker", "cloud_controller_worker-*", "clock_global", "clock_global-*"}
+	//CCRestartRetryDelay - how long to wait before retrying a cloud controller job which failed to start
+	CCRestartRetryDelay = 10 * time.Second
+	//SupervisedSignals - the signals on which a CCSupervisor restarts the cloud controllers before the process exits
+	SupervisedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	//ReraiseSignal - delivers a signal the CCSupervisor caught to the process again, once it stopped catching it
+	ReraiseSignal = func(sig os.Signal) {
+		if process, err := os.FindProcess(os.Getpid()); err == nil {
+			process.Signal(sig)
+		}
+	}
+	//ErrPreHookFailed - error for a pre hook which aborted the run
+	ErrPreHookFailed = errors.New(ErrPreHookFailedMsg)
+	//ErrRecoveredPanic - error for a call which panicked while it ran in the background
+	ErrRecoveredPanic = errors.New(ErrRecoveredPanicMsg)
+
+	//NewHookCommand - builds the command used to run an executable hook
+	NewHookCommand = exec.CommandContext
 
 	//TileRestoreAction -- executes a restore action on the given tile
 	TileRestoreAction = func(t Tile) func() error {
@@ -0,0 +1,188 @@
+package cfbackup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"runtime/debug"
+	"time"
+
+	"github.com/pivotalservices/gtils/command"
+	ghttp "github.com/pivotalservices/gtils/http"
+	"github.com/xchapter7x/lo"
+)
+
+//WithPhaseTimeout - derives a context for a single phase, bounded by the given timeout unless it is zero
+func WithPhaseTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+//CleanupContext - a context for cleanup work which has to run even when the operation's own context was cancelled
+func CleanupContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	return WithPhaseTimeout(context.Background(), timeout)
+}
+
+//RunWithContext - runs a blocking call, returning early with the context's error once it is done.
+//The call itself is left to finish in the background, so callers should also make it fail fast,
+//for example by wrapping its reader or writer with NewContextReader or NewContextWriter.
+//A panic in the call is recovered and returned as an ErrRecoveredPanic error.
+func RunWithContext(ctx context.Context, run func() error) (err error) {
+	_, err = startWithContext(ctx, run)
+	return
+}
+
+//ExecuteWithContext - runs the command, returning early with the context's error once it is done. Executers
+//which can be closed, such as ssh executers holding a session, are closed then so the command is torn down
+func ExecuteWithContext(ctx context.Context, caller command.Executer, dest io.Writer, cmd string) (err error) {
+	_, err = runRemote(ctx, caller, func() error {
+		return caller.Execute(NewContextWriter(ctx, dest), cmd)
+	})
+	return
+}
+
+//NewContextWriter - wraps a writer so writes fail once the context is done
+func NewContextWriter(ctx context.Context, writer io.Writer) io.Writer {
+	return &contextWriter{ctx: ctx, writer: writer}
+}
+
+//NewContextReader - wraps a reader so reads fail once the context is done
+func NewContextReader(ctx context.Context, reader io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, reader: reader}
+}
+
+//DumpWithContext - dumps the persistence backup, stopping when the context is done
+func DumpWithContext(ctx context.Context, pb PersistanceBackup, dest io.Writer) error {
+	if cpb, ok := pb.(ContextPersistanceBackup); ok {
+		return cpb.DumpWithContext(ctx, dest)
+	}
+	return RunWithContext(ctx, func() error {
+		return pb.Dump(NewContextWriter(ctx, dest))
+	})
+}
+
+//ImportWithContext - imports into the persistence backup, stopping when the context is done
+func ImportWithContext(ctx context.Context, pb PersistanceBackup, src io.Reader) error {
+	if cpb, ok := pb.(ContextPersistanceBackup); ok {
+		return cpb.ImportWithContext(ctx, src)
+	}
+	return RunWithContext(ctx, func() error {
+		return pb.Import(NewContextReader(ctx, src))
+	})
+}
+
+//GatewayWithContext - the gateway with its requests bound to the context when it is a ContextHTTPGateway
+func GatewayWithContext(ctx context.Context, gateway ghttp.HttpGateway) ghttp.HttpGateway {
+	if contextGateway, ok := gateway.(ContextHTTPGateway); ok {
+		return contextGateway.WithContext(ctx)
+	}
+	return gateway
+}
+
+func (s *contextWriter) Write(p []byte) (n int, err error) {
+	if err = s.ctx.Err(); err == nil {
+		n, err = s.writer.Write(p)
+	}
+	return
+}
+
+func (s *contextReader) Read(p []byte) (n int, err error) {
+	if err = s.ctx.Err(); err == nil {
+		n, err = s.reader.Read(p)
+	}
+	return
+}
+
+//Context - the context the reader was made with, uploads of the reader are cancelled along with it
+func (s *contextReader) Context() context.Context {
+	return s.ctx
+}
+
+//startWithContext - runs the call in the background, returning its error or the context's once it is done,
+//along with a channel which is closed when the call itself has returned
+func startWithContext(ctx context.Context, run func() error) (finished <-chan struct{}, err error) {
+	exited := make(chan struct{})
+	finished = exited
+
+	if err = ctx.Err(); err != nil {
+		close(exited)
+		return
+	}
+	done := make(chan error, 1)
+
+	go func() {
+		defer close(exited)
+		defer func() {
+			if r := recover(); r != nil {
+				lo.G.Error(fmt.Sprintf("recovered from a panic: %v\n%s", r, debug.Stack()))
+				done <- fmt.Errorf("%w: %v", ErrRecoveredPanic, r)
+			}
+		}()
+		done <- run()
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
+
+//runRemote - runs a call against the executer, closing the executer when the context is done first
+func runRemote(ctx context.Context, caller command.Executer, run func() error) (finished <-chan struct{}, err error) {
+	if finished, err = startWithContext(ctx, run); err != nil && err == ctx.Err() {
+		if closer, ok := caller.(io.Closer); ok {
+			if closeErr := closer.Close(); closeErr != nil {
+				lo.G.Debug("failed to close the remote session: %s", closeErr)
+			}
+		}
+	}
+	return
+}
+
+//removeRemoteFileAfter - removes the uploaded archive once the calls using it have returned, in the
+//background when they are still running because the context was done first
+func removeRemoteFileAfter(finished <-chan struct{}, remoteOps remoteOpsInterface) {
+	remove := func() {
+		if removeErr := remoteOps.RemoveRemoteFile(); removeErr != nil {
+			lo.G.Debug("failed to remove remote archive %s: %s", remoteOps.Path(), removeErr)
+		}
+	}
+
+	select {
+	case <-finished:
+		remove()
+	default:
+		go func() {
+			<-finished
+			remove()
+		}()
+	}
+}
+
+func remoteImport(ctx context.Context, caller command.Executer, remoteOps remoteOpsInterface, restoreCommand string, src io.Reader) (err error) {
+	var finished <-chan struct{}
+	lo.G.Debug("uploading file for restore")
+
+	finished, err = runRemote(ctx, caller, func() (err error) {
+		if err = remoteOps.UploadFile(NewContextReader(ctx, src)); err == nil {
+			if err = ctx.Err(); err == nil {
+				lo.G.Debug("starting restore from %s", remoteOps.Path())
+				err = caller.Execute(ioutil.Discard, restoreCommand)
+			}
+		}
+		return
+	})
+	removeRemoteFileAfter(finished, remoteOps)
+
+	if err == nil {
+		lo.G.Debug("restore from %s completed", remoteOps.Path())
+	} else {
+		lo.G.Debug("restore from %s completed with error %s", remoteOps.Path(), err)
+	}
+	return
+}
@@ -0,0 +1,260 @@
+package cfbackup_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/gtils/bosh"
+)
+
+type hangingExecuter struct {
+	release chan struct{}
+	once    sync.Once
+}
+
+func (s *hangingExecuter) Execute(dest io.Writer, cmd string) error {
+	<-s.release
+	return nil
+}
+
+func (s *hangingExecuter) unblock() {
+	s.once.Do(func() { close(s.release) })
+}
+
+type closableExecuter struct {
+	hangingExecuter
+}
+
+func (s *closableExecuter) Close() error {
+	s.unblock()
+	return nil
+}
+
+type recordingRemoteOps struct {
+	mutex    sync.Mutex
+	uploaded string
+	removed  bool
+}
+
+func (s *recordingRemoteOps) UploadFile(lfile io.Reader) (err error) {
+	var contents []byte
+	contents, err = ioutil.ReadAll(lfile)
+	s.uploaded = string(contents)
+	return
+}
+
+func (s *recordingRemoteOps) Path() string {
+	return "/tmp/archive.backup"
+}
+
+func (s *recordingRemoteOps) RemoveRemoteFile() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.removed = true
+	return nil
+}
+
+func (s *recordingRemoteOps) wasRemoved() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.removed
+}
+
+type hangingDirector struct {
+	mockDirector
+}
+
+func (s *hangingDirector) ChangeJobState(deploymentName, jobName, state string, index int, manifest io.Reader) (int, error) {
+	select {}
+}
+
+type panickingDumper struct{}
+
+func (s *panickingDumper) Dump(io.Writer) error {
+	panic("dump exploded")
+}
+
+func (s *panickingDumper) Import(io.Reader) error {
+	panic("import exploded")
+}
+
+var _ = Describe("context", func() {
+	Describe("given a WithPhaseTimeout() function", func() {
+		Context("when called with a zero timeout", func() {
+			It("then it should not set a deadline", func() {
+				ctx, cancel := WithPhaseTimeout(context.Background(), 0)
+				defer cancel()
+				_, hasDeadline := ctx.Deadline()
+				Ω(hasDeadline).Should(BeFalse())
+			})
+		})
+
+		Context("when called with a timeout", func() {
+			It("then it should expire after the timeout", func() {
+				ctx, cancel := WithPhaseTimeout(context.Background(), time.Millisecond)
+				defer cancel()
+				Eventually(ctx.Done()).Should(BeClosed())
+				Ω(ctx.Err()).Should(Equal(context.DeadlineExceeded))
+			})
+		})
+	})
+
+	Describe("given a CleanupContext() function", func() {
+		It("then it should not be cancelled with the operation's context", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			cleanupCtx, cleanupCancel := CleanupContext(0)
+			defer cleanupCancel()
+			Ω(ctx.Err()).Should(HaveOccurred())
+			Ω(cleanupCtx.Err()).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Describe("given a RunWithContext() function", func() {
+		Context("when the call finishes first", func() {
+			It("then it should return the call's error", func() {
+				controlErr := errors.New("failed")
+				Ω(RunWithContext(context.Background(), func() error { return controlErr })).Should(Equal(controlErr))
+			})
+		})
+
+		Context("when the context is cancelled while the call blocks", func() {
+			It("then it should return the context's error", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				release := make(chan struct{})
+				defer close(release)
+				time.AfterFunc(time.Millisecond, cancel)
+				Ω(RunWithContext(ctx, func() error { <-release; return nil })).Should(Equal(context.Canceled))
+			})
+		})
+
+		Context("when the call panics", func() {
+			It("then it should return the panic as an error", func() {
+				err := RunWithContext(context.Background(), func() error { panic("nil map") })
+				Ω(errors.Is(err, ErrRecoveredPanic)).Should(BeTrue())
+				Ω(err.Error()).Should(ContainSubstring("nil map"))
+			})
+		})
+
+		Context("when the context is already done", func() {
+			It("then it should not make the call", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				called := false
+				Ω(RunWithContext(ctx, func() error { called = true; return nil })).Should(Equal(context.Canceled))
+				Ω(called).Should(BeFalse())
+			})
+		})
+	})
+
+	Describe("given a DumpWithContext() function", func() {
+		Context("when the dumper panics", func() {
+			It("then it should return the panic as an error rather than crash the run", func() {
+				err := DumpWithContext(context.Background(), new(panickingDumper), new(bytes.Buffer))
+				Ω(errors.Is(err, ErrRecoveredPanic)).Should(BeTrue())
+				Ω(err.Error()).Should(ContainSubstring("dump exploded"))
+			})
+		})
+	})
+
+	Describe("given a NewContextWriter() function", func() {
+		It("then it should fail writes once the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			buffer := new(bytes.Buffer)
+			writer := NewContextWriter(ctx, buffer)
+			_, err := io.WriteString(writer, "before")
+			Ω(err).ShouldNot(HaveOccurred())
+			cancel()
+			_, err = io.WriteString(writer, "after")
+			Ω(err).Should(Equal(context.Canceled))
+			Ω(buffer.String()).Should(Equal("before"))
+		})
+	})
+
+	Describe("given a NFSBackup", func() {
+		var (
+			executer  *hangingExecuter
+			remoteOps *recordingRemoteOps
+			nfs       *NFSBackup
+		)
+
+		BeforeEach(func() {
+			executer = &hangingExecuter{release: make(chan struct{})}
+			remoteOps = new(recordingRemoteOps)
+			nfs = &NFSBackup{Caller: executer, RemoteOps: remoteOps, BackupType: NFSBackupTypeFull}
+		})
+
+		AfterEach(func() {
+			executer.unblock()
+		})
+
+		Context("when a dump outlives its deadline", func() {
+			It("then it should return a deadline error", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+				defer cancel()
+				Ω(nfs.DumpWithContext(ctx, new(bytes.Buffer))).Should(Equal(context.DeadlineExceeded))
+			})
+		})
+
+		Context("when an import is cancelled during the restore command", func() {
+			It("then it should only remove the uploaded archive once the restore command returned", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(time.Millisecond, cancel)
+				Ω(nfs.ImportWithContext(ctx, strings.NewReader("archive"))).Should(Equal(context.Canceled))
+				Ω(remoteOps.uploaded).Should(Equal("archive"))
+				Consistently(remoteOps.wasRemoved).Should(BeFalse())
+				executer.unblock()
+				Eventually(remoteOps.wasRemoved).Should(BeTrue())
+			})
+		})
+
+		Context("when an import through a closable executer is cancelled", func() {
+			It("then it should close the executer so the restore command returns and the archive is removed", func() {
+				closable := &closableExecuter{hangingExecuter{release: make(chan struct{})}}
+				nfs.Caller = closable
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(time.Millisecond, cancel)
+				Ω(nfs.ImportWithContext(ctx, strings.NewReader("archive"))).Should(Equal(context.Canceled))
+				Eventually(closable.release).Should(BeClosed())
+				Eventually(remoteOps.wasRemoved).Should(BeTrue())
+			})
+		})
+
+		Context("when an import finishes", func() {
+			It("then it should have removed the uploaded archive before returning", func() {
+				executer.unblock()
+				Ω(nfs.ImportWithContext(context.Background(), strings.NewReader("archive"))).Should(Succeed())
+				Ω(remoteOps.wasRemoved()).Should(BeTrue())
+			})
+		})
+	})
+
+	Describe("given a CloudController", func() {
+		var originalNewDirector = NewDirector
+
+		AfterEach(func() {
+			NewDirector = originalNewDirector
+		})
+
+		Context("when the bosh director hangs during a stop", func() {
+			It("then it should give up once the context is done", func() {
+				NewDirector = func(ip, username, password string, port int, clients *TLSClients) bosh.Bosh {
+					return new(hangingDirector)
+				}
+				cloudController := NewCloudController(ip, username, password, deploymentName, "manifest", ccjobs, nil)
+				ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+				defer cancel()
+				Ω(cloudController.StopWithContext(ctx)).Should(Equal(context.DeadlineExceeded))
+			})
+		})
+	})
+})
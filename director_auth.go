@@ -0,0 +1,174 @@
+package cfbackup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	ghttp "github.com/pivotalservices/gtils/http"
+	"github.com/xchapter7x/lo"
+)
+
+var (
+	directorAuths      = make(map[string]*DirectorAuth)
+	directorAuthsMutex sync.Mutex
+)
+
+//DirectorAuthFor - the DirectorAuth of the director at ip for the given user. Every client of the director
+//shares it, and with it the director's uaa token
+func DirectorAuthFor(ip, username, password string) *DirectorAuth {
+	directorAuthsMutex.Lock()
+	defer directorAuthsMutex.Unlock()
+	key := username + "@" + ip
+
+	if auth, ok := directorAuths[key]; ok && auth.Password == password {
+		return auth
+	}
+	directorAuths[key] = &DirectorAuth{IP: ip, Username: username, Password: password}
+	return directorAuths[key]
+}
+
+//SetClientCredentials - sets the uaa client tokens are requested with, when the director takes uaa tokens
+func (s *DirectorAuth) SetClientCredentials(clientID, clientSecret string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	RedactSecrets(clientSecret)
+
+	if clientID != s.ClientID || clientSecret != s.ClientSecret {
+		s.ClientID, s.ClientSecret, s.token = clientID, clientSecret, ""
+	}
+}
+
+//Authenticate - the entity with a bearer token when the director takes uaa tokens, otherwise with basic auth
+//using the director credentials. Basic auth is also the fallback when no token can be had
+func (s *DirectorAuth) Authenticate(gateway ghttp.HttpGateway, entity ghttp.HttpRequestEntity) ghttp.HttpRequestEntity {
+	if token := s.Token(gateway); token != "" {
+		entity.Username, entity.Password = "", ""
+		entity.Authorization = "Bearer " + token
+		return entity
+	}
+
+	if entity.Username == "" {
+		entity.Username, entity.Password = s.Username, s.Password
+	}
+	return entity
+}
+
+//Token - a current uaa token for the director, requesting a new one once UAATokenRefreshInterval passed.
+//Empty when the director uses basic auth, or no token can be had
+func (s *DirectorAuth) Token(gateway ghttp.HttpGateway) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.detected {
+		s.detect(gateway)
+	}
+
+	if s.uaaURL == "" || s.ClientID == "" {
+		return ""
+	}
+
+	if s.token == "" || time.Since(s.issued) > UAATokenRefreshInterval {
+		token, err := GetUAAToken(GatewayTLSClients(gateway), s.uaaURL, "", "", s.ClientID, s.ClientSecret, DirectorUAAGrantType)
+
+		if err != nil {
+			lo.G.Warning(fmt.Sprintf("failed to get a uaa token for the director at %s, falling back to basic auth: %s", s.IP, err))
+			s.token = ""
+			return ""
+		}
+		RedactSecrets(token)
+		s.token, s.issued = token, time.Now()
+	}
+	return s.token
+}
+
+//Invalidate - drops the current uaa token, so the next request gets a new one
+func (s *DirectorAuth) Invalidate() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.token = ""
+}
+
+func (s *DirectorAuth) detect(gateway ghttp.HttpGateway) {
+	var info directorInfo
+	resp, err := gateway.Get(ghttp.HttpRequestEntity{
+		Url:         fmt.Sprintf(ERDirectorInfoURL, s.IP),
+		ContentType: "application/json",
+	})()
+
+	if err == nil {
+		defer resp.Body.Close()
+		err = json.NewDecoder(resp.Body).Decode(&info)
+	}
+
+	if err != nil {
+		lo.G.Debug("could not read the director info, using basic auth ", s.IP, err)
+		return
+	}
+	s.detected = true
+
+	if info.UserAuthentication.Type == DirectorAuthTypeUAA {
+		s.uaaURL = info.UserAuthentication.Options.URL
+
+		if s.ClientID == "" {
+			lo.G.Warning(fmt.Sprintf("the director at %s takes uaa tokens but installation settings hold no uaa client for it, falling back to basic auth", s.IP))
+		}
+	}
+}
+
+//NewDirectorGateway - a gateway to a bosh director sending its requests through the given gateway, authenticated by auth
+func NewDirectorGateway(gateway ghttp.HttpGateway, auth *DirectorAuth) *DirectorGateway {
+	return &DirectorGateway{Gateway: gateway, Auth: auth}
+}
+
+//Get - an authenticated get request
+func (s *DirectorGateway) Get(entity ghttp.HttpRequestEntity) ghttp.RequestAdaptor {
+	return s.authenticated(entity, s.Gateway.Get)
+}
+
+//Post - an authenticated post request
+func (s *DirectorGateway) Post(entity ghttp.HttpRequestEntity, body io.Reader) ghttp.RequestAdaptor {
+	return s.withBody(entity, body, s.Gateway.Post)
+}
+
+//Put - an authenticated put request
+func (s *DirectorGateway) Put(entity ghttp.HttpRequestEntity, body io.Reader) ghttp.RequestAdaptor {
+	return s.withBody(entity, body, s.Gateway.Put)
+}
+
+//withBody - keeps the body, so the request can be sent again with a new token
+func (s *DirectorGateway) withBody(entity ghttp.HttpRequestEntity, body io.Reader, send func(ghttp.HttpRequestEntity, io.Reader) ghttp.RequestAdaptor) ghttp.RequestAdaptor {
+	var contents []byte
+
+	if body != nil {
+		var err error
+
+		if contents, err = ioutil.ReadAll(body); err != nil {
+			return func() (*http.Response, error) {
+				return nil, err
+			}
+		}
+	}
+	return s.authenticated(entity, func(entity ghttp.HttpRequestEntity) ghttp.RequestAdaptor {
+		return send(entity, bytes.NewReader(contents))
+	})
+}
+
+//authenticated - sends the request, once more with a new token when the director rejects the token it was sent with
+func (s *DirectorGateway) authenticated(entity ghttp.HttpRequestEntity, send func(ghttp.HttpRequestEntity) ghttp.RequestAdaptor) ghttp.RequestAdaptor {
+	return func() (resp *http.Response, err error) {
+		authenticated := s.Auth.Authenticate(s.Gateway, entity)
+
+		if resp, err = send(authenticated)(); err == nil && resp.StatusCode == http.StatusUnauthorized && authenticated.Authorization != "" {
+			resp.Body.Close()
+			s.Auth.Invalidate()
+			resp, err = send(s.Auth.Authenticate(s.Gateway, entity))()
+		}
+		return
+	}
+}
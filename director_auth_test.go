@@ -0,0 +1,158 @@
+package cfbackup_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/pivotalservices/cfbackup"
+	ghttp "github.com/pivotalservices/gtils/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type mockDirectorGateway struct {
+	authType      string
+	rejectedToken string
+	entities      []ghttp.HttpRequestEntity
+	bodies        []string
+}
+
+func (s *mockDirectorGateway) Get(entity ghttp.HttpRequestEntity) ghttp.RequestAdaptor {
+	return s.respond(entity, nil)
+}
+
+func (s *mockDirectorGateway) Post(entity ghttp.HttpRequestEntity, body io.Reader) ghttp.RequestAdaptor {
+	return s.respond(entity, body)
+}
+
+func (s *mockDirectorGateway) Put(entity ghttp.HttpRequestEntity, body io.Reader) ghttp.RequestAdaptor {
+	return s.respond(entity, body)
+}
+
+func (s *mockDirectorGateway) respond(entity ghttp.HttpRequestEntity, body io.Reader) ghttp.RequestAdaptor {
+	return func() (*http.Response, error) {
+		if strings.HasSuffix(entity.Url, "/info") {
+			return directorResponse(http.StatusOK, fmt.Sprintf(`{"user_authentication":{"type":%q,"options":{"url":"https://10.0.0.6:8443"}}}`, s.authType)), nil
+		}
+		s.entities = append(s.entities, entity)
+
+		if body != nil {
+			contents, _ := ioutil.ReadAll(body)
+			s.bodies = append(s.bodies, string(contents))
+		}
+
+		if s.rejectedToken != "" && entity.Authorization == "Bearer "+s.rejectedToken {
+			return directorResponse(http.StatusUnauthorized, ""), nil
+		}
+		return directorResponse(http.StatusOK, "{}"), nil
+	}
+}
+
+func directorResponse(statusCode int, body string) *http.Response {
+	return &http.Response{StatusCode: statusCode, Body: ioutil.NopCloser(bytes.NewBufferString(body))}
+}
+
+var _ = Describe("DirectorGateway", func() {
+	var (
+		getUAATokenOrig = GetUAAToken
+		refreshOrig     = UAATokenRefreshInterval
+		tokenRequests   []string
+		tokenErr        error
+		mockGateway     *mockDirectorGateway
+		auth            *DirectorAuth
+		gateway         *DirectorGateway
+		vmsEntity       = ghttp.HttpRequestEntity{Url: "https://10.0.0.5:25555/deployments/cf/vms", ContentType: "application/json"}
+	)
+
+	BeforeEach(func() {
+		tokenRequests, tokenErr = nil, nil
+		GetUAAToken = func(clients *TLSClients, uaaURL, username, password, clientID, clientSecret, grantType string) (string, error) {
+			tokenRequests = append(tokenRequests, strings.Join([]string{uaaURL, clientID, clientSecret, grantType}, " "))
+			return fmt.Sprintf("token-%d", len(tokenRequests)), tokenErr
+		}
+		mockGateway = &mockDirectorGateway{authType: "basic"}
+		auth = &DirectorAuth{IP: "10.0.0.5", Username: "director", Password: "directorpass"}
+		gateway = NewDirectorGateway(mockGateway, auth)
+	})
+
+	AfterEach(func() {
+		GetUAAToken = getUAATokenOrig
+		UAATokenRefreshInterval = refreshOrig
+	})
+
+	Context("when the director uses basic auth", func() {
+		It("then it should send the director credentials", func() {
+			auth.SetClientCredentials("bbr_client", "clientsecret")
+			_, err := gateway.Get(vmsEntity)()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(mockGateway.entities[0].Username).Should(Equal("director"))
+			Ω(mockGateway.entities[0].Password).Should(Equal("directorpass"))
+			Ω(mockGateway.entities[0].Authorization).Should(BeEmpty())
+			Ω(tokenRequests).Should(BeEmpty())
+		})
+	})
+
+	Context("when the director takes uaa tokens", func() {
+		BeforeEach(func() {
+			mockGateway.authType = DirectorAuthTypeUAA
+			auth.SetClientCredentials("bbr_client", "clientsecret")
+		})
+
+		It("then it should send a bearer token from the director's uaa, reusing it for later requests", func() {
+			gateway.Get(vmsEntity)()
+			gateway.Get(vmsEntity)()
+			Ω(tokenRequests).Should(Equal([]string{"https://10.0.0.6:8443 bbr_client clientsecret client_credentials"}))
+			Ω(mockGateway.entities).Should(HaveLen(2))
+			Ω(mockGateway.entities[1].Authorization).Should(Equal("Bearer token-1"))
+			Ω(mockGateway.entities[1].Username).Should(BeEmpty())
+			Ω(mockGateway.entities[1].Password).Should(BeEmpty())
+		})
+
+		It("then it should request a new token once the refresh interval passed", func() {
+			UAATokenRefreshInterval = time.Duration(0)
+			gateway.Get(vmsEntity)()
+			gateway.Get(vmsEntity)()
+			Ω(tokenRequests).Should(HaveLen(2))
+			Ω(mockGateway.entities[1].Authorization).Should(Equal("Bearer token-2"))
+		})
+
+		It("then it should send the request again with a new token when the director rejects the token", func() {
+			mockGateway.rejectedToken = "token-1"
+			resp, err := gateway.Put(vmsEntity, strings.NewReader("manifest"))()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp.StatusCode).Should(Equal(http.StatusOK))
+			Ω(mockGateway.entities[1].Authorization).Should(Equal("Bearer token-2"))
+			Ω(mockGateway.bodies).Should(Equal([]string{"manifest", "manifest"}))
+		})
+
+		It("then it should fall back to basic auth when no token can be had", func() {
+			tokenErr = errors.New("uaa unavailable")
+			gateway.Get(vmsEntity)()
+			Ω(mockGateway.entities[0].Username).Should(Equal("director"))
+			Ω(mockGateway.entities[0].Authorization).Should(BeEmpty())
+		})
+	})
+
+	Context("when the director takes uaa tokens but there is no uaa client for it", func() {
+		It("then it should fall back to basic auth", func() {
+			mockGateway.authType = DirectorAuthTypeUAA
+			gateway.Get(vmsEntity)()
+			Ω(mockGateway.entities[0].Username).Should(Equal("director"))
+			Ω(tokenRequests).Should(BeEmpty())
+		})
+	})
+
+	Describe("given a DirectorAuthFor function", func() {
+		It("then it should share the auth of a director between its clients", func() {
+			Ω(DirectorAuthFor("10.0.0.7", "director", "pass")).Should(BeIdenticalTo(DirectorAuthFor("10.0.0.7", "director", "pass")))
+			Ω(DirectorAuthFor("10.0.0.7", "director", "newpass")).ShouldNot(BeIdenticalTo(DirectorAuthFor("10.0.0.7", "director", "pass")))
+		})
+	})
+})
@@ -26,6 +26,20 @@ func NewEncryptedStorageProvider(storageProvider StorageProvider, encryptionKey
 	return
 }
 
+//UnencryptedStorageProvider - the storage the given provider, or backup context, keeps its files in without the encryption of
+//an EncryptedStorageProvider, for state such as run locks which every run has to read whatever crypt key it was given
+func UnencryptedStorageProvider(storageProvider StorageProvider) StorageProvider {
+	switch provider := storageProvider.(type) {
+	case BackupContext:
+		return UnencryptedStorageProvider(provider.StorageProvider)
+	case *BackupContext:
+		return UnencryptedStorageProvider(provider.StorageProvider)
+	case *EncryptedStorageProvider:
+		return provider.wrappedStorageProvider
+	}
+	return storageProvider
+}
+
 //Reader - returns the encrpyted reader for the given path
 func (s *EncryptedStorageProvider) Reader(path ...string) (decryptReader io.ReadCloser, err error) {
 	var unEncryptedReader io.ReadCloser
@@ -35,6 +35,17 @@ var _ = Describe("EncryptedStorageProvider", func() {
 		})
 	})
 
+	Describe("given an UnencryptedStorageProvider function", func() {
+		It("then it should return the storage an encrypted provider or backup context wraps", func() {
+			msp := fakes.NewMockStringStorageProvider()
+			encrypted, _ := NewEncryptedStorageProvider(msp, "0123456789abcdef")
+			Ω(UnencryptedStorageProvider(encrypted)).Should(BeIdenticalTo(msp))
+			Ω(UnencryptedStorageProvider(BackupContext{StorageProvider: encrypted})).Should(BeIdenticalTo(msp))
+			Ω(UnencryptedStorageProvider(&BackupContext{StorageProvider: msp})).Should(BeIdenticalTo(msp))
+			Ω(UnencryptedStorageProvider(msp)).Should(BeIdenticalTo(msp))
+		})
+	})
+
 	Describe("given an EncryptedStorageProvider object", func() {
 		var msp *fakes.MockStringStorageProvider
 		var encrpytedProvidor *EncryptedStorageProvider
@@ -0,0 +1,68 @@
+package fakes
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+)
+
+//Exported dump fixtures
+var (
+	MysqlDumpContents   = "-- MySQL dump 10.13\nCREATE TABLE `t` (`id` int);\n-- Dump completed on 2016-10-31 12:27:25\n"
+	PgPlainDumpContents = "--\n-- PostgreSQL database dump\n--\nCREATE TABLE t (id integer);\n--\n-- PostgreSQL database dump complete\n--\n"
+)
+
+//NewTarGz --
+func NewTarGz(files map[string]string) []byte {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for name, contents := range files {
+		tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(contents))})
+		tarWriter.Write([]byte(contents))
+	}
+	tarWriter.Close()
+	gzipWriter.Close()
+	return buffer.Bytes()
+}
+
+//NewZip --
+func NewZip(files map[string]string) []byte {
+	var buffer bytes.Buffer
+	zipWriter := zip.NewWriter(&buffer)
+
+	for name, contents := range files {
+		w, _ := zipWriter.Create(name)
+		w.Write([]byte(contents))
+	}
+	zipWriter.Close()
+	return buffer.Bytes()
+}
+
+//NewPgCustomDump -- builds the header of a pg_dump -Fc archive (format 1.12) with the given toc entry count
+func NewPgCustomDump(tocCount byte) []byte {
+	const intSize = 4
+	var buffer bytes.Buffer
+	writeInt := func(v byte) {
+		buffer.Write([]byte{0, v, 0, 0, 0})
+	}
+	writeString := func(s string) {
+		writeInt(byte(len(s)))
+		buffer.WriteString(s)
+	}
+	buffer.WriteString("PGDMP")
+	buffer.Write([]byte{1, 12, 0, intSize, 8, 1})
+	writeInt(0)
+
+	for i := 0; i < 7; i++ {
+		writeInt(1)
+	}
+	writeString("ccdb")
+	writeString("9.4.6")
+	writeString("9.4.6")
+	writeInt(tocCount)
+	buffer.WriteString("table of contents and data")
+	return buffer.Bytes()
+}
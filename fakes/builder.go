@@ -0,0 +1,94 @@
+package fakes
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	"github.com/pivotalservices/cfbackup/tiles/opsmanager"
+	"github.com/pivotalservices/gtils/command"
+)
+
+//BuilderSpec - the tile spec the builder tests build their tiles from
+var BuilderSpec = tileregistry.TileSpec{OpsManagerHost: "opsman.example.com", ArchiveDirectory: "/backups"}
+
+//TileBuilderFixture - the setup the tile builder tests share: ops manager returns the installation settings at
+//InstallationSettingsPath, and the tile's remote executers are created by NewExecuter
+type TileBuilderFixture struct {
+	InstallationSettingsPath string
+	NewExecuter              func(command.SshConfig) command.Executer
+	RequestedSpec            tileregistry.TileSpec
+	SSHConfigs               []command.SshConfig
+	newRemoteExecuter        *func(command.SshConfig) (command.Executer, error)
+	originalRemoteExecuter   func(command.SshConfig) (command.Executer, error)
+	originalGetSettings      func(tileregistry.TileSpec) (io.Reader, error)
+}
+
+//NewTileBuilderFixture - a fixture serving the installation settings at installationSettingsPath. newRemoteExecuter is the
+//tile package's executer constructor, which Stub replaces, nil for a tile which does not ssh to its vms
+func NewTileBuilderFixture(installationSettingsPath string, newRemoteExecuter *func(command.SshConfig) (command.Executer, error)) *TileBuilderFixture {
+	return &TileBuilderFixture{
+		InstallationSettingsPath: installationSettingsPath,
+		NewExecuter:              func(command.SshConfig) command.Executer { return new(SuccessExecuter) },
+		newRemoteExecuter:        newRemoteExecuter,
+	}
+}
+
+//Stub - replaces opsmanager.GetInstallationSettings, and the tile's executer constructor, until Restore
+func (s *TileBuilderFixture) Stub() {
+	s.RequestedSpec = tileregistry.TileSpec{}
+	s.SSHConfigs = nil
+	s.originalGetSettings = opsmanager.GetInstallationSettings
+	opsmanager.GetInstallationSettings = func(tileSpec tileregistry.TileSpec) (io.Reader, error) {
+		s.RequestedSpec = tileSpec
+		return os.Open(s.InstallationSettingsPath)
+	}
+
+	if s.newRemoteExecuter != nil {
+		s.originalRemoteExecuter = *s.newRemoteExecuter
+		*s.newRemoteExecuter = func(config command.SshConfig) (command.Executer, error) {
+			s.SSHConfigs = append(s.SSHConfigs, config)
+			return s.NewExecuter(config), nil
+		}
+	}
+}
+
+//Restore - puts back what Stub replaced
+func (s *TileBuilderFixture) Restore() {
+	opsmanager.GetInstallationSettings = s.originalGetSettings
+
+	if s.newRemoteExecuter != nil {
+		*s.newRemoteExecuter = s.originalRemoteExecuter
+	}
+}
+
+//ItBuildsTilesFromOpsManager - the behaviour every builder of a tile read from the ops manager installation settings shares,
+//declared within the builder's Describe
+func ItBuildsTilesFromOpsManager(fixture *TileBuilderFixture, builder tileregistry.TileGenerator) {
+	BeforeEach(fixture.Stub)
+	AfterEach(fixture.Restore)
+
+	It("then it should build a tile whose backup and restore stop with their context", func() {
+		tile, err := builder.New(BuilderSpec)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, isContextTile := tile.(tileregistry.ContextTile)
+		Ω(isContextTile).Should(BeTrue())
+	})
+
+	It("then it should read the installation settings of the tileSpec's ops manager", func() {
+		_, err := builder.New(BuilderSpec)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(fixture.RequestedSpec.OpsManagerHost).Should(Equal(BuilderSpec.OpsManagerHost))
+	})
+
+	It("then it should return the error of an ops manager which does not return the installation settings", func() {
+		opsmanager.GetInstallationSettings = func(tileregistry.TileSpec) (io.Reader, error) {
+			return nil, errors.New("unauthorized")
+		}
+		_, err := builder.New(tileregistry.TileSpec{})
+		Ω(err).Should(MatchError("unauthorized"))
+	})
+}
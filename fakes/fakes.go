@@ -32,7 +32,7 @@ var (
 )
 
 //NewFakeDirector ---
-func NewFakeDirector(ip, username, password string, port int) bosh.Bosh {
+func NewFakeDirector(ip, username, password string, port int, clients *cfbackup.TLSClients) bosh.Bosh {
 	return &mockDirector{
 		getManifest:             true,
 		manifest:                strings.NewReader("manifest"),
@@ -267,6 +267,18 @@ var GetNfs = func(lf io.Writer, cmdexec command.Executer) *cfbackup.NFSBackup {
 	}
 }
 
+//GetRemoteCommandBackup ---
+var GetRemoteCommandBackup = func(lf io.Writer, cmdexec command.Executer, dumpCommand, restoreCommand string) *cfbackup.RemoteCommandBackup {
+	return &cfbackup.RemoteCommandBackup{
+		Caller: cmdexec,
+		RemoteOps: &mockRemoteOps{
+			Writer: lf,
+		},
+		DumpCommand:    dumpCommand,
+		RestoreCommand: restoreCommand,
+	}
+}
+
 var logger = getLogger("debug")
 
 //Logger ---
@@ -301,6 +313,10 @@ func (s *mockTile) Restore() error {
 	return s.ErrRestore
 }
 
+func (s *mockTile) Verify() (report cfbackup.VerificationReport, err error) {
+	return
+}
+
 //NewFakeBackupContext --
 func NewFakeBackupContext(target string, env map[string]string, storageProvider cfbackup.StorageProvider) (backupContext cfbackup.BackupContext) {
 	backupContext = cfbackup.NewBackupContext(target, env, "")
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"path"
 	"strings"
+
+	"github.com/xchapter7x/lo"
 )
 
 type (
@@ -16,13 +19,16 @@ type (
 	}
 	//CloudControllerDeploymentParser - a struct which will handle the parsing of deployments
 	CloudControllerDeploymentParser struct {
-		vms []CCJob
+		//Patterns - the job name patterns, in path.Match syntax, of the vms to stop. DefaultCCJobPatterns when empty
+		Patterns []string
+		vms      []CCJob
 	}
 )
 
-//GetCCVMs - a function to get a list of ccjobs
-func GetCCVMs(jsonObj []VMObject) ([]CCJob, error) {
-	parser := &CloudControllerDeploymentParser{}
+//GetCCVMs - a function to get a list of ccjobs, those whose job names match the given patterns or
+//DefaultCCJobPatterns when none are given
+func GetCCVMs(jsonObj []VMObject, patterns ...string) ([]CCJob, error) {
+	parser := &CloudControllerDeploymentParser{Patterns: patterns}
 	return parser.Parse(jsonObj)
 }
 
@@ -33,11 +39,21 @@ func (s *CloudControllerDeploymentParser) Parse(jsonObj []VMObject) ([]CCJob, er
 }
 
 func (s *CloudControllerDeploymentParser) setupAndRun(jsonObj []VMObject) (err error) {
-
 	var ccjobs = make([]CCJob, 0)
+	patterns := s.Patterns
+
+	if len(patterns) == 0 {
+		patterns = DefaultCCJobPatterns
+	}
 
 	for _, vmObject := range jsonObj {
-		if strings.Contains(vmObject.Job, "cloud_controller-partition") {
+		var matched bool
+
+		if matched, err = MatchCCJob(patterns, vmObject.Job); err != nil {
+			return
+		}
+
+		if matched {
 			ccJob := CCJob{
 				Job:   vmObject.Job,
 				Index: vmObject.Index,
@@ -47,12 +63,26 @@ func (s *CloudControllerDeploymentParser) setupAndRun(jsonObj []VMObject) (err e
 	}
 
 	if len(ccjobs) == 0 {
-		return fmt.Errorf("no cc jobs found")
+		lo.G.Warning(fmt.Sprintf("no cc jobs match %s, no vms will be stopped", strings.Join(patterns, ", ")))
 	}
 	s.vms = ccjobs
 	return nil
 }
 
+//MatchCCJob - true when the job name matches one of the patterns, an error when a pattern is malformed
+func MatchCCJob(patterns []string, jobName string) (matched bool, err error) {
+	for _, pattern := range patterns {
+		if matched, err = path.Match(pattern, jobName); err != nil {
+			return false, fmt.Errorf("cc job pattern %q: %s", pattern, err)
+		}
+
+		if matched {
+			return
+		}
+	}
+	return
+}
+
 //ReadAndUnmarshalVMObjects - read the io.reader and unmarshal its contents into an vmobject array
 func ReadAndUnmarshalVMObjects(src io.Reader) (jsonObj []VMObject, err error) {
 	var contents []byte
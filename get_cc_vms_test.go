@@ -28,7 +28,7 @@ var _ = Describe("get_cc_vms", func() {
 			})
 
 			It("Should have 4 cc correct jobs", func() {
-				vms, _ := GetCCVMs(jsonObj)
+				vms, _ := GetCCVMs(jsonObj, "cloud_controller-partition-*")
 				Ω(vms).Should(HaveLen(4))
 				Ω(vms[0].Index).Should(Equal(0))
 				Ω(vms[1].Index).Should(Equal(1))
@@ -41,6 +41,18 @@ var _ = Describe("get_cc_vms", func() {
 					GetCCVMs(jsonObj)
 				}).ShouldNot(Panic())
 			})
+
+			It("Should also return the jobs writing to ccdb and the blobstore by default", func() {
+				vms, _ := GetCCVMs(jsonObj)
+				Ω(vms).Should(HaveLen(6))
+				Ω(vms).Should(ContainElement(CCJob{Job: "cloud_controller_worker-partition-7bc61fd2fa9d654696df", Index: 0}))
+				Ω(vms).Should(ContainElement(CCJob{Job: "clock_global-partition-7bc61fd2fa9d654696df", Index: 0}))
+			})
+
+			It("Should return error for a malformed pattern", func() {
+				_, err := GetCCVMs(jsonObj, "cloud_controller[")
+				Ω(err).Should(MatchError(ContainSubstring(`cc job pattern "cloud_controller["`)))
+			})
 		})
 
 		Context("when cc job is not found", func() {
@@ -50,10 +62,10 @@ var _ = Describe("get_cc_vms", func() {
 				fileRef, _ = os.Open("fixtures/deployment_without_cc.json")
 				jsonObj, _ = ReadAndUnmarshalVMObjects(fileRef)
 			})
-			It("Should return error", func() {
-				vms, err := GetCCVMs(jsonObj)
-				Ω(err).ShouldNot(BeNil())
-				Ω(vms).Should(BeNil())
+			It("Should warn and return no jobs rather than fail", func() {
+				vms, err := GetCCVMs(jsonObj, "cloud_controller-partition-*")
+				Ω(err).Should(BeNil())
+				Ω(vms).Should(BeEmpty())
 			})
 		})
 	})
@@ -71,7 +83,7 @@ var _ = Describe("get_cc_vms", func() {
 				fileRef, _ = os.Open("fixtures/deployment_vms.json")
 				jsonObj, _ = ReadAndUnmarshalVMObjects(fileRef)
 
-				parser = &CloudControllerDeploymentParser{}
+				parser = &CloudControllerDeploymentParser{Patterns: []string{"cloud_controller-partition-*"}}
 			})
 
 			AfterEach(func() {
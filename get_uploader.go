@@ -1,15 +1,7 @@
 package cfbackup
 
-import (
-	ghttp "github.com/pivotalservices/gtils/http"
-)
-
-//GetUploader - returns an uploader from a given backup context
-func GetUploader(backupContext BackupContext) (uploader httpUploader) {
-	uploader = ghttp.LargeMultiPartUpload
-
-	if backupContext.IsS3 {
-		uploader = ghttp.MultiPartUpload
-	}
-	return
+//GetUploader - returns an uploader from a given backup context, it streams the archive to ops manager, verified
+//with the given clients, whether the context reads it from disk or s3
+func GetUploader(backupContext BackupContext, clients *TLSClients) (uploader httpUploader) {
+	return NewTLSUploader(TLSTargetOpsManager, clients)
 }
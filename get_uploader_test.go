@@ -13,14 +13,14 @@ var _ = Describe("GetUploader", func() {
 		Context("when the context is s3", func() {
 			It("then we should return a MultiPartUploader", func() {
 				bc := BackupContext{IsS3: true}
-				uploader := GetUploader(bc)
+				uploader := GetUploader(bc, nil)
 				Ω(uploader).Should(BeAssignableToTypeOf(ghttp.MultiPartUpload))
 			})
 		})
 		Context("when the context is NOT s3", func() {
 			It("then we should return a LargeMultiPartUploader", func() {
 				bc := BackupContext{IsS3: false}
-				uploader := GetUploader(bc)
+				uploader := GetUploader(bc, nil)
 				Ω(uploader).Should(BeAssignableToTypeOf(ghttp.LargeMultiPartUpload))
 			})
 		})
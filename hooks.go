@@ -0,0 +1,113 @@
+package cfbackup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pivotalservices/gtils/log"
+	"github.com/xchapter7x/lo"
+)
+
+//Run - calls the callback
+func (s HookFunc) Run(ctx context.Context, event HookEvent) error {
+	return s(ctx, event)
+}
+
+//NewExecutableHook - creates a hook which runs the executable at the given path
+func NewExecutableHook(path string, args ...string) *ExecutableHook {
+	return &ExecutableHook{Path: path, Args: args}
+}
+
+//Run - runs the executable, failing when it exits non zero
+func (s *ExecutableHook) Run(ctx context.Context, event HookEvent) (err error) {
+	var (
+		eventJSON []byte
+		output    bytes.Buffer
+	)
+
+	if eventJSON, err = json.Marshal(event); err != nil {
+		return
+	}
+	cmd := NewHookCommand(ctx, s.Path, s.Args...)
+	cmd.Stdin = bytes.NewReader(eventJSON)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", HookEventEnvVarname, eventJSON))
+
+	if err = cmd.Run(); err != nil {
+		err = fmt.Errorf("hook %s failed: %s: %s", s.Path, err, bytes.TrimSpace(output.Bytes()))
+	}
+	lo.G.Debug("hook output", log.Data{"hook": s.Path, "output": output.String()})
+	return
+}
+
+//BeforeTile - runs the pre tile hooks, returning ErrPreHookFailed if one of them fails
+func (s *HookSet) BeforeTile(ctx context.Context, event HookEvent) error {
+	if s == nil {
+		return nil
+	}
+	event.Point = HookPreTile
+	return runPreHooks(ctx, s.PreTile, event)
+}
+
+//AfterTile - runs the post tile hooks with the outcome of the tile
+func (s *HookSet) AfterTile(ctx context.Context, event HookEvent, runErr error) error {
+	if s == nil {
+		return nil
+	}
+	event.Point = HookPostTile
+	return runPostHooks(ctx, s.PostTile, event, runErr)
+}
+
+//BeforeComponent - runs the pre component hooks, returning ErrPreHookFailed if one of them fails
+func (s *HookSet) BeforeComponent(ctx context.Context, event HookEvent) error {
+	if s == nil {
+		return nil
+	}
+	event.Point = HookPreComponent
+	return runPreHooks(ctx, s.PreComponent, event)
+}
+
+//AfterComponent - runs the post component hooks with the outcome of the component
+func (s *HookSet) AfterComponent(ctx context.Context, event HookEvent, runErr error) error {
+	if s == nil {
+		return nil
+	}
+	event.Point = HookPostComponent
+	return runPostHooks(ctx, s.PostComponent, event, runErr)
+}
+
+func runPreHooks(ctx context.Context, hooks []Hook, event HookEvent) (err error) {
+	event.Time = time.Now()
+
+	for _, hook := range hooks {
+		if err = hook.Run(ctx, event); err != nil {
+			lo.G.Error("pre hook failed", log.Data{"point": event.Point, "tile": event.Tile, "component": event.Component, "error": err.Error()})
+			return ErrPreHookFailed
+		}
+	}
+	return
+}
+
+func runPostHooks(ctx context.Context, hooks []Hook, event HookEvent, runErr error) (err error) {
+	event.Time = time.Now()
+
+	if runErr != nil {
+		event.Error = runErr.Error()
+	}
+
+	for _, hook := range hooks {
+		if hookErr := hook.Run(ctx, event); hookErr != nil {
+			lo.G.Error("post hook failed", log.Data{"point": event.Point, "tile": event.Tile, "component": event.Component, "error": hookErr.Error()})
+
+			if err == nil {
+				err = hookErr
+			}
+		}
+	}
+	return
+}
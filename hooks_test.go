@@ -0,0 +1,108 @@
+package cfbackup_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotalservices/cfbackup"
+)
+
+var _ = Describe("hooks", func() {
+	var (
+		controlEvent = HookEvent{Action: HookActionBackup, Tile: "elastic-runtime", TargetDir: "/backups"}
+		events       []HookEvent
+		recordHook   = HookFunc(func(ctx context.Context, event HookEvent) error {
+			events = append(events, event)
+			return nil
+		})
+		failingHook = HookFunc(func(ctx context.Context, event HookEvent) error {
+			events = append(events, event)
+			return errors.New("hook failed")
+		})
+	)
+
+	BeforeEach(func() {
+		events = nil
+	})
+
+	Describe("given a HookSet", func() {
+		Context("when it is nil", func() {
+			It("then it should run nothing", func() {
+				var hooks *HookSet
+				Ω(hooks.BeforeTile(context.Background(), controlEvent)).Should(Succeed())
+				Ω(hooks.AfterComponent(context.Background(), controlEvent, nil)).Should(Succeed())
+			})
+		})
+
+		Context("when a pre hook fails", func() {
+			It("then it should abort without running the remaining hooks", func() {
+				hooks := &HookSet{PreTile: []Hook{failingHook, recordHook}}
+				Ω(hooks.BeforeTile(context.Background(), controlEvent)).Should(Equal(ErrPreHookFailed))
+				Ω(events).Should(HaveLen(1))
+				Ω(events[0].Point).Should(Equal(HookPreTile))
+			})
+		})
+
+		Context("when a post hook fails", func() {
+			It("then it should still run the remaining hooks", func() {
+				hooks := &HookSet{PostComponent: []Hook{failingHook, recordHook}}
+				Ω(hooks.AfterComponent(context.Background(), controlEvent, nil)).Should(HaveOccurred())
+				Ω(events).Should(HaveLen(2))
+			})
+		})
+
+		Context("when the run failed", func() {
+			It("then it should hand the error to the post hooks", func() {
+				hooks := &HookSet{PostTile: []Hook{recordHook}}
+				Ω(hooks.AfterTile(context.Background(), controlEvent, errors.New("dump failed"))).Should(Succeed())
+				Ω(events[0].Point).Should(Equal(HookPostTile))
+				Ω(events[0].Error).Should(Equal("dump failed"))
+			})
+		})
+	})
+
+	Describe("given an ExecutableHook", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir, _ = ioutil.TempDir("", "hooks")
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		writeHook := func(script string) *ExecutableHook {
+			hookPath := path.Join(dir, "hook")
+			ioutil.WriteFile(hookPath, []byte("#!/bin/sh\n"+script), 0755)
+			return NewExecutableHook(hookPath, path.Join(dir, "event.json"))
+		}
+
+		Context("when the executable succeeds", func() {
+			It("then it should be given the event as json on stdin and in its environment", func() {
+				hook := writeHook("cat > \"$1\"\n[ -n \"$" + HookEventEnvVarname + "\" ]\n")
+				Ω(hook.Run(context.Background(), controlEvent)).Should(Succeed())
+				var event HookEvent
+				contents, _ := ioutil.ReadFile(path.Join(dir, "event.json"))
+				Ω(json.Unmarshal(contents, &event)).Should(Succeed())
+				Ω(event.Tile).Should(Equal(controlEvent.Tile))
+				Ω(event.Action).Should(Equal(HookActionBackup))
+			})
+		})
+
+		Context("when the executable exits non zero", func() {
+			It("then it should return an error with its output", func() {
+				hook := writeHook("echo maintenance page unavailable\nexit 3\n")
+				err := hook.Run(context.Background(), controlEvent)
+				Ω(err).Should(HaveOccurred())
+				Ω(err.Error()).Should(ContainSubstring("maintenance page unavailable"))
+			})
+		})
+	})
+})
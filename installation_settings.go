@@ -18,6 +18,14 @@ var (
 		"1.4": legacyPGDumpBin,
 		"":    legacyPGDumpBin,
 	}
+	legacyCCJobPatterns = []string{"cloud_controller-partition-*", "cloud_controller_worker-partition-*", "clock_global-partition-*"}
+	ccJobPatterns       = map[string][]string{
+		"1.7": legacyCCJobPatterns,
+		"1.6": legacyCCJobPatterns,
+		"1.5": legacyCCJobPatterns,
+		"1.4": legacyCCJobPatterns,
+		"":    legacyCCJobPatterns,
+	}
 	pgRestoreBin = map[string]string{
 		"1.6": v942PGRestoreBin,
 		"1.5": legacyPGRestoreBin,
@@ -47,6 +55,7 @@ func (s *InstallationSettings) FindPropertyValues(productName, jobName, identifi
 	if product, err = s.FindByProductID(productName); err == nil {
 		if job, err = s.FindJobByProductAndJobName(productName, jobName); err == nil {
 			propertyMap = product.GetPropertyValues(job, identifier)
+			RedactSecretProperties(propertyMap)
 		}
 	}
 	return
@@ -67,6 +76,7 @@ func (s *InstallationSettings) FindVMCredentialsByProductAndJob(productName, job
 	if product, err = s.FindByProductID(productName); err == nil {
 		vmCredentials, err = product.GetVMCredentialsByJob(jobName)
 		vmCredentials.SSLKey = s.Infrastructure.IaaSConfig.SSHPrivateKey
+		RedactSecrets(vmCredentials.Password, vmCredentials.SSLKey)
 	}
 	return
 }
@@ -198,6 +208,15 @@ func (s *InstallationSettings) GetBoshName() (boshName string) {
 	return
 }
 
+//GetCCJobPatterns - returns the patterns matching the jobs to stop during backup and restore for the installation's version
+func (s *InstallationSettings) GetCCJobPatterns() (patterns []string) {
+	var ok bool
+	if patterns, ok = ccJobPatterns[s.Version]; !ok {
+		patterns = DefaultCCJobPatterns
+	}
+	return
+}
+
 //SetPGDumpUtilVersions - initializes the correct dump commands
 func (s *InstallationSettings) SetPGDumpUtilVersions() {
 	var ok bool
@@ -21,6 +21,10 @@ var _ = Describe("given a InstallationSettings object", func() {
 		checkBoshName("./fixtures/installation-settings-1-4.json", "microbosh")
 		checkBoshName("./fixtures/installation-settings-1-4-variant.json", "microbosh")
 
+		checkCCJobPatterns(InstallationSettings{Version: "1.7"}, "cloud_controller-partition-*")
+		checkCCJobPatterns(InstallationSettings{Version: "1.4"}, "cloud_controller-partition-*")
+		checkCCJobPatterns(InstallationSettings{Version: "1.8"}, "cloud_controller")
+
 		checkInstallationSettingsIPMethods("./fixtures/installation-settings-1-7-multiaz-unbalanced.json", "cf", "nfs_server", 1)
 		checkInstallationSettingsIPMethods("./fixtures/installation-settings-1-7-multiaz-unbalanced.json", "p-bosh", "director", 1)
 		checkInstallationSettingsIPMethods("./fixtures/installation-settings-1-7-pgsql.json", "cf", "nfs_server", 1)
@@ -100,6 +104,18 @@ func checkBoshName(fixturePath string, expectedBoshName string) {
 	})
 }
 
+func checkCCJobPatterns(installationSettings InstallationSettings, expectedPattern string) {
+	Context(fmt.Sprintf("when called with installation schema version %s", installationSettings.Version), func() {
+		It("then it should return the cc job patterns of that version, including the jobs writing to ccdb", func() {
+			patterns := installationSettings.GetCCJobPatterns()
+			Ω(patterns).Should(ContainElement(expectedPattern))
+			matched, err := MatchCCJob(patterns, "clock_global-partition-7bc61fd2fa9d654696df")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(matched).Should(BeTrue())
+		})
+	})
+}
+
 func checkInstallationSettingsIPMethods(fixturePath string, productName string, jobName string, ipsCount int) {
 	Context(fmt.Sprintf("when called with a given %s fixture", fixturePath), func() {
 		var installationSettings InstallationSettings
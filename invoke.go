@@ -1,27 +0,0 @@
-package cfbackup
-
-import (
-	"crypto/tls"
-	"fmt"
-	"net/http"
-)
-
-func invoke(method string, connectionURL string, username string, password string, isYaml bool) (*http.Response, error) {
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-
-	req, err := http.NewRequest(method, connectionURL, nil)
-	req.SetBasicAuth(username, password)
-
-	if isYaml {
-		req.Header.Set("Content-Type", "text/yaml")
-	}
-
-	resp, err := tr.RoundTrip(req)
-	if err != nil {
-		fmt.Printf("Error : %s", err)
-	}
-
-	return resp, err
-}
@@ -0,0 +1,237 @@
+package cfbackup
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/xchapter7x/lo"
+)
+
+var leaseKeyUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+//NewLeaseLock - creates the run lock for the given key, stored in the given directory of the storage provider
+func NewLeaseLock(storageProvider StorageProvider, key string, dir ...string) *LeaseLock {
+	hostname, _ := os.Hostname()
+	return &LeaseLock{
+		StorageProvider: storageProvider,
+		Path:            append(append([]string{}, dir...), fmt.Sprintf(LeaseFileFormat, leaseKeyUnsafe.ReplaceAllString(key, "_"))),
+		Key:             key,
+		Owner:           fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		TTL:             DefaultLeaseTTL,
+	}
+}
+
+//Acquire - takes the lock, failing with ErrLeaseHeld while another run holds a live lease unless Force is set.
+//A lease which can not be read, other than one which does not exist yet, fails it too unless Force is set.
+//Stale and released leases are taken over. A StorageProvider can not create a file only if it is missing,
+//so the lease is read back after LeaseSettleTime to catch a run which wrote its own at the same time.
+//Once acquired, the lease is renewed by heartbeats until it is released.
+func (s *LeaseLock) Acquire() (err error) {
+	var current *Lease
+
+	if s == nil {
+		return
+	}
+
+	if current, err = s.read(); err != nil && !s.Force {
+		return
+	}
+
+	if current != nil && current.Held(time.Now()) {
+		if !s.Force {
+			return fmt.Errorf("%w: %s has held it since %s", ErrLeaseHeld, current.Owner, current.Acquired.Format(time.RFC3339))
+		}
+		lo.G.Warning(fmt.Sprintf("breaking the run lock %s held by %s", s.Key, current.Owner))
+
+	} else if current != nil && !current.Released {
+		lo.G.Info(fmt.Sprintf("taking over the stale run lock %s of %s, last renewed %s", s.Key, current.Owner, current.Renewed.Format(time.RFC3339)))
+	}
+	now := time.Now()
+	lease := &Lease{
+		Key:      s.Key,
+		Owner:    s.Owner,
+		Token:    newLeaseToken(),
+		Acquired: now,
+		Renewed:  now,
+		TTL:      s.TTL,
+	}
+
+	if err = s.write(lease); err != nil {
+		return
+	}
+	time.Sleep(LeaseSettleTime)
+
+	if current, err = s.read(); err == nil && (current == nil || current.Token != lease.Token) {
+		err = ErrLeaseHeld
+
+		if current != nil {
+			err = fmt.Errorf("%w: %s took it at the same time", ErrLeaseHeld, current.Owner)
+		}
+	}
+
+	if err == nil {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.lease = lease
+		s.lost = make(chan struct{})
+		s.stop = make(chan struct{})
+		s.stopped = make(chan struct{})
+		go s.heartbeat(lease, s.stop)
+	}
+	return
+}
+
+//Release - stops the heartbeats and marks the lease released, unless another run took it over in the meantime
+func (s *LeaseLock) Release() (err error) {
+	var current *Lease
+
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	lease := s.lease
+	s.lease = nil
+	s.mutex.Unlock()
+
+	if lease == nil {
+		return
+	}
+	close(s.stop)
+	<-s.stopped
+
+	if current, err = s.read(); err == nil && current != nil && current.Token == lease.Token {
+		lease.Released = true
+		err = s.write(lease)
+	}
+	return
+}
+
+//Lost - closed when another run takes over the lease while it is held
+func (s *LeaseLock) Lost() <-chan struct{} {
+	if s == nil {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lost
+}
+
+//WithContext - derives a context which is cancelled when the lease is lost
+func (s *LeaseLock) WithContext(parent context.Context) (ctx context.Context, cancel context.CancelFunc) {
+	ctx, cancel = context.WithCancel(parent)
+
+	if lost := s.Lost(); lost != nil {
+		go func() {
+			select {
+			case <-lost:
+				lo.G.Error("cancelling the run, its lock was lost", s.Key)
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return
+}
+
+//Run - runs the given function while holding the lock, with a context which is cancelled if the lock is lost
+func (s *LeaseLock) Run(ctx context.Context, run func(ctx context.Context) error) (err error) {
+	if err = s.Acquire(); err != nil {
+		lo.G.Error("failed to acquire the run lock", err)
+		return
+	}
+	defer func() {
+		if releaseErr := s.Release(); releaseErr != nil {
+			lo.G.Error("failed to release the run lock", releaseErr)
+		}
+	}()
+	runCtx, cancel := s.WithContext(ctx)
+	defer cancel()
+
+	err = run(runCtx)
+
+	select {
+	case <-s.Lost():
+		err = ErrLeaseLost
+	default:
+	}
+	return
+}
+
+//Held - true while the lease is neither released nor past its ttl
+func (s *Lease) Held(now time.Time) bool {
+	return !s.Released && now.Before(s.Renewed.Add(s.TTL))
+}
+
+func (s *LeaseLock) heartbeat(lease *Lease, stop chan struct{}) {
+	defer close(s.stopped)
+	interval := s.Heartbeat
+
+	if interval <= 0 {
+		interval = s.TTL / 3
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if current, err := s.read(); err == nil && current != nil && current.Token != lease.Token {
+				lo.G.Error(fmt.Sprintf("run lock %s was taken over by %s", s.Key, current.Owner))
+				close(s.lost)
+				return
+			}
+			lease.Renewed = time.Now()
+
+			if err := s.write(lease); err != nil {
+				lo.G.Error("failed to renew the run lock", err)
+			}
+		}
+	}
+}
+
+func (s *LeaseLock) read() (lease *Lease, err error) {
+	var reader io.ReadCloser
+
+	if reader, err = s.StorageProvider.Reader(s.Path...); errors.Is(err, os.ErrNotExist) {
+		lo.G.Debug("no run lock found", s.Path, err)
+		return nil, nil
+
+	} else if err != nil {
+		lo.G.Error("unable to read the run lock", s.Path, err)
+		return
+	}
+	defer reader.Close()
+	lease = new(Lease)
+
+	if err = json.NewDecoder(reader).Decode(lease); err != nil {
+		lo.G.Error("unreadable run lock", s.Path, err)
+		lease = nil
+	}
+	return
+}
+
+func (s *LeaseLock) write(lease *Lease) (err error) {
+	var writer io.WriteCloser
+
+	if writer, err = s.StorageProvider.Writer(s.Path...); err == nil {
+		defer writer.Close()
+		err = json.NewEncoder(writer).Encode(lease)
+	}
+	return
+}
+
+func newLeaseToken() string {
+	token := make([]byte, 16)
+	rand.Read(token)
+	return hex.EncodeToString(token)
+}
@@ -0,0 +1,129 @@
+package cfbackup_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	cfenv "github.com/cloudfoundry-community/go-cfenv"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotalservices/cfbackup"
+)
+
+type failingLeaseStorage struct {
+	StorageProvider
+	err    error
+	writes int
+}
+
+func (s *failingLeaseStorage) Reader(path ...string) (io.ReadCloser, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.StorageProvider.Reader(path...)
+}
+
+func (s *failingLeaseStorage) Writer(path ...string) (io.WriteCloser, error) {
+	s.writes++
+	return s.StorageProvider.Writer(path...)
+}
+
+var _ = Describe("LeaseLock", func() {
+	var (
+		target             string
+		backupSet          BackupContext
+		first, second      *LeaseLock
+		originalSettleTime time.Duration
+		newLock            = func() *LeaseLock {
+			lock := NewLeaseLock(backupSet, "opsman.example.com", target)
+			lock.Heartbeat = 10 * time.Millisecond
+			return lock
+		}
+	)
+
+	BeforeEach(func() {
+		originalSettleTime = LeaseSettleTime
+		LeaseSettleTime = 0
+		target, _ = ioutil.TempDir("", "lease")
+		backupSet = NewBackupContext(target, cfenv.CurrentEnv(), "")
+		first, second = newLock(), newLock()
+	})
+
+	AfterEach(func() {
+		first.Release()
+		second.Release()
+		LeaseSettleTime = originalSettleTime
+		os.RemoveAll(target)
+	})
+
+	Context("when no run holds the lock", func() {
+		It("then it should store a lease keyed by the foundation", func() {
+			Ω(first.Acquire()).Should(Succeed())
+			_, err := os.Stat(path.Join(target, "opsman.example.com.lock"))
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Context("when the lock can not be read", func() {
+		It("then it should fail to acquire it rather than take it as free", func() {
+			storage := &failingLeaseStorage{StorageProvider: backupSet, err: errors.New("blobstore unavailable")}
+			lock := NewLeaseLock(storage, "opsman.example.com", target)
+			Ω(lock.Acquire()).Should(MatchError("blobstore unavailable"))
+			Ω(storage.writes).Should(Equal(0))
+		})
+	})
+
+	Context("when another run holds the lock", func() {
+		BeforeEach(func() {
+			Ω(first.Acquire()).Should(Succeed())
+		})
+
+		It("then it should fail to acquire it", func() {
+			err := second.Acquire()
+			Ω(errors.Is(err, ErrLeaseHeld)).Should(BeTrue())
+		})
+
+		It("then it should acquire it once it is released", func() {
+			Ω(first.Release()).Should(Succeed())
+			Ω(second.Acquire()).Should(Succeed())
+		})
+
+		It("then it should break it when forced, and the other run should notice it lost the lock", func() {
+			second.Force = true
+			Ω(second.Acquire()).Should(Succeed())
+			Eventually(first.Lost()).Should(BeClosed())
+		})
+	})
+
+	Context("when the run holding the lock stopped renewing it", func() {
+		It("then it should take over the stale lease", func() {
+			first.TTL = time.Millisecond
+			first.Heartbeat = time.Hour
+			Ω(first.Acquire()).Should(Succeed())
+			time.Sleep(5 * time.Millisecond)
+			Ω(second.Acquire()).Should(Succeed())
+		})
+	})
+
+	Context("when running a function under the lock", func() {
+		It("then it should cancel the function and fail when the lock is lost", func() {
+			err := first.Run(context.Background(), func(ctx context.Context) error {
+				second.Force = true
+				Ω(second.Acquire()).Should(Succeed())
+				<-ctx.Done()
+				return ctx.Err()
+			})
+			Ω(err).Should(Equal(ErrLeaseLost))
+		})
+
+		It("then it should release the lock when the function returns", func() {
+			Ω(first.Run(context.Background(), func(context.Context) error { return nil })).Should(Succeed())
+			Ω(second.Acquire()).Should(Succeed())
+		})
+	})
+})
@@ -0,0 +1,35 @@
+package metrics
+
+const (
+	//LastSuccessMetric -- unix time of the last successful run of a tile or component
+	LastSuccessMetric = "cfbackup_last_success_timestamp_seconds"
+	//DurationMetric -- duration of the last run of a tile or component
+	DurationMetric = "cfbackup_duration_seconds"
+	//ArtifactBytesMetric -- size of the artifact written or read by the last run of a component
+	ArtifactBytesMetric = "cfbackup_artifact_bytes"
+	//FailuresMetric -- number of failed runs of a tile or component
+	FailuresMetric = "cfbackup_failures_total"
+	//CCDowntimeMetric -- how long the cloud controllers were stopped during the last run of a tile
+	CCDowntimeMetric = "cfbackup_cc_downtime_seconds"
+
+	//TileLabel -- label holding the tile name
+	TileLabel = "tile"
+	//ComponentLabel -- label holding the component name, empty for the whole tile
+	ComponentLabel = "component"
+	//ActionLabel -- label holding the backup or restore action
+	ActionLabel = "action"
+
+	//MetricsPath -- path the metrics endpoint is served on
+	MetricsPath = "/metrics"
+
+	gaugeType   = "gauge"
+	counterType = "counter"
+)
+
+var families = map[string]family{
+	LastSuccessMetric:   {help: "Unix time of the last successful backup or restore.", kind: gaugeType},
+	DurationMetric:      {help: "Duration of the last backup or restore in seconds.", kind: gaugeType},
+	ArtifactBytesMetric: {help: "Size of the artifact handled by the last backup or restore in bytes.", kind: gaugeType},
+	FailuresMetric:      {help: "Number of failed backups or restores.", kind: counterType},
+	CCDowntimeMetric:    {help: "Seconds the cloud controllers were stopped during the last backup or restore.", kind: gaugeType},
+}
@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	"github.com/xchapter7x/lo"
+)
+
+//NewRegistry - creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{samples: make(map[string]map[labels]float64)}
+}
+
+//ObserveRun - records the outcome of a run of a tile which does not keep a run report
+func (s *Registry) ObserveRun(tile string, action string, started time.Time, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.observe(labels{tile: tile, action: action}, started, time.Now(), err == nil)
+}
+
+//ObserveRunReport - records the outcome of a run and each of its components from the run's report
+func (s *Registry) ObserveRunReport(report *cfbackup.RunReport) {
+	if report == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	tileLabels := labels{tile: report.Tile, action: report.Action}
+	s.observe(tileLabels, report.Started, report.Finished, report.Status == cfbackup.RunStatusSucceeded)
+	s.set(CCDowntimeMetric, tileLabels, report.CCDowntime().Seconds())
+
+	for _, component := range report.Components {
+		componentLabels := labels{tile: report.Tile, component: component.Name, action: report.Action}
+		s.observe(componentLabels, component.Started, component.Finished, component.Status == cfbackup.RunStatusSucceeded)
+		s.set(ArtifactBytesMetric, componentLabels, float64(component.Bytes))
+	}
+}
+
+//ObserveTile - records the outcome of a tile's run, from its run report when it keeps one
+func (s *Registry) ObserveTile(name string, tile tileregistry.Tile, action string, started time.Time, err error) {
+	if report := tileregistry.GetRunReport(tile); report != nil {
+		s.ObserveRunReport(report)
+	} else {
+		s.ObserveRun(name, action, started, err)
+	}
+}
+
+//WriteTo - writes every metric in the prometheus text exposition format
+func (s *Registry) WriteTo(w io.Writer) (n int64, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	buffer := bufio.NewWriter(w)
+	counter := &countingWriter{writer: buffer}
+
+	for _, name := range sortedKeys(s.samples) {
+		fmt.Fprintf(counter, "# HELP %s %s\n", name, families[name].help)
+		fmt.Fprintf(counter, "# TYPE %s %s\n", name, families[name].kind)
+
+		for _, sampleLabels := range sortedLabels(s.samples[name]) {
+			fmt.Fprintf(counter, "%s{%s} %s\n", name, sampleLabels, strconv.FormatFloat(s.samples[name][sampleLabels], 'g', -1, 64))
+		}
+	}
+
+	if err = buffer.Flush(); err == nil {
+		err = counter.err
+	}
+	return counter.n, err
+}
+
+//WriteTextfile - writes the metrics to the given file for the node exporter's textfile collector.
+//The file is written next to its destination and renamed, so the collector never reads a partial file.
+func (s *Registry) WriteTextfile(filename string) (err error) {
+	var tmpfile *os.File
+
+	if tmpfile, err = ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)); err != nil {
+		return
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err = s.WriteTo(tmpfile); err == nil {
+		err = tmpfile.Chmod(0644)
+	}
+
+	if closeErr := tmpfile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmpfile.Name(), filename)
+	}
+	return
+}
+
+//ServeHTTP - serves the metrics in the prometheus text exposition format
+func (s *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	if _, err := s.WriteTo(w); err != nil {
+		lo.G.Error("failed to write metrics", err)
+	}
+}
+
+//ListenAndServe - serves the metrics endpoint on the given address, for long running modes
+func (s *Registry) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, s)
+	return http.ListenAndServe(addr, mux)
+}
+
+func (s *Registry) observe(sampleLabels labels, started time.Time, finished time.Time, succeeded bool) {
+	s.set(DurationMetric, sampleLabels, finished.Sub(started).Seconds())
+
+	if succeeded {
+		s.set(LastSuccessMetric, sampleLabels, float64(finished.Unix()))
+	} else {
+		s.add(FailuresMetric, sampleLabels, 1)
+	}
+}
+
+func (s *Registry) set(name string, sampleLabels labels, value float64) {
+	if s.samples[name] == nil {
+		s.samples[name] = make(map[labels]float64)
+	}
+	s.samples[name][sampleLabels] = value
+}
+
+func (s *Registry) add(name string, sampleLabels labels, value float64) {
+	s.set(name, sampleLabels, s.samples[name][sampleLabels]+value)
+}
+
+func (s *countingWriter) Write(p []byte) (n int, err error) {
+	if s.err == nil {
+		n, s.err = s.writer.Write(p)
+		s.n += int64(n)
+	}
+	return n, s.err
+}
+
+func (s labels) String() string {
+	return fmt.Sprintf("%s=%s,%s=%s,%s=%s", TileLabel, quote(s.tile), ComponentLabel, quote(s.component), ActionLabel, quote(s.action))
+}
+
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+func sortedKeys(samples map[string]map[labels]float64) (names []string) {
+	for name := range samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+func sortedLabels(samples map[labels]float64) (sampleLabels []labels) {
+	for l := range samples {
+		sampleLabels = append(sampleLabels, l)
+	}
+	sort.Slice(sampleLabels, func(i, j int) bool {
+		return sampleLabels[i].String() < sampleLabels[j].String()
+	})
+	return
+}
@@ -0,0 +1,113 @@
+package metrics_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
+	. "github.com/pivotalservices/cfbackup/metrics"
+	"github.com/pivotalservices/cfbackup/tileregistry/fake"
+)
+
+var _ = Describe("Registry", func() {
+	var (
+		registry *Registry
+		started  = time.Unix(1477900000, 0)
+		report   *cfbackup.RunReport
+	)
+
+	BeforeEach(func() {
+		registry = NewRegistry()
+		report = &cfbackup.RunReport{
+			Tile:     "elastic-runtime",
+			Action:   cfbackup.HookActionBackup,
+			Status:   cfbackup.RunStatusFailed,
+			Started:  started,
+			Finished: started.Add(90 * time.Second),
+			Components: []*cfbackup.ComponentReport{
+				{Name: "ccdb", Status: cfbackup.RunStatusSucceeded, Started: started, Finished: started.Add(30 * time.Second), Bytes: 2048},
+				{Name: "uaadb", Status: cfbackup.RunStatusFailed, Started: started, Finished: started.Add(5 * time.Second)},
+			},
+			CCTasks: []cfbackup.CCTaskReport{
+				{State: cfbackup.CCJobStopped, Finished: started.Add(10 * time.Second)},
+				{State: cfbackup.CCJobStarted, Finished: started.Add(85 * time.Second)},
+			},
+		}
+	})
+
+	exposition := func() string {
+		var buffer bytes.Buffer
+		registry.WriteTo(&buffer)
+		return buffer.String()
+	}
+
+	Describe("given an ObserveRunReport() method", func() {
+		Context("when the report has components", func() {
+			It("then it should record the tile and component metrics", func() {
+				registry.ObserveRunReport(report)
+				metrics := exposition()
+				Ω(metrics).Should(ContainSubstring("# TYPE cfbackup_failures_total counter\n"))
+				Ω(metrics).Should(ContainSubstring(`cfbackup_duration_seconds{tile="elastic-runtime",component="",action="backup"} 90` + "\n"))
+				Ω(metrics).Should(ContainSubstring(`cfbackup_artifact_bytes{tile="elastic-runtime",component="ccdb",action="backup"} 2048` + "\n"))
+				Ω(metrics).Should(ContainSubstring(`cfbackup_last_success_timestamp_seconds{tile="elastic-runtime",component="ccdb",action="backup"} 1.47790003e+09` + "\n"))
+				Ω(metrics).Should(ContainSubstring(`cfbackup_failures_total{tile="elastic-runtime",component="uaadb",action="backup"} 1` + "\n"))
+				Ω(metrics).Should(ContainSubstring(`cfbackup_cc_downtime_seconds{tile="elastic-runtime",component="",action="backup"} 75` + "\n"))
+			})
+		})
+
+		Context("when the same component fails twice", func() {
+			It("then it should count both failures", func() {
+				registry.ObserveRunReport(report)
+				registry.ObserveRunReport(report)
+				Ω(exposition()).Should(ContainSubstring(`cfbackup_failures_total{tile="elastic-runtime",component="uaadb",action="backup"} 2` + "\n"))
+			})
+		})
+	})
+
+	Describe("given an ObserveRun() method", func() {
+		It("then it should record the tile's outcome", func() {
+			registry.ObserveRun("p-mysql", cfbackup.HookActionRestore, time.Now(), errors.New("failed"))
+			Ω(exposition()).Should(ContainSubstring(`cfbackup_failures_total{tile="p-mysql",component="",action="restore"} 1` + "\n"))
+		})
+	})
+
+	Describe("given an ObserveTile() method", func() {
+		Context("when the tile does not keep a run report", func() {
+			It("then it should record the run under the given name", func() {
+				registry.ObserveTile("p-redis", new(fake.Tile), cfbackup.HookActionBackup, time.Now(), nil)
+				Ω(exposition()).Should(ContainSubstring(`cfbackup_last_success_timestamp_seconds{tile="p-redis",component="",action="backup"}`))
+			})
+		})
+	})
+
+	Describe("given a WriteTextfile() method", func() {
+		It("then it should write the metrics to the given file", func() {
+			dir, _ := ioutil.TempDir("", "metrics")
+			defer os.RemoveAll(dir)
+			registry.ObserveRunReport(report)
+			filename := path.Join(dir, "cfbackup.prom")
+			Ω(registry.WriteTextfile(filename)).Should(Succeed())
+			contents, _ := ioutil.ReadFile(filename)
+			Ω(string(contents)).Should(Equal(exposition()))
+			files, _ := ioutil.ReadDir(dir)
+			Ω(files).Should(HaveLen(1))
+		})
+	})
+
+	Describe("given a ServeHTTP() method", func() {
+		It("then it should serve the metrics in the text format", func() {
+			registry.ObserveRunReport(report)
+			recorder := httptest.NewRecorder()
+			registry.ServeHTTP(recorder, httptest.NewRequest("GET", MetricsPath, nil))
+			Ω(recorder.Header().Get("Content-Type")).Should(ContainSubstring("text/plain"))
+			Ω(recorder.Body.String()).Should(Equal(exposition()))
+		})
+	})
+})
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"io"
+	"sync"
+)
+
+type (
+	//Registry - holds the backup run metrics, written out in the prometheus text format
+	Registry struct {
+		mutex   sync.Mutex
+		samples map[string]map[labels]float64
+	}
+
+	labels struct {
+		tile      string
+		component string
+		action    string
+	}
+
+	countingWriter struct {
+		writer io.Writer
+		n      int64
+		err    error
+	}
+
+	family struct {
+		help string
+		kind string
+	}
+)
@@ -1,13 +1,12 @@
 package cfbackup
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 
 	"github.com/pivotalservices/gtils/command"
 	"github.com/pivotalservices/gtils/osutils"
-	"github.com/xchapter7x/lo"
 )
 
 const (
@@ -39,24 +38,22 @@ func NewNFSBackup(username, password, ip, sslKey, remoteArchivePath, backupType
 
 //Dump - will dump the output of a executed command to the given writer
 func (s *NFSBackup) Dump(dest io.Writer) (err error) {
-	err = s.Caller.Execute(dest, s.getDumpCommand())
-	return
+	return s.DumpWithContext(context.Background(), dest)
+}
+
+//DumpWithContext - dumps the nfs store to the given writer, stopping when the context is done
+func (s *NFSBackup) DumpWithContext(ctx context.Context, dest io.Writer) (err error) {
+	return ExecuteWithContext(ctx, s.Caller, dest, s.getDumpCommand())
 }
 
 //Import - will upload the contents of the given io.reader to the remote execution target and execute the restore command against the uploaded file.
 func (s *NFSBackup) Import(lfile io.Reader) (err error) {
-	lo.G.Debug("uploading file for backup")
-	if err = s.RemoteOps.UploadFile(lfile); err == nil {
-		lo.G.Debug("starting backup from %s", s.RemoteOps.Path())
-		err = s.Caller.Execute(ioutil.Discard, s.getRestoreCommand())
-	}
-	if err == nil {
-		lo.G.Debug("backup from %s completed", s.RemoteOps.Path())
-	} else {
-		lo.G.Debug("backup from %s completed with error %s", s.RemoteOps.Path(), err)
-	}
-	s.RemoteOps.RemoveRemoteFile()
-	return
+	return s.ImportWithContext(context.Background(), lfile)
+}
+
+//ImportWithContext - uploads and restores the given archive, stopping when the context is done. The uploaded archive is always removed.
+func (s *NFSBackup) ImportWithContext(ctx context.Context, lfile io.Reader) (err error) {
+	return remoteImport(ctx, s.Caller, s.RemoteOps, s.getRestoreCommand(), lfile)
 }
 
 func (s *NFSBackup) getRestoreCommand() string {
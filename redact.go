@@ -0,0 +1,161 @@
+package cfbackup
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	logging "github.com/op/go-logging"
+	"github.com/xchapter7x/lo"
+)
+
+const secretNamePattern = `(?:password|passwd|passphrase|pass|secret(?:_?access_?key)?|token|private_?key|crypt_?key)`
+
+var (
+	secretProperty = regexp.MustCompile(`(?i)` + secretNamePattern + `$`)
+	secretPatterns = []struct {
+		pattern     *regexp.Regexp
+		replacement string
+	}{
+		{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`), RedactedValue},
+		{regexp.MustCompile(`(?i)(authorization["']?\s*[:=]\s*["']?(?:(?:bearer|basic|token)\s+)?)[^"'\s,}\]&]+`), "${1}" + RedactedValue},
+		{regexp.MustCompile(`(?i)(\w*` + secretNamePattern + `["']?\s*[:=]\s*["']?)[^"'\s,}\]&]+`), "${1}" + RedactedValue},
+	}
+	//DefaultRedactor - the redactor every log line written through lo.G passes through
+	DefaultRedactor = NewRedactor()
+)
+
+func init() {
+	if RedactLogsEnabled() {
+		RedactLogs(logging.NewLogBackend(os.Stderr, "", 0))
+	}
+}
+
+//RedactLogsEnabled - false only when the RedactLogsEnvVarname environment variable opts out of redacting lo.G output,
+//eg. CFBACKUP_REDACT_LOGS=false
+func RedactLogsEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv(RedactLogsEnvVarname))
+	return enabled || err != nil
+}
+
+//RedactLogs - sends the output of lo.G to the given backend through the DefaultRedactor, formatted with the logging
+//package's DefaultFormatter unless the backend brings its own formatter. Importing the package redacts lo.G output
+//to stderr, unless RedactLogsEnabled is false
+func RedactLogs(backend logging.Backend) {
+	lo.G.SetBackend(&RedactingBackend{Backend: logging.NewBackendFormatter(backend, logging.DefaultFormatter), Redactor: DefaultRedactor})
+}
+
+//RedactSecrets - adds secrets resolved during the run to the DefaultRedactor
+func RedactSecrets(secrets ...string) {
+	DefaultRedactor.Add(secrets...)
+}
+
+//RedactSecretProperties - adds the values of secret looking properties, eg. password or secret, to the DefaultRedactor
+func RedactSecretProperties(properties map[string]string) {
+	for name, value := range properties {
+		if secretProperty.MatchString(name) {
+			DefaultRedactor.Add(value)
+		}
+	}
+}
+
+//NewRedactor - creates a redactor which knows no secrets yet
+func NewRedactor() *Redactor {
+	return &Redactor{secrets: make(map[string]struct{})}
+}
+
+//Add - makes the redactor mask the given secrets, safe for concurrent use
+func (s *Redactor) Add(secrets ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	added := false
+
+	for _, secret := range secrets {
+		if _, known := s.secrets[secret]; len(secret) >= RedactMinSecretLength && !known {
+			s.secrets[secret] = struct{}{}
+			added = true
+		}
+	}
+
+	if added {
+		s.replacer = s.newReplacer()
+	}
+}
+
+//Redact - the text with every known secret, and the values of secret looking properties, headers and private keys, masked
+func (s *Redactor) Redact(text string) string {
+	s.mutex.RLock()
+	replacer := s.replacer
+	s.mutex.RUnlock()
+
+	if replacer != nil {
+		text = replacer.Replace(text)
+	}
+
+	for _, secret := range secretPatterns {
+		text = secret.pattern.ReplaceAllString(text, secret.replacement)
+	}
+	return text
+}
+
+func (s *Redactor) newReplacer() *strings.Replacer {
+	var secrets, oldnew []string
+
+	for secret := range s.secrets {
+		secrets = append(secrets, secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+
+	for _, secret := range secrets {
+		oldnew = append(oldnew, secret, RedactedValue)
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+//Log - redacts the record's arguments as they are formatted, then logs it to the wrapped backend
+func (s *RedactingBackend) Log(level logging.Level, calldepth int, record *logging.Record) error {
+	for i, arg := range record.Args {
+		record.Args[i] = redactedArg{value: arg, redactor: s.Redactor}
+	}
+	return s.Backend.Log(level, calldepth+1, record)
+}
+
+//GetLevel - the level set on the logging package for the module
+func (s *RedactingBackend) GetLevel(module string) logging.Level {
+	return logging.GetLevel(module)
+}
+
+//SetLevel - sets the level on the logging package for the module
+func (s *RedactingBackend) SetLevel(level logging.Level, module string) {
+	logging.SetLevel(level, module)
+}
+
+//IsEnabledFor - true when the module logs at the level
+func (s *RedactingBackend) IsEnabledFor(level logging.Level, module string) bool {
+	return level <= s.GetLevel(module)
+}
+
+//Format - formats the wrapped value with the same verb and flags, then redacts it
+func (s redactedArg) Format(state fmt.State, verb rune) {
+	format := "%"
+
+	for _, flag := range "+-# 0" {
+		if state.Flag(int(flag)) {
+			format += string(flag)
+		}
+	}
+
+	if width, ok := state.Width(); ok {
+		format += fmt.Sprint(width)
+	}
+
+	if precision, ok := state.Precision(); ok {
+		format += "." + fmt.Sprint(precision)
+	}
+	fmt.Fprint(state, s.redactor.Redact(fmt.Sprintf(format+string(verb), s.value)))
+}
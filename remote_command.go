@@ -0,0 +1,58 @@
+package cfbackup
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/pivotalservices/gtils/command"
+	"github.com/pivotalservices/gtils/osutils"
+)
+
+//NewRemoteCommandBackup - constructor for a persistence backup which runs the given commands over ssh
+func NewRemoteCommandBackup(username, password, ip, sslKey, remoteArchivePath, dumpCommand, restoreCommand string) (backup *RemoteCommandBackup, err error) {
+	config := command.SshConfig{
+		Username: username,
+		Password: password,
+		Host:     ip,
+		Port:     22,
+		SSLKey:   sslKey,
+	}
+	var remoteExecuter command.Executer
+
+	if remoteExecuter, err = RemoteCommandNewRemoteExecuter(config); err == nil {
+		backup = &RemoteCommandBackup{
+			Caller:         remoteExecuter,
+			RemoteOps:      osutils.NewRemoteOperationsWithPath(config, remoteArchivePath),
+			DumpCommand:    dumpCommand,
+			RestoreCommand: restoreCommand,
+		}
+	}
+	return
+}
+
+//ShellQuote - the value single quoted for a posix shell, so passwords and other credentials put into remote
+//commands are passed on literally whatever characters they hold
+func ShellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+//Dump - will write the output of the dump command to the given writer
+func (s *RemoteCommandBackup) Dump(dest io.Writer) (err error) {
+	return s.DumpWithContext(context.Background(), dest)
+}
+
+//DumpWithContext - writes the output of the dump command to the given writer, stopping when the context is done
+func (s *RemoteCommandBackup) DumpWithContext(ctx context.Context, dest io.Writer) (err error) {
+	return ExecuteWithContext(ctx, s.Caller, dest, s.DumpCommand)
+}
+
+//Import - will upload the contents of the given io.reader to the remote archive path and run the restore command against it.
+func (s *RemoteCommandBackup) Import(lfile io.Reader) (err error) {
+	return s.ImportWithContext(context.Background(), lfile)
+}
+
+//ImportWithContext - uploads the given archive and runs the restore command against it, stopping when the context is done. The uploaded archive is always removed.
+func (s *RemoteCommandBackup) ImportWithContext(ctx context.Context, lfile io.Reader) (err error) {
+	return remoteImport(ctx, s.Caller, s.RemoteOps, s.RestoreCommand, lfile)
+}
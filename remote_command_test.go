@@ -0,0 +1,142 @@
+package cfbackup_test
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/pivotalservices/cfbackup"
+
+	"github.com/pivotalservices/cfbackup/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("RemoteCommandBackup", func() {
+	var (
+		controlDumpCommand    = "redis-cli save && cat /var/vcap/store/redis/dump.rdb"
+		controlRestoreCommand = "cp /tmp/archive.backup /var/vcap/store/redis/dump.rdb"
+		controlString         = "test of local file"
+	)
+
+	Describe("given a Dump method", func() {
+		Context("when the remote command succeeds", func() {
+			var (
+				executer *fakes.SuccessMockNFSExecuter
+				buffer   *bytes.Buffer
+				err      error
+			)
+
+			BeforeEach(func() {
+				executer = new(fakes.SuccessMockNFSExecuter)
+				buffer = new(bytes.Buffer)
+				backup := fakes.GetRemoteCommandBackup(gbytes.NewBuffer(), executer, controlDumpCommand, controlRestoreCommand)
+				err = backup.Dump(buffer)
+			})
+
+			It("then it should run the dump command", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(executer.ActualCommand).Should(Equal(controlDumpCommand))
+			})
+
+			It("then it should write the command output to the writer", func() {
+				Ω(buffer.String()).Should(Equal(fakes.NfsSuccessString))
+			})
+		})
+
+		Context("when the remote command fails", func() {
+			It("then it should return the execution error", func() {
+				backup := fakes.GetRemoteCommandBackup(gbytes.NewBuffer(), new(fakes.FailureMockNFSExecuter), controlDumpCommand, controlRestoreCommand)
+				Ω(backup.Dump(new(bytes.Buffer))).Should(Equal(fakes.ErrMockNfsCommand))
+			})
+		})
+	})
+
+	Describe("given an Import method", func() {
+		Context("when the restore command succeeds", func() {
+			var (
+				executer *fakes.SuccessMockNFSExecuter
+				buffer   *gbytes.Buffer
+				err      error
+			)
+
+			BeforeEach(func() {
+				executer = new(fakes.SuccessMockNFSExecuter)
+				buffer = gbytes.NewBuffer()
+				backup := fakes.GetRemoteCommandBackup(buffer, executer, controlDumpCommand, controlRestoreCommand)
+				err = backup.Import(strings.NewReader(controlString))
+			})
+
+			It("then it should upload the local file to the remote", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(buffer).Should(gbytes.Say(controlString))
+			})
+
+			It("then it should run the restore command", func() {
+				Ω(executer.ActualCommand).Should(Equal(controlRestoreCommand))
+			})
+		})
+
+		Context("when the restore command fails", func() {
+			It("then it should return the execution error", func() {
+				backup := fakes.GetRemoteCommandBackup(gbytes.NewBuffer(), new(fakes.FailureMockNFSExecuter), controlDumpCommand, controlRestoreCommand)
+				Ω(backup.Import(strings.NewReader(controlString))).Should(Equal(fakes.ErrMockNfsCommand))
+			})
+		})
+	})
+})
+
+var _ = Describe("RemoteCommandInfo", func() {
+	var info *RemoteCommandInfo
+
+	BeforeEach(func() {
+		info = &RemoteCommandInfo{
+			SystemInfo: SystemInfo{
+				Product:           "p-redis",
+				Component:         "dedicated-node",
+				Ip:                "10.0.16.59",
+				User:              "admin",
+				Pass:              "secret",
+				RemoteArchivePath: "/tmp/archive.backup",
+			},
+			Format: ArtifactFormatTarGz,
+		}
+	})
+
+	Describe("given a RenderCommand method", func() {
+		Context("when called with a valid command template", func() {
+			It("then it should substitute the systeminfo fields", func() {
+				command, err := info.RenderCommand("dump -h {{.Ip}} -u {{.User}} -p {{.Pass}} > {{.RemoteArchivePath}}")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(command).Should(Equal("dump -h 10.0.16.59 -u admin -p secret > /tmp/archive.backup"))
+			})
+		})
+
+		Context("when called with a template quoting the credentials", func() {
+			It("then it should shell quote them", func() {
+				info.Pass = "it's $(secret)"
+				command, err := info.RenderCommand("dump -u {{quote .User}} -p {{.Pass | quote}}")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(command).Should(Equal(`dump -u 'admin' -p 'it'\''s $(secret)'`))
+			})
+		})
+
+		Context("when called with a template referencing an unknown field", func() {
+			It("then it should return an error", func() {
+				_, err := info.RenderCommand("dump {{.Unknown}}")
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("given GetArtifactCheck", func() {
+		Context("when called with a remote command info", func() {
+			It("then it should use the check for its format", func() {
+				Ω(GetArtifactCheck(info)(strings.NewReader("not a tarball"))).Should(HaveOccurred())
+				info.Format = ""
+				Ω(GetArtifactCheck(info)(strings.NewReader("not a tarball"))).ShouldNot(HaveOccurred())
+			})
+		})
+	})
+})
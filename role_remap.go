@@ -0,0 +1,71 @@
+package cfbackup
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const roleReferencePattern = "(?i)((?:OWNER TO|SESSION AUTHORIZATION|FOR ROLE|GRANT [^;]*? TO|REVOKE [^;]*? FROM|DEFINER\\s*=)\\s*[\"'`]?)"
+
+//NewRoleRemappingReader - wraps a plain sql dump, renaming the source roles it sets as owner, grantee or definer
+//to their targets and counting every rewrite on the remap. Custom format pg_dump archives can not be rewritten,
+//for those it returns ErrRoleRemapCustomFormat rather than restoring them with the source roles.
+func NewRoleRemappingReader(reader io.Reader, remaps []*RoleRemap) (remapped io.Reader, err error) {
+	buffered := bufio.NewReader(reader)
+
+	if magic, _ := buffered.Peek(len(pgDumpMagic)); string(magic) == pgDumpMagic {
+		return nil, ErrRoleRemapCustomFormat
+	}
+	patterns := make([]*regexp.Regexp, len(remaps))
+
+	for i, remap := range remaps {
+		patterns[i] = regexp.MustCompile(roleReferencePattern + regexp.QuoteMeta(remap.Source) + `\b`)
+	}
+	return &roleRemappingReader{reader: buffered, remaps: remaps, patterns: patterns}, nil
+}
+
+//RecordRemaps - adds the roles renamed while importing the tile's components
+func (s *RunReport) RecordRemaps(remaps ...*RoleRemap) {
+	if s != nil {
+		s.Remaps = append(s.Remaps, remaps...)
+	}
+}
+
+//String - the remap as logged and warned about
+func (s *RoleRemap) String() string {
+	return fmt.Sprintf("%s: %s -> %s", s.Component, s.Source, s.Target)
+}
+
+func (s *roleRemappingReader) Read(p []byte) (n int, err error) {
+	for len(s.pending) == 0 && s.err == nil {
+		var line []byte
+		line, s.err = s.reader.ReadBytes('\n')
+		s.pending = s.rewrite(line)
+	}
+	n = copy(p, s.pending)
+	s.pending = s.pending[n:]
+
+	if len(s.pending) == 0 {
+		err = s.err
+	}
+	return
+}
+
+func (s *roleRemappingReader) rewrite(line []byte) []byte {
+	for i, remap := range s.remaps {
+		if !bytes.Contains(line, []byte(remap.Source)) {
+			continue
+		}
+		pattern := s.patterns[i]
+
+		if matches := pattern.FindAllIndex(line, -1); len(matches) > 0 {
+			remap.Rewrites += len(matches)
+			line = pattern.ReplaceAll(line, []byte("${1}"+strings.Replace(remap.Target, "$", "$$", -1)))
+		}
+	}
+	return line
+}
@@ -0,0 +1,75 @@
+package cfbackup_test
+
+import (
+	"io/ioutil"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotalservices/cfbackup"
+)
+
+var _ = Describe("NewRoleRemappingReader", func() {
+	var remap *RoleRemap
+
+	BeforeEach(func() {
+		remap = &RoleRemap{Component: "ccdb", Source: "prod_ccdb", Target: "dr_ccdb"}
+	})
+
+	remapped := func(dump string) string {
+		reader, err := NewRoleRemappingReader(strings.NewReader(dump), []*RoleRemap{remap})
+		Ω(err).ShouldNot(HaveOccurred())
+		b, err := ioutil.ReadAll(reader)
+		Ω(err).ShouldNot(HaveOccurred())
+		return string(b)
+	}
+
+	Context("when given a plain pg_dump", func() {
+		It("then it should rename the owners and grantees", func() {
+			dump := strings.Join([]string{
+				"-- PostgreSQL database dump",
+				"SET SESSION AUTHORIZATION 'prod_ccdb';",
+				"ALTER TABLE public.apps OWNER TO prod_ccdb;",
+				"GRANT ALL ON SCHEMA public TO prod_ccdb;",
+				"REVOKE ALL ON SCHEMA public FROM \"prod_ccdb\";",
+				"COPY apps (name) FROM stdin;",
+				"prod_ccdb",
+				"\\.",
+				"",
+			}, "\n")
+			Ω(remapped(dump)).Should(Equal(strings.Join([]string{
+				"-- PostgreSQL database dump",
+				"SET SESSION AUTHORIZATION 'dr_ccdb';",
+				"ALTER TABLE public.apps OWNER TO dr_ccdb;",
+				"GRANT ALL ON SCHEMA public TO dr_ccdb;",
+				"REVOKE ALL ON SCHEMA public FROM \"dr_ccdb\";",
+				"COPY apps (name) FROM stdin;",
+				"prod_ccdb",
+				"\\.",
+				"",
+			}, "\n")))
+			Ω(remap.Rewrites).Should(Equal(4))
+		})
+
+		It("then it should leave roles which only share a prefix alone", func() {
+			Ω(remapped("ALTER TABLE public.apps OWNER TO prod_ccdb_admin;")).Should(Equal("ALTER TABLE public.apps OWNER TO prod_ccdb_admin;"))
+			Ω(remap.Rewrites).Should(Equal(0))
+		})
+	})
+
+	Context("when given a mysqldump", func() {
+		It("then it should rename the definers", func() {
+			Ω(remapped("/*!50013 DEFINER=`prod_ccdb`@`%` SQL SECURITY DEFINER */\n")).Should(Equal("/*!50013 DEFINER=`dr_ccdb`@`%` SQL SECURITY DEFINER */\n"))
+			Ω(remap.Rewrites).Should(Equal(1))
+		})
+	})
+
+	Context("when given a custom format pg_dump", func() {
+		It("then it should refuse to remap the archive", func() {
+			archive := "PGDMP\x01\x0c\x00 OWNER TO prod_ccdb;"
+			_, err := NewRoleRemappingReader(strings.NewReader(archive), []*RoleRemap{remap})
+			Ω(err).Should(Equal(ErrRoleRemapCustomFormat))
+			Ω(remap.Rewrites).Should(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,166 @@
+package cfbackup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+//NewRunReport - starts the report of a backup or restore of the given tile
+func NewRunReport(tile string, action string) *RunReport {
+	return &RunReport{
+		Tile:    tile,
+		Action:  action,
+		Status:  RunStatusRunning,
+		Started: time.Now(),
+	}
+}
+
+//ReadRunReport - reads a run report kept in the backup set
+func ReadRunReport(storageProvider StorageProvider, filepath ...string) (report *RunReport, err error) {
+	var reader io.ReadCloser
+
+	if reader, err = storageProvider.Reader(filepath...); err == nil {
+		defer reader.Close()
+		report = new(RunReport)
+		err = json.NewDecoder(reader).Decode(report)
+	}
+	return
+}
+
+//StartComponent - adds a running component, whose artifact is read or written through the component's Reader or Writer
+func (s *RunReport) StartComponent(name string, artifact string) *ComponentReport {
+	component := &ComponentReport{
+		Name:     name,
+		Status:   RunStatusRunning,
+		Artifact: artifact,
+		Started:  time.Now(),
+		hash:     sha256.New(),
+	}
+
+	if s != nil {
+		s.Components = append(s.Components, component)
+	}
+	return component
+}
+
+//RecordCCTasks - adds the bosh tasks run to stop or start the cloud controllers
+func (s *RunReport) RecordCCTasks(tasks ...CCTaskReport) {
+	if s != nil {
+		s.CCTasks = append(s.CCTasks, tasks...)
+	}
+}
+
+//CCDowntime - how long the cloud controllers were down, from the first stop task finishing to the last start task finishing
+func (s *RunReport) CCDowntime() (downtime time.Duration) {
+	var stopped, started time.Time
+
+	for _, task := range s.CCTasks {
+		switch {
+		case task.State == CCJobStopped && (stopped.IsZero() || task.Finished.Before(stopped)):
+			stopped = task.Finished
+		case task.State == CCJobStarted && task.Finished.After(started):
+			started = task.Finished
+		}
+	}
+
+	if !stopped.IsZero() && started.After(stopped) {
+		downtime = started.Sub(stopped)
+	}
+	return
+}
+
+//Warn - adds a warning which did not fail the run
+func (s *RunReport) Warn(format string, args ...interface{}) {
+	if s != nil {
+		s.Warnings = append(s.Warnings, fmt.Sprintf(format, args...))
+	}
+}
+
+//Finish - marks the run finished with the given outcome, followed by the underlying causes a generic error stands for
+func (s *RunReport) Finish(err error, causes ...error) {
+	if s == nil {
+		return
+	}
+	s.Finished = time.Now()
+	s.Status, s.Errors = outcome(err)
+
+	for _, cause := range causes {
+		_, chain := outcome(cause)
+		s.Errors = append(s.Errors, chain...)
+	}
+}
+
+//Write - stores the report as json in the backup set
+func (s *RunReport) Write(storageProvider StorageProvider, filepath ...string) (err error) {
+	var writer io.WriteCloser
+
+	if writer, err = storageProvider.Writer(filepath...); err == nil {
+		defer writer.Close()
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(s)
+	}
+	return
+}
+
+//Writer - wraps the artifact writer, counting and checksumming what is written
+func (s *ComponentReport) Writer(writer io.Writer) io.Writer {
+	return &reportingStream{component: s, writer: writer}
+}
+
+//Reader - wraps the artifact reader, counting and checksumming what is read
+func (s *ComponentReport) Reader(reader io.Reader) io.Reader {
+	return &reportingStream{component: s, reader: reader}
+}
+
+//Finish - marks the component finished with the given outcome
+func (s *ComponentReport) Finish(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Finished = time.Now()
+	s.Status, s.Errors = outcome(err)
+
+	if s.Bytes > 0 {
+		s.SHA256 = hex.EncodeToString(s.hash.Sum(nil))
+	}
+}
+
+func (s *reportingStream) Write(p []byte) (n int, err error) {
+	n, err = s.writer.Write(p)
+	s.component.record(p[:n])
+	return
+}
+
+func (s *reportingStream) Read(p []byte) (n int, err error) {
+	n, err = s.reader.Read(p)
+	s.component.record(p[:n])
+	return
+}
+
+func (s *ComponentReport) record(p []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.Finished.IsZero() {
+		s.Bytes += int64(len(p))
+		s.hash.Write(p)
+	}
+}
+
+func outcome(err error) (status string, chain []string) {
+	status = RunStatusSucceeded
+
+	if err != nil {
+		status = RunStatusFailed
+	}
+
+	for ; err != nil; err = errors.Unwrap(err) {
+		chain = append(chain, err.Error())
+	}
+	return
+}
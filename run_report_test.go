@@ -0,0 +1,120 @@
+package cfbackup_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/fakes"
+)
+
+var _ = Describe("RunReport", func() {
+	var report *RunReport
+
+	BeforeEach(func() {
+		report = NewRunReport("elastic-runtime", HookActionBackup)
+	})
+
+	Describe("given a StartComponent() method", func() {
+		Context("when the artifact is written through the component", func() {
+			It("then it should count and checksum the bytes written", func() {
+				component := report.StartComponent("ccdb", "/backups/ccdb.backup")
+				io.WriteString(component.Writer(new(strings.Builder)), "sometext")
+				component.Finish(nil)
+				sum := sha256.Sum256([]byte("sometext"))
+				Ω(component.Status).Should(Equal(RunStatusSucceeded))
+				Ω(component.Bytes).Should(Equal(int64(8)))
+				Ω(component.SHA256).Should(Equal(hex.EncodeToString(sum[:])))
+				Ω(report.Components).Should(ConsistOf(component))
+			})
+		})
+
+		Context("when the artifact is read through the component", func() {
+			It("then it should count the bytes read", func() {
+				component := report.StartComponent("ccdb", "/backups/ccdb.backup")
+				io.Copy(new(strings.Builder), component.Reader(strings.NewReader("sometext")))
+				component.Finish(nil)
+				Ω(component.Bytes).Should(Equal(int64(8)))
+			})
+		})
+
+		Context("when called on a nil report", func() {
+			It("then it should still return a usable component", func() {
+				var nilReport *RunReport
+				component := nilReport.StartComponent("ccdb", "/backups/ccdb.backup")
+				component.Finish(errors.New("failed"))
+				Ω(component.Status).Should(Equal(RunStatusFailed))
+			})
+		})
+	})
+
+	Describe("given a Finish() method", func() {
+		Context("when the run failed with a wrapped error", func() {
+			It("then it should record the error chain followed by the causes", func() {
+				cause := errors.New("pg_dump exited 1")
+				report.Finish(fmt.Errorf("ccdb: %w", cause), errors.New("connection reset"))
+				Ω(report.Status).Should(Equal(RunStatusFailed))
+				Ω(report.Errors).Should(Equal([]string{"ccdb: pg_dump exited 1", "pg_dump exited 1", "connection reset"}))
+			})
+		})
+
+		Context("when the run succeeded", func() {
+			It("then it should record no errors", func() {
+				report.Finish(nil, nil)
+				Ω(report.Status).Should(Equal(RunStatusSucceeded))
+				Ω(report.Errors).Should(BeEmpty())
+				Ω(report.Finished).ShouldNot(BeZero())
+			})
+		})
+	})
+
+	Describe("given a CCDowntime() method", func() {
+		It("then it should measure from the first stop to the last start", func() {
+			stopped := time.Unix(1477900000, 0)
+			report.RecordCCTasks(
+				CCTaskReport{State: CCJobStopped, Finished: stopped.Add(time.Second)},
+				CCTaskReport{State: CCJobStopped, Finished: stopped},
+				CCTaskReport{State: CCJobStarted, Finished: stopped.Add(time.Minute)},
+			)
+			Ω(report.CCDowntime()).Should(Equal(time.Minute))
+		})
+	})
+
+	Describe("given a Write() method", func() {
+		It("then it should store the report as json", func() {
+			storageProvider := fakes.NewMockStringStorageProvider()
+			report.RecordCCTasks(CCTaskReport{Job: "cloud_controller", State: "stopped", TaskID: 42})
+			report.Warn("skipped %s", "nfs")
+			report.Finish(nil)
+			Ω(report.Write(storageProvider, "backups", "backup-report.json")).Should(Succeed())
+
+			var stored RunReport
+			Ω(json.Unmarshal([]byte(storageProvider.String()), &stored)).Should(Succeed())
+			Ω(stored.Tile).Should(Equal("elastic-runtime"))
+			Ω(stored.CCTasks[0].TaskID).Should(Equal(42))
+			Ω(stored.Warnings).Should(Equal([]string{"skipped nfs"}))
+		})
+	})
+
+	Describe("given a ReadRunReport() function", func() {
+		It("then it should read back a stored report, including its mode", func() {
+			storageProvider := fakes.NewMockStringStorageProvider()
+			report.Mode = RunModeOnline
+			report.Finish(nil)
+			Ω(report.Write(storageProvider, "backups", "backup-report.json")).Should(Succeed())
+
+			stored, err := ReadRunReport(storageProvider, "backups", "backup-report.json")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(stored.Mode).Should(Equal(RunModeOnline))
+			Ω(stored.Status).Should(Equal(RunStatusSucceeded))
+		})
+	})
+})
@@ -2,6 +2,7 @@ package cfbackup
 
 import (
 	"io"
+	"os"
 	"strings"
 
 	"github.com/pivotalservices/gtils/storage"
@@ -37,7 +38,7 @@ func (s *S3Provider) Writer(path ...string) (io.WriteCloser, error) {
 	return s3.NewWriter(s3FilePath)
 }
 
-// Reader for reading from an S3 bucket
+// Reader for reading from an S3 bucket, failing with an os.ErrNotExist error when the object does not exist
 func (s *S3Provider) Reader(path ...string) (io.ReadCloser, error) {
 	s3FilePath := strings.Join(path, "/")
 	s3, err := storage.SafeCreateS3Bucket(s.S3Domain, s.BucketName, s.AccessKeyID, s.SecretAccessKey)
@@ -45,5 +46,10 @@ func (s *S3Provider) Reader(path ...string) (io.ReadCloser, error) {
 	if err != nil {
 		return nil, err
 	}
-	return s3.NewReader(s3FilePath)
+	reader, err := s3.NewReader(s3FilePath)
+
+	if err != nil && strings.Contains(err.Error(), S3NoSuchKey) {
+		err = &os.PathError{Op: "open", Path: s3FilePath, Err: os.ErrNotExist}
+	}
+	return reader, err
 }
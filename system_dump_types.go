@@ -1,7 +1,9 @@
 package cfbackup
 
 import (
+	"bytes"
 	"fmt"
+	"text/template"
 
 	"github.com/pivotalservices/gtils/command"
 	"github.com/pivotalservices/gtils/persistence"
@@ -34,8 +36,16 @@ func (s *DirectorInfo) GetPersistanceBackup() (dumper PersistanceBackup, err err
 	return persistence.NewPgRemoteDumpWithPath(2544, s.Database, s.User, s.Pass, sshConfig, s.RemoteArchivePath)
 }
 
-//GetPersistanceBackup - the constructor for a new mysqlinfo object
+//GetPersistanceBackup - the constructor for a new mysqlinfo object. With SingleTransaction all databases
+//are dumped from a single consistent snapshot, without locking the tables. The password is handed to mysql
+//through MYSQL_PWD, shell quoted, so it neither breaks the command nor shows up in its arguments
 func (s *MysqlInfo) GetPersistanceBackup() (dumper PersistanceBackup, err error) {
+	if s.SingleTransaction {
+		credentials := fmt.Sprintf("MYSQL_PWD=%s", ShellQuote(s.Pass))
+		return NewRemoteCommandBackup(s.VcapUser, s.VcapPass, s.Ip, s.SSHPrivateKey, s.RemoteArchivePath,
+			fmt.Sprintf("%s %s -u %s --single-transaction --all-databases", credentials, MysqlDumpBin, ShellQuote(s.User)),
+			fmt.Sprintf("%s %s -u %s < %s", credentials, MysqlSQLBin, ShellQuote(s.User), ShellQuote(s.RemoteArchivePath)))
+	}
 	sshConfig := command.SshConfig{
 		Username: s.VcapUser,
 		Password: s.VcapPass,
@@ -58,6 +68,41 @@ func (s *PgInfo) GetPersistanceBackup() (dumper PersistanceBackup, err error) {
 	return persistence.NewPgRemoteDump(2544, s.Database, s.User, s.Pass, sshConfig)
 }
 
+//GetPersistanceBackup - the constructor for a new remotecommandinfo object, rendering its commands against the systeminfo
+func (s *RemoteCommandInfo) GetPersistanceBackup() (dumper PersistanceBackup, err error) {
+	var dumpCommand, restoreCommand string
+
+	if dumpCommand, err = s.RenderCommand(s.DumpCommand); err != nil {
+		return
+	}
+
+	if restoreCommand, err = s.RenderCommand(s.RestoreCommand); err != nil {
+		return
+	}
+	return NewRemoteCommandBackup(s.VcapUser, s.VcapPass, s.Ip, s.SSHPrivateKey, s.RemoteArchivePath, dumpCommand, restoreCommand)
+}
+
+//CommandFuncs - the functions a command template can call: quote shell quotes a value, eg. {{quote .Pass}}
+var CommandFuncs = template.FuncMap{
+	"quote": ShellQuote,
+}
+
+//RenderCommand - executes the given command template against the systeminfo, exposing fields such as {{.User}}, {{.Pass}}, {{.Ip}} and {{.RemoteArchivePath}}
+//along with the CommandFuncs
+func (s *SystemInfo) RenderCommand(commandTemplate string) (rendered string, err error) {
+	var (
+		tmpl   *template.Template
+		buffer bytes.Buffer
+	)
+
+	if tmpl, err = template.New(s.Component).Funcs(CommandFuncs).Parse(commandTemplate); err == nil {
+		if err = tmpl.Execute(&buffer, s); err == nil {
+			rendered = buffer.String()
+		}
+	}
+	return
+}
+
 //GetPersistanceBackup - the constructor for a systeminfo object
 func (s *SystemInfo) GetPersistanceBackup() (dumper PersistanceBackup, err error) {
 	panic("you have to extend SystemInfo and implement GetPersistanceBackup method on the child")
@@ -0,0 +1,56 @@
+package cfbackup_test
+
+import (
+	. "github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/fakes"
+	"github.com/pivotalservices/gtils/command"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MysqlInfo", func() {
+	Describe("given a GetPersistanceBackup method", func() {
+		var (
+			executerOrig = RemoteCommandNewRemoteExecuter
+			mysqlInfo    *MysqlInfo
+		)
+
+		BeforeEach(func() {
+			RemoteCommandNewRemoteExecuter = func(command.SshConfig) (command.Executer, error) {
+				return new(fakes.SuccessMockNFSExecuter), nil
+			}
+			mysqlInfo = &MysqlInfo{
+				SystemInfo: SystemInfo{
+					Ip:                "10.0.0.5",
+					User:              "root",
+					Pass:              "mysqlpass",
+					RemoteArchivePath: "/var/vcap/store/mysql/archive.backup",
+				},
+			}
+		})
+
+		AfterEach(func() {
+			RemoteCommandNewRemoteExecuter = executerOrig
+		})
+
+		Context("when it dumps in a single transaction", func() {
+			It("then it should dump every database from one consistent snapshot", func() {
+				mysqlInfo.SingleTransaction = true
+				dumper, err := mysqlInfo.GetPersistanceBackup()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(dumper).Should(BeAssignableToTypeOf(&RemoteCommandBackup{}))
+				backup := dumper.(*RemoteCommandBackup)
+				Ω(backup.DumpCommand).Should(Equal("MYSQL_PWD='mysqlpass' " + MysqlDumpBin + " -u 'root' --single-transaction --all-databases"))
+				Ω(backup.RestoreCommand).Should(Equal("MYSQL_PWD='mysqlpass' " + MysqlSQLBin + " -u 'root' < '/var/vcap/store/mysql/archive.backup'"))
+			})
+
+			It("then it should quote a password holding shell characters", func() {
+				mysqlInfo.SingleTransaction = true
+				mysqlInfo.Pass = "pa$s'; rm -rf /"
+				dumper, _ := mysqlInfo.GetPersistanceBackup()
+				Ω(dumper.(*RemoteCommandBackup).DumpCommand).Should(HavePrefix(`MYSQL_PWD='pa$s'\''; rm -rf /' `))
+			})
+		})
+	})
+})
@@ -67,12 +67,15 @@ func NewSystemsInfo(installationSettingsFile string, sshKey string, nfs string)
 		}
 	}
 
-	systemDumps[ERDirector] = &SystemInfo{
-		Product:           installationSettings.GetBoshName(),
-		Component:         "director",
-		Identifier:        "director_credentials",
-		SSHPrivateKey:     sshKey,
-		RemoteArchivePath: defaultRemoteArchivePath,
+	systemDumps[ERDirector] = &DirectorInfo{
+		SystemInfo: SystemInfo{
+			Product:           installationSettings.GetBoshName(),
+			Component:         "director",
+			Identifier:        "director_credentials",
+			SSHPrivateKey:     sshKey,
+			RemoteArchivePath: defaultRemoteArchivePath,
+		},
+		Database: "bosh",
 	}
 	if installationSettings.FindJobInstanceCount("cf", "nfs_server") > 0 {
 		systemDumps[ERNfs] = &NfsInfo{
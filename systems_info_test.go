@@ -68,6 +68,11 @@ var _ = Describe("SystemsInfo", func() {
 				Ω(systemDumps[ERDirector]).ShouldNot(BeNil())
 				Ω(len(systemsInfo.PersistentSystems())).Should(Equal(0))
 			})
+			It("should have a director systemDump which can back up the director database", func() {
+				Ω(systemDumps[ERDirector]).Should(BeAssignableToTypeOf(&DirectorInfo{}))
+				Ω(systemDumps[ERDirector].(*DirectorInfo).Database).Should(Equal("bosh"))
+				Ω(systemDumps[ERDirector].Get(SDComponent)).Should(Equal("director"))
+			})
 		})
 	})
 })
@@ -1,6 +1,33 @@
 package tileregistry
 
+import "errors"
+
+const (
+	//SecretReferenceSeparator - separates a secret source's scheme from the reference it resolves
+	SecretReferenceSeparator = "://"
+	//DefaultSecretField - the field read from an http kv secret whose reference names none
+	DefaultSecretField = "value"
+)
+
 var (
 	//Repo -- repo holds the registered sku interfaces
 	Repo = make(map[string]TileGenerator)
+	//SecretSources -- the sources a credential field can reference, keyed by scheme
+	SecretSources = map[string]SecretSource{
+		"file": FileSecretSource{},
+		"env":  EnvSecretSource{},
+		"cmd":  CommandSecretSource{},
+		"vault": &HTTPKVSecretSource{
+			AddressEnv: "VAULT_ADDR",
+			TokenEnv:   "VAULT_TOKEN",
+			PathPrefix: "/v1/",
+		},
+		"credhub": &HTTPKVSecretSource{
+			AddressEnv: "CREDHUB_SERVER",
+			TokenEnv:   "CREDHUB_TOKEN",
+			PathPrefix: "/api/v1/data?current=true&name=/",
+		},
+	}
+	//ErrSecretUnresolved - error for a credential whose secret reference could not be resolved
+	ErrSecretUnresolved = errors.New("failed to resolve secret")
 )
@@ -0,0 +1,25 @@
+package tileregistry
+
+import (
+	"context"
+
+	"github.com/pivotalservices/cfbackup"
+)
+
+//BackupWithContext - backs up the tile, returning once the context is done. Tiles which are not
+//context aware keep running in the background, without their cleanup being guaranteed.
+func BackupWithContext(ctx context.Context, tile Tile) error {
+	if contextTile, ok := tile.(ContextTile); ok {
+		return contextTile.BackupWithContext(ctx)
+	}
+	return cfbackup.RunWithContext(ctx, tile.Backup)
+}
+
+//RestoreWithContext - restores the tile, returning once the context is done. Tiles which are not
+//context aware keep running in the background, without their cleanup being guaranteed.
+func RestoreWithContext(ctx context.Context, tile Tile) error {
+	if contextTile, ok := tile.(ContextTile); ok {
+		return contextTile.RestoreWithContext(ctx)
+	}
+	return cfbackup.RunWithContext(ctx, tile.Restore)
+}
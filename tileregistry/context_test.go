@@ -0,0 +1,60 @@
+package tileregistry_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotalservices/cfbackup/tileregistry"
+	"github.com/pivotalservices/cfbackup/tileregistry/fake"
+)
+
+var _ = Describe("context", func() {
+	Describe("given: a BackupWithContext() function", func() {
+		Context("when: passed a context aware tile", func() {
+			It("then: it should call the tile's context aware backup", func() {
+				tile := new(fake.ContextTile)
+				Ω(BackupWithContext(context.Background(), tile)).Should(Succeed())
+				Ω(tile.ContextCallCount).Should(Equal(1))
+				Ω(tile.BackupCallCount).Should(Equal(1))
+			})
+		})
+
+		Context("when: passed a tile which is not context aware", func() {
+			It("then: it should call the tile's backup", func() {
+				tile := new(fake.Tile)
+				Ω(BackupWithContext(context.Background(), tile)).Should(Succeed())
+				Ω(tile.BackupCallCount).Should(Equal(1))
+			})
+		})
+
+		Context("when: passed a context which is already done", func() {
+			It("then: it should not back up the tile", func() {
+				tile := new(fake.Tile)
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				Ω(BackupWithContext(ctx, tile)).Should(Equal(context.Canceled))
+				Ω(tile.BackupCallCount).Should(Equal(0))
+			})
+		})
+	})
+
+	Describe("given: a RestoreWithContext() function", func() {
+		Context("when: passed a context aware tile", func() {
+			It("then: it should call the tile's context aware restore", func() {
+				tile := new(fake.ContextTile)
+				Ω(RestoreWithContext(context.Background(), tile)).Should(Succeed())
+				Ω(tile.ContextCallCount).Should(Equal(1))
+				Ω(tile.RestoreCallCount).Should(Equal(1))
+			})
+		})
+
+		Context("when: passed a tile which is not context aware", func() {
+			It("then: it should call the tile's restore", func() {
+				tile := new(fake.Tile)
+				Ω(RestoreWithContext(context.Background(), tile)).Should(Succeed())
+				Ω(tile.RestoreCallCount).Should(Equal(1))
+			})
+		})
+	})
+})
@@ -1,6 +1,9 @@
 package fake
 
 import (
+	"context"
+
+	"github.com/pivotalservices/cfbackup"
 	"github.com/pivotalservices/cfbackup/tileregistry"
 	"github.com/xchapter7x/lo"
 )
@@ -40,6 +43,8 @@ type Tile struct {
 	ErrFake          error
 	BackupCallCount  int
 	RestoreCallCount int
+	VerifyCallCount  int
+	FakeReport       cfbackup.VerificationReport
 }
 
 //Backup --
@@ -55,3 +60,28 @@ func (s *Tile) Restore() error {
 	s.RestoreCallCount++
 	return s.ErrFake
 }
+
+//Verify --
+func (s *Tile) Verify() (cfbackup.VerificationReport, error) {
+	lo.G.Debug("we fake verified")
+	s.VerifyCallCount++
+	return s.FakeReport, s.ErrFake
+}
+
+//ContextTile --
+type ContextTile struct {
+	Tile
+	ContextCallCount int
+}
+
+//BackupWithContext --
+func (s *ContextTile) BackupWithContext(ctx context.Context) error {
+	s.ContextCallCount++
+	return s.Backup()
+}
+
+//RestoreWithContext --
+func (s *ContextTile) RestoreWithContext(ctx context.Context) error {
+	s.ContextCallCount++
+	return s.Restore()
+}
@@ -0,0 +1,92 @@
+package tileregistry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pivotalservices/cfbackup"
+	"github.com/xchapter7x/lo"
+)
+
+//NewHookedTile - wraps the tile built from the tileSpec with the tileSpec's hooks. Reports of tiles which do not keep
+//their own are stored through the tile's storage, or the tileSpec's backup context, under the tile's name
+func NewHookedTile(name string, tile TileCloser, tileSpec TileSpec) *HookedTile {
+	hookedTile := &HookedTile{
+		TileCloser: tile,
+		Name:       name,
+		TargetDir:  tileSpec.ArchiveDirectory,
+		Hooks:      tileSpec.Hooks,
+	}
+
+	if storageProvider, ok := tile.(cfbackup.StorageProvider); ok {
+		hookedTile.StorageProvider = storageProvider
+
+	} else if tileSpec.BackupContext != nil {
+		hookedTile.StorageProvider = tileSpec.BackupContext
+	}
+	return hookedTile
+}
+
+//Backup - runs the tile's backup between its hooks
+func (s *HookedTile) Backup() error {
+	return s.BackupWithContext(context.Background())
+}
+
+//Restore - runs the tile's restore between its hooks
+func (s *HookedTile) Restore() error {
+	return s.RestoreWithContext(context.Background())
+}
+
+//BackupWithContext - runs the tile's backup between its hooks, skipping it when a pre hook fails
+func (s *HookedTile) BackupWithContext(ctx context.Context) error {
+	return s.run(ctx, cfbackup.HookActionBackup, BackupWithContext)
+}
+
+//RestoreWithContext - runs the tile's restore between its hooks, skipping it when a pre hook fails
+func (s *HookedTile) RestoreWithContext(ctx context.Context) error {
+	return s.run(ctx, cfbackup.HookActionRestore, RestoreWithContext)
+}
+
+func (s *HookedTile) run(ctx context.Context, action string, tileAction func(context.Context, Tile) error) (err error) {
+	event := cfbackup.HookEvent{
+		Action:    action,
+		Tile:      s.Name,
+		TargetDir: s.TargetDir,
+	}
+
+	if _, ok := s.TileCloser.(ReportingTile); !ok {
+		s.report = cfbackup.NewRunReport(s.Name, action)
+		defer s.writeReport(&err)
+	}
+
+	if err = s.Hooks.BeforeTile(ctx, event); err != nil {
+		return
+	}
+	err = tileAction(ctx, s.TileCloser)
+
+	if hookErr := s.Hooks.AfterTile(context.Background(), event, err); err == nil {
+		err = hookErr
+	}
+	return
+}
+
+//RunReport - returns the report of the last backup or restore, kept by the wrapped tile when it keeps one
+func (s *HookedTile) RunReport() *cfbackup.RunReport {
+	if report := GetRunReport(s.TileCloser); report != nil {
+		return report
+	}
+	return s.report
+}
+
+func (s *HookedTile) writeReport(err *error) {
+	s.report.Finish(*err)
+
+	if s.StorageProvider == nil {
+		lo.G.Debug("no storage to keep the run report of ", s.Name)
+		return
+	}
+
+	if writeErr := s.report.Write(s.StorageProvider, s.TargetDir, s.Name, fmt.Sprintf(cfbackup.RunReportFileFormat, s.report.Action)); writeErr != nil {
+		lo.G.Error("failed to store the run report", writeErr)
+	}
+}
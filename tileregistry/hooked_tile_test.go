@@ -0,0 +1,149 @@
+package tileregistry_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
+	. "github.com/pivotalservices/cfbackup/tileregistry"
+	"github.com/pivotalservices/cfbackup/tileregistry/fake"
+)
+
+type reportingTile struct {
+	Tile
+	report *cfbackup.RunReport
+}
+
+func (s *reportingTile) RunReport() *cfbackup.RunReport {
+	return s.report
+}
+
+var _ = Describe("HookedTile", func() {
+	var (
+		tile   *fake.Tile
+		events []cfbackup.HookEvent
+		hooks  *cfbackup.HookSet
+		record = cfbackup.HookFunc(func(ctx context.Context, event cfbackup.HookEvent) error {
+			events = append(events, event)
+			return nil
+		})
+	)
+
+	BeforeEach(func() {
+		tile = new(fake.Tile)
+		events = nil
+		hooks = &cfbackup.HookSet{PreTile: []cfbackup.Hook{record}, PostTile: []cfbackup.Hook{record}}
+	})
+
+	newHookedTile := func() *HookedTile {
+		return NewHookedTile("my-tile", struct {
+			Tile
+			Closer
+		}{tile, new(fake.Closer)}, TileSpec{ArchiveDirectory: "/backups", Hooks: hooks})
+	}
+
+	Describe("given: a Backup() method", func() {
+		Context("when: the pre hooks pass", func() {
+			It("then: it should back up the tile between the hooks", func() {
+				Ω(newHookedTile().Backup()).Should(Succeed())
+				Ω(tile.BackupCallCount).Should(Equal(1))
+				Ω(events).Should(HaveLen(2))
+				Ω(events[0].Point).Should(Equal(cfbackup.HookPreTile))
+				Ω(events[0].Tile).Should(Equal("my-tile"))
+				Ω(events[0].Action).Should(Equal(cfbackup.HookActionBackup))
+				Ω(events[1].Point).Should(Equal(cfbackup.HookPostTile))
+			})
+		})
+
+		Context("when: a pre hook fails", func() {
+			It("then: it should not back up the tile", func() {
+				hooks.PreTile = []cfbackup.Hook{cfbackup.HookFunc(func(context.Context, cfbackup.HookEvent) error {
+					return errors.New("alerts could not be paused")
+				})}
+				Ω(newHookedTile().Backup()).Should(Equal(cfbackup.ErrPreHookFailed))
+				Ω(tile.BackupCallCount).Should(Equal(0))
+				Ω(events).Should(BeEmpty())
+			})
+		})
+	})
+
+	Describe("given: a RunReport() method", func() {
+		var (
+			dir     string
+			storage cfbackup.StorageProvider
+		)
+
+		BeforeEach(func() {
+			dir, _ = ioutil.TempDir("", "hooked-tile")
+			storage = cfbackup.NewDiskProvider()
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		newReportedTile := func() *HookedTile {
+			return NewHookedTile("my-tile", struct {
+				Tile
+				Closer
+			}{tile, new(fake.Closer)}, TileSpec{ArchiveDirectory: dir, Hooks: hooks, BackupContext: &cfbackup.BackupContext{TargetDir: dir, StorageProvider: storage}})
+		}
+
+		Context("when: the wrapped tile does not keep a report", func() {
+			It("then: it should report and store the outcome of each run under the tile's name", func() {
+				hookedTile := newReportedTile()
+				Ω(hookedTile.RunReport()).Should(BeNil())
+				Ω(hookedTile.Backup()).Should(Succeed())
+				Ω(hookedTile.RunReport().Tile).Should(Equal("my-tile"))
+				Ω(hookedTile.RunReport().Action).Should(Equal(cfbackup.HookActionBackup))
+				Ω(hookedTile.RunReport().Status).Should(Equal(cfbackup.RunStatusSucceeded))
+
+				stored, err := cfbackup.ReadRunReport(storage, dir, "my-tile", "backup-report.json")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(stored.Status).Should(Equal(cfbackup.RunStatusSucceeded))
+			})
+
+			It("then: it should report the failure of the tile", func() {
+				tile.ErrFake = errors.New("restore failed")
+				hookedTile := newReportedTile()
+				Ω(hookedTile.Restore()).Should(Equal(tile.ErrFake))
+				Ω(hookedTile.RunReport().Status).Should(Equal(cfbackup.RunStatusFailed))
+				Ω(hookedTile.RunReport().Errors).Should(Equal([]string{"restore failed"}))
+
+				stored, err := cfbackup.ReadRunReport(storage, dir, "my-tile", "restore-report.json")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(stored.Status).Should(Equal(cfbackup.RunStatusFailed))
+			})
+		})
+
+		Context("when: the wrapped tile keeps its own report", func() {
+			It("then: it should return the tile's report without storing another", func() {
+				report := cfbackup.NewRunReport("elastic-runtime", cfbackup.HookActionBackup)
+				hookedTile := NewHookedTile("my-tile", struct {
+					*reportingTile
+					Closer
+				}{&reportingTile{Tile: tile, report: report}, new(fake.Closer)}, TileSpec{ArchiveDirectory: dir, Hooks: hooks, BackupContext: &cfbackup.BackupContext{TargetDir: dir, StorageProvider: storage}})
+				Ω(hookedTile.Backup()).Should(Succeed())
+				Ω(hookedTile.RunReport()).Should(BeIdenticalTo(report))
+
+				_, err := cfbackup.ReadRunReport(storage, dir, "my-tile", "backup-report.json")
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("given: a Restore() method", func() {
+		Context("when: the tile fails", func() {
+			It("then: it should pass the failure to the post hooks", func() {
+				tile.ErrFake = errors.New("restore failed")
+				Ω(newHookedTile().Restore()).Should(Equal(tile.ErrFake))
+				Ω(events[1].Action).Should(Equal(cfbackup.HookActionRestore))
+				Ω(events[1].Error).Should(Equal("restore failed"))
+			})
+		})
+	})
+})
@@ -0,0 +1,28 @@
+package tileregistry
+
+import "github.com/pivotalservices/cfbackup"
+
+//NewRunLock - creates the lock a tile holds while it runs, keyed by the tileSpec's LockKey or else its ops manager host,
+//so every tile of one foundation shares it. It is stored unencrypted in the LockDirectory of the storage, defaulting to
+//cfbackup.DefaultLeaseDirectory, so runs given different crypt keys still read each other's lease. On disk storage the
+//default directory is local to the host and only keeps apart runs on that host, a LockDirectory on a shared mount, or
+//s3 storage, keeps apart runs on different hosts.
+func NewRunLock(tileSpec TileSpec, storageProvider cfbackup.StorageProvider) (lock *cfbackup.LeaseLock) {
+	key := tileSpec.LockKey
+	dir := tileSpec.LockDirectory
+
+	if key == "" {
+		key = tileSpec.OpsManagerHost
+	}
+
+	if dir == "" {
+		dir = cfbackup.DefaultLeaseDirectory
+	}
+	lock = cfbackup.NewLeaseLock(cfbackup.UnencryptedStorageProvider(storageProvider), key, dir)
+	lock.Force = tileSpec.ForceUnlock
+
+	if tileSpec.LockTTL > 0 {
+		lock.TTL = tileSpec.LockTTL
+	}
+	return
+}
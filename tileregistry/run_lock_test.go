@@ -0,0 +1,50 @@
+package tileregistry_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/fakes"
+	. "github.com/pivotalservices/cfbackup/tileregistry"
+)
+
+var _ = Describe("NewRunLock", func() {
+	var storageProvider = fakes.NewMockStringStorageProvider()
+
+	Context("when the tile spec names no lock key or directory", func() {
+		It("then it should key the lock by the ops manager host in the same directory whatever the archive directory", func() {
+			for _, archiveDirectory := range []string{"/backups/2016-10-19", "/backups/2016-10-20"} {
+				lock := NewRunLock(TileSpec{OpsManagerHost: "opsman.example.com", ArchiveDirectory: archiveDirectory}, storageProvider)
+				Ω(lock.Key).Should(Equal("opsman.example.com"))
+				Ω(lock.Path).Should(Equal([]string{cfbackup.DefaultLeaseDirectory, "opsman.example.com.lock"}))
+			}
+		})
+	})
+
+	Context("when the tile's storage is encrypted", func() {
+		It("then it should keep the lease in the storage unencrypted", func() {
+			encrypted, _ := cfbackup.NewEncryptedStorageProvider(storageProvider, "0123456789abcdef")
+			lock := NewRunLock(TileSpec{OpsManagerHost: "opsman.example.com"}, cfbackup.BackupContext{StorageProvider: encrypted})
+			Ω(lock.StorageProvider).Should(BeIdenticalTo(storageProvider))
+		})
+	})
+
+	Context("when the tile spec sets the lock", func() {
+		It("then it should use its key, directory, ttl and force flag", func() {
+			lock := NewRunLock(TileSpec{
+				OpsManagerHost:   "opsman.example.com",
+				ArchiveDirectory: "/backups/2016-10-19",
+				LockKey:          "prod foundation",
+				LockDirectory:    "/backups",
+				LockTTL:          time.Minute,
+				ForceUnlock:      true,
+			}, storageProvider)
+			Ω(lock.Key).Should(Equal("prod foundation"))
+			Ω(lock.Path).Should(Equal([]string{"/backups", "prod_foundation.lock"}))
+			Ω(lock.TTL).Should(Equal(time.Minute))
+			Ω(lock.Force).Should(BeTrue())
+		})
+	})
+})
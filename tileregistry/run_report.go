@@ -0,0 +1,11 @@
+package tileregistry
+
+import "github.com/pivotalservices/cfbackup"
+
+//GetRunReport - returns the report of the tile's last backup or restore, nil when the tile does not keep one
+func GetRunReport(tile Tile) *cfbackup.RunReport {
+	if reportingTile, ok := tile.(ReportingTile); ok {
+		return reportingTile.RunReport()
+	}
+	return nil
+}
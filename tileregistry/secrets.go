@@ -0,0 +1,186 @@
+package tileregistry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pivotalservices/cfbackup"
+)
+
+//RegisterSecretSource -- adds a secret source credential fields can reference by the given scheme
+func RegisterSecretSource(scheme string, source SecretSource) {
+	SecretSources[scheme] = source
+}
+
+//IsSecretReference - true when the value references a secret through a registered secret source
+func IsSecretReference(value string) bool {
+	scheme := strings.SplitN(value, SecretReferenceSeparator, 2)[0]
+	_, ok := SecretSources[scheme]
+	return ok && scheme != value
+}
+
+//ResolveSecret - the secret the value references through a registered secret source, or the value itself when it references none.
+//Errors name the reference, never the secret
+func ResolveSecret(value string) (secret string, err error) {
+	if !IsSecretReference(value) {
+		return value, nil
+	}
+	scheme := strings.SplitN(value, SecretReferenceSeparator, 2)[0]
+
+	if secret, err = SecretSources[scheme].Resolve(strings.TrimPrefix(value, scheme+SecretReferenceSeparator)); err != nil {
+		err = fmt.Errorf("%w %s: %s", ErrSecretUnresolved, value, err)
+	}
+	return
+}
+
+//ResolveSecrets - a copy of the tileSpec with the secret references of its credential fields resolved.
+//Tiles resolve them while they are built, so the secrets are only read when a tile is used, and the
+//resolved secrets are masked in the log output from then on
+func (s TileSpec) ResolveSecrets() (resolved TileSpec, err error) {
+	resolved = s
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"admin_user", &resolved.AdminUser},
+		{"admin_pass", &resolved.AdminPass},
+		{"admin_token", &resolved.AdminToken},
+		{"ops_manager_user", &resolved.OpsManagerUser},
+		{"ops_manager_pass", &resolved.OpsManagerPass},
+		{"ops_manager_passphrase", &resolved.OpsManagerPassphrase},
+		{"ops_manager_ssh_key", &resolved.OpsManagerSSHKey},
+		{"client_id", &resolved.ClientID},
+		{"client_secret", &resolved.ClientSecret},
+		{"crypt_key", &resolved.CryptKey},
+	}
+
+	for _, field := range fields {
+		if *field.value, err = ResolveSecret(*field.value); err != nil {
+			return s, fmt.Errorf("%s: %s", field.name, err)
+		}
+	}
+	cfbackup.RedactSecrets(resolved.AdminPass, resolved.AdminToken, resolved.OpsManagerPass, resolved.OpsManagerPassphrase, resolved.OpsManagerSSHKey, resolved.ClientSecret, resolved.CryptKey)
+	return
+}
+
+//Resolve - reads the referenced file
+func (s FileSecretSource) Resolve(reference string) (secret string, err error) {
+	var contents []byte
+
+	if contents, err = ioutil.ReadFile(reference); err == nil {
+		secret = strings.TrimRight(string(contents), "\r\n")
+	}
+	return
+}
+
+//Resolve - reads the referenced environment variable, which must be set
+func (s EnvSecretSource) Resolve(reference string) (secret string, err error) {
+	var ok bool
+
+	if secret, ok = os.LookupEnv(reference); !ok {
+		err = fmt.Errorf("environment variable %s is not set", reference)
+	}
+	return
+}
+
+//Resolve - runs the referenced command with sh, its stderr is passed through to ours
+func (s CommandSecretSource) Resolve(reference string) (secret string, err error) {
+	var stdout []byte
+	command := exec.Command("sh", "-c", reference)
+	command.Stderr = os.Stderr
+
+	if stdout, err = command.Output(); err == nil {
+		secret = strings.TrimRight(string(stdout), "\r\n")
+	}
+	return
+}
+
+//Resolve - reads the field of the secret at the referenced path#field, DefaultSecretField when no field is given.
+//Understands vault kv version 1 and 2 responses and credhub's
+func (s *HTTPKVSecretSource) Resolve(reference string) (secret string, err error) {
+	var (
+		request  *http.Request
+		response *http.Response
+		body     struct {
+			Data json.RawMessage `json:"data"`
+		}
+	)
+	secretPath, field := reference, DefaultSecretField
+
+	if i := strings.LastIndex(reference, "#"); i >= 0 {
+		secretPath, field = reference[:i], reference[i+1:]
+	}
+	address, token := s.Address, s.Token
+
+	if address == "" {
+		address = os.Getenv(s.AddressEnv)
+	}
+
+	if token == "" {
+		token = os.Getenv(s.TokenEnv)
+	}
+
+	if address == "" {
+		return "", fmt.Errorf("no address for the secret store, set %s", s.AddressEnv)
+	}
+
+	if request, err = http.NewRequest("GET", strings.TrimRight(address, "/")+s.PathPrefix+strings.TrimLeft(secretPath, "/"), nil); err != nil {
+		return
+	}
+	request.Header.Set("X-Vault-Token", token)
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	if response, err = s.client().Do(request); err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secret store responded %s", response.Status)
+	}
+
+	if err = json.NewDecoder(response.Body).Decode(&body); err == nil {
+		secret, err = secretField(body.Data, field)
+	}
+	return
+}
+
+func (s *HTTPKVSecretSource) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+func secretField(data json.RawMessage, field string) (secret string, err error) {
+	var (
+		fields      map[string]interface{}
+		credentials []map[string]interface{}
+	)
+
+	if json.Unmarshal(data, &credentials) == nil && len(credentials) > 0 {
+		if value, ok := credentials[0]["value"].(string); ok {
+			return value, nil
+		}
+		fields, _ = credentials[0]["value"].(map[string]interface{})
+
+	} else if err = json.Unmarshal(data, &fields); err != nil {
+		return
+	}
+
+	if nested, ok := fields["data"].(map[string]interface{}); ok {
+		if _, versioned := fields["metadata"]; versioned {
+			fields = nested
+		}
+	}
+
+	if value, ok := fields[field].(string); ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("secret has no field %s", field)
+}
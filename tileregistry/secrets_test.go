@@ -0,0 +1,153 @@
+package tileregistry_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotalservices/cfbackup/tileregistry"
+)
+
+var _ = Describe("Secrets", func() {
+	Describe("given a ResolveSecret() function", func() {
+		Context("when the value references no secret source", func() {
+			It("then it should return the value itself", func() {
+				for _, value := range []string{"", "admin", "https://opsman.example.com", "unknown://thing"} {
+					Ω(ResolveSecret(value)).Should(Equal(value))
+				}
+			})
+		})
+
+		Context("when the value references a file", func() {
+			It("then it should read the file without its trailing newline", func() {
+				dir, _ := ioutil.TempDir("", "secrets")
+				defer os.RemoveAll(dir)
+				secretFile := filepath.Join(dir, "password")
+				ioutil.WriteFile(secretFile, []byte("s3cr3t\n"), 0600)
+				Ω(ResolveSecret("file://" + secretFile)).Should(Equal("s3cr3t"))
+			})
+		})
+
+		Context("when the value references an environment variable", func() {
+			It("then it should read the variable", func() {
+				os.Setenv("CFBACKUP_TEST_SECRET", "from-env")
+				defer os.Unsetenv("CFBACKUP_TEST_SECRET")
+				Ω(ResolveSecret("env://CFBACKUP_TEST_SECRET")).Should(Equal("from-env"))
+			})
+
+			It("then it should fail when the variable is not set", func() {
+				_, err := ResolveSecret("env://CFBACKUP_TEST_MISSING")
+				Ω(err).Should(MatchError(ErrSecretUnresolved))
+				Ω(err.Error()).Should(ContainSubstring("env://CFBACKUP_TEST_MISSING"))
+			})
+		})
+
+		Context("when the value references a command", func() {
+			It("then it should use the command's stdout", func() {
+				Ω(ResolveSecret("cmd://echo from-command")).Should(Equal("from-command"))
+			})
+
+			It("then it should fail when the command fails", func() {
+				_, err := ResolveSecret("cmd://exit 3")
+				Ω(err).Should(MatchError(ErrSecretUnresolved))
+			})
+		})
+	})
+
+	Describe("given an HTTPKVSecretSource", func() {
+		var (
+			server   *httptest.Server
+			source   *HTTPKVSecretSource
+			response string
+			path     string
+			token    string
+		)
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path, token = r.URL.String(), r.Header.Get("X-Vault-Token")
+
+				if response == "" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				fmt.Fprint(w, response)
+			}))
+			source = &HTTPKVSecretSource{Address: server.URL, Token: "root", PathPrefix: "/v1/"}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Context("when the store answers like vault kv version 1", func() {
+			It("then it should read the referenced field", func() {
+				response = `{"data": {"password": "v1-secret"}}`
+				Ω(source.Resolve("secret/prod/opsman#password")).Should(Equal("v1-secret"))
+				Ω(path).Should(Equal("/v1/secret/prod/opsman"))
+				Ω(token).Should(Equal("root"))
+			})
+		})
+
+		Context("when the store answers like vault kv version 2", func() {
+			It("then it should read the field from the versioned data", func() {
+				response = `{"data": {"data": {"password": "v2-secret"}, "metadata": {"version": 3}}}`
+				Ω(source.Resolve("secret/data/prod/opsman#password")).Should(Equal("v2-secret"))
+			})
+		})
+
+		Context("when the store answers like credhub", func() {
+			It("then it should read the current value", func() {
+				source.PathPrefix = "/api/v1/data?current=true&name=/"
+				response = `{"data": [{"name": "/prod/opsman", "value": "credhub-secret"}]}`
+				Ω(source.Resolve("prod/opsman")).Should(Equal("credhub-secret"))
+				Ω(path).Should(Equal("/api/v1/data?current=true&name=/prod/opsman"))
+			})
+		})
+
+		Context("when the secret does not have the field", func() {
+			It("then it should return an error", func() {
+				response = `{"data": {"password": "v1-secret"}}`
+				_, err := source.Resolve("secret/prod/opsman#passphrase")
+				Ω(err).Should(MatchError("secret has no field passphrase"))
+			})
+		})
+
+		Context("when the store does not have the secret", func() {
+			It("then it should return an error", func() {
+				response = ""
+				_, err := source.Resolve("secret/prod/opsman#password")
+				Ω(err).Should(MatchError(ContainSubstring("404")))
+			})
+		})
+
+		Context("when it is registered as a secret source", func() {
+			It("then tile specs should resolve their credentials through it", func() {
+				response = `{"data": {"value": "registered-secret"}}`
+				RegisterSecretSource("teststore", source)
+				defer delete(SecretSources, "teststore")
+				tileSpec, err := TileSpec{AdminUser: "admin", AdminPass: "teststore://secret/prod/opsman"}.ResolveSecrets()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(tileSpec.AdminUser).Should(Equal("admin"))
+				Ω(tileSpec.AdminPass).Should(Equal("registered-secret"))
+			})
+		})
+	})
+
+	Describe("given a TileSpec ResolveSecrets() method", func() {
+		It("then it should name the field which failed without resolving the others", func() {
+			os.Setenv("CFBACKUP_TEST_SECRET", "from-env")
+			defer os.Unsetenv("CFBACKUP_TEST_SECRET")
+			tileSpec := TileSpec{AdminPass: "env://CFBACKUP_TEST_SECRET", CryptKey: "env://CFBACKUP_TEST_MISSING"}
+			resolved, err := tileSpec.ResolveSecrets()
+			Ω(err).Should(MatchError(ContainSubstring("crypt_key: ")))
+			Ω(err.Error()).ShouldNot(ContainSubstring("from-env"))
+			Ω(resolved).Should(Equal(tileSpec))
+		})
+	})
+})
@@ -0,0 +1,16 @@
+package tileregistry
+
+import "github.com/pivotalservices/cfbackup"
+
+//ApplyBackupContext - replaces the backup context a tile built from the environment with the tileSpec's own, when it carries one
+func (s TileSpec) ApplyBackupContext(backupContext *cfbackup.BackupContext) {
+	if s.BackupContext != nil {
+		*backupContext = *s.BackupContext
+	}
+}
+
+//TLSClients - the clients a tile built from the tileSpec verifies ops manager, director and uaa servers with,
+//following the tileSpec's tls config whatever the config of other tiles
+func (s TileSpec) TLSClients() (*cfbackup.TLSClients, error) {
+	return cfbackup.NewTLSClients(s.TLS)
+}
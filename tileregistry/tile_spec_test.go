@@ -0,0 +1,33 @@
+package tileregistry_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
+	. "github.com/pivotalservices/cfbackup/tileregistry"
+)
+
+var _ = Describe("TileSpec", func() {
+	Describe("given an ApplyBackupContext() method", func() {
+		var backupContext cfbackup.BackupContext
+
+		BeforeEach(func() {
+			backupContext = cfbackup.BackupContext{TargetDir: "from-env", StorageProvider: cfbackup.NewDiskProvider()}
+		})
+
+		Context("when the tileSpec carries a backup context", func() {
+			It("then it should replace the tile's", func() {
+				configured := &cfbackup.BackupContext{TargetDir: "from-config", IsS3: true}
+				TileSpec{BackupContext: configured}.ApplyBackupContext(&backupContext)
+				Ω(backupContext).Should(Equal(*configured))
+			})
+		})
+
+		Context("when the tileSpec has none", func() {
+			It("then it should leave the tile's alone", func() {
+				TileSpec{}.ApplyBackupContext(&backupContext)
+				Ω(backupContext.TargetDir).Should(Equal("from-env"))
+			})
+		})
+	})
+})
@@ -1,10 +1,24 @@
 package tileregistry
 
-//Register -- add a Sku interface object to the Repo
+//Register -- add a Sku interface object to the Repo, whose tiles are built wrapped with the hooks of their tileSpec
 func Register(name string, tile TileGenerator) {
+	if _, hooked := tile.(*HookedTileGenerator); !hooked {
+		tile = &HookedTileGenerator{TileGenerator: tile, Name: name}
+	}
 	Repo[name] = tile
 }
 
+//New -- builds the tile with the wrapped generator and wraps it with the tileSpec's hooks, so they run and
+//the tile's run report is kept whichever tile it is
+func (s *HookedTileGenerator) New(tileSpec TileSpec) (tile TileCloser, err error) {
+	if tile, err = s.TileGenerator.New(tileSpec); err == nil {
+		if _, hooked := tile.(*HookedTile); !hooked {
+			tile = NewHookedTile(s.Name, tile, tileSpec)
+		}
+	}
+	return
+}
+
 //GetRegistry -- gets the map of all registered Sku interface objects
 func GetRegistry() map[string]TileGenerator {
 	return Repo
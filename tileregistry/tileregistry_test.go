@@ -1,12 +1,31 @@
 package tileregistry_test
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
 	. "github.com/pivotalservices/cfbackup/tileregistry"
 	"github.com/pivotalservices/cfbackup/tileregistry/fake"
 )
 
+//storingTileGenerator - builds tiles which, like the built in ones, keep their archives through a backup context
+type storingTileGenerator struct {
+	tile *fake.Tile
+	dir  string
+}
+
+func (s *storingTileGenerator) New(tileSpec TileSpec) (TileCloser, error) {
+	return struct {
+		*fake.Tile
+		cfbackup.BackupContext
+		Closer
+	}{s.tile, cfbackup.BackupContext{TargetDir: s.dir, StorageProvider: cfbackup.NewDiskProvider()}, new(fake.Closer)}, nil
+}
+
 var _ = Describe("tileregistry", func() {
 	var (
 		controlTileGeneratorKey = "myRegisteredTileGenerator"
@@ -23,7 +42,55 @@ var _ = Describe("tileregistry", func() {
 			It("then: it should add the given TileGenerator under the given name in the registry", func() {
 				registry := GetRegistry()
 				Ω(registry).ShouldNot(BeEmpty())
-				Ω(registry[controlTileGeneratorKey]).Should(Equal(myTileGenerator))
+				Ω(registry[controlTileGeneratorKey]).Should(Equal(&HookedTileGenerator{TileGenerator: myTileGenerator, Name: controlTileGeneratorKey}))
+			})
+		})
+
+		Context("when: a tile is built from the registered TileGenerator", func() {
+			var (
+				tile   *fake.Tile
+				events []cfbackup.HookEvent
+				spec   TileSpec
+			)
+
+			BeforeEach(func() {
+				tile = new(fake.Tile)
+				events = nil
+				record := cfbackup.HookFunc(func(ctx context.Context, event cfbackup.HookEvent) error {
+					events = append(events, event)
+					return nil
+				})
+				spec = TileSpec{ArchiveDirectory: "/backups", Hooks: &cfbackup.HookSet{PreTile: []cfbackup.Hook{record}, PostTile: []cfbackup.Hook{record}}}
+				Register(controlTileGeneratorKey, &fake.TileGenerator{TileSpy: tile, Closer: new(fake.Closer)})
+			})
+			AfterEach(func() {
+				Repo = make(map[string]TileGenerator)
+			})
+			It("then: it should run the tileSpec's hooks around the tile and keep its report", func() {
+				builtTile, err := GetRegistry()[controlTileGeneratorKey].New(spec)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(builtTile.Backup()).Should(Succeed())
+				Ω(tile.BackupCallCount).Should(Equal(1))
+				Ω(events).Should(HaveLen(2))
+				Ω(events[0].Tile).Should(Equal(controlTileGeneratorKey))
+				Ω(GetRunReport(builtTile).Status).Should(Equal(cfbackup.RunStatusSucceeded))
+			})
+			It("then: it should store the report through the tile's storage", func() {
+				dir, _ := ioutil.TempDir("", "registry")
+				defer os.RemoveAll(dir)
+				Register(controlTileGeneratorKey, &storingTileGenerator{tile: tile, dir: dir})
+				builtTile, _ := GetRegistry()[controlTileGeneratorKey].New(TileSpec{ArchiveDirectory: dir})
+				Ω(builtTile.Restore()).Should(Succeed())
+
+				stored, err := cfbackup.ReadRunReport(cfbackup.NewDiskProvider(), dir, controlTileGeneratorKey, "restore-report.json")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(stored.Status).Should(Equal(cfbackup.RunStatusSucceeded))
+				Ω(GetRunReport(builtTile).Action).Should(Equal(cfbackup.HookActionRestore))
+			})
+			It("then: it should not wrap a generator registered twice again", func() {
+				Register(controlTileGeneratorKey, GetRegistry()[controlTileGeneratorKey])
+				builtTile, _ := GetRegistry()[controlTileGeneratorKey].New(spec)
+				Ω(builtTile.(*HookedTile).TileCloser).ShouldNot(BeAssignableToTypeOf(&HookedTile{}))
 			})
 		})
 	})
@@ -53,7 +120,7 @@ var _ = Describe("tileregistry", func() {
 			})
 			It("then: it should return the map of registered TileGenerator interfaces", func() {
 				Ω(registry).ShouldNot(BeEmpty())
-				Ω(registry[controlTileGeneratorKey]).Should(Equal(myTileGenerator))
+				Ω(registry[controlTileGeneratorKey].(*HookedTileGenerator).TileGenerator).Should(Equal(myTileGenerator))
 			})
 		})
 	})
@@ -1,14 +1,41 @@
 package tileregistry
 
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pivotalservices/cfbackup"
+)
+
 type (
 	//TileGenerator - interface for a tile creating object
 	TileGenerator interface {
 		New(tileSpec TileSpec) (TileCloser, error)
 	}
+	//HookedTileGenerator - a registered TileGenerator, building its tiles as HookedTiles
+	HookedTileGenerator struct {
+		TileGenerator
+		Name string
+	}
 	//Tile - definition for what a tile looks like
 	Tile interface {
 		Backup() error
 		Restore() error
+		Verify() (cfbackup.VerificationReport, error)
+	}
+
+	//ContextTile - a tile whose backup and restore stop, and clean up after themselves, when the given context is done
+	ContextTile interface {
+		Tile
+		BackupWithContext(ctx context.Context) error
+		RestoreWithContext(ctx context.Context) error
+	}
+
+	//ReportingTile - a tile which keeps a report of its last backup or restore
+	ReportingTile interface {
+		Tile
+		RunReport() *cfbackup.RunReport
 	}
 
 	//Closer - define how to close the tile
@@ -22,24 +49,78 @@ type (
 		Closer
 	}
 
+	//HookedTile - a tile whose backup and restore are wrapped by the pre and post tile hooks
+	HookedTile struct {
+		TileCloser
+		Name            string
+		TargetDir       string
+		Hooks           *cfbackup.HookSet
+		StorageProvider cfbackup.StorageProvider
+		report          *cfbackup.RunReport
+	}
+
+	//SecretSource - resolves the secret a credential field references as <scheme>://<reference>
+	SecretSource interface {
+		Resolve(reference string) (string, error)
+	}
+
+	//FileSecretSource - the secret is the contents of the referenced file, without its trailing newline
+	FileSecretSource struct{}
+
+	//EnvSecretSource - the secret is the value of the referenced environment variable
+	EnvSecretSource struct{}
+
+	//CommandSecretSource - the secret is the stdout of the referenced shell command, without its trailing newline
+	CommandSecretSource struct{}
+
+	//HTTPKVSecretSource - the secret is read from a vault or credhub compatible http kv endpoint, referenced as path#field.
+	//Address and Token default to the AddressEnv and TokenEnv environment variables when they are read
+	HTTPKVSecretSource struct {
+		Address    string
+		Token      string
+		AddressEnv string
+		TokenEnv   string
+		PathPrefix string
+		Client     *http.Client
+	}
+
 	//DoNothingCloser - This Closer do nothing
 	DoNothingCloser struct {
 	}
 	//TileSpec -- defines what a tile would need to be initialized
 	TileSpec struct {
-		OpsManagerHost       string
-		AdminUser            string
-		AdminPass            string
-		AdminToken           string
-		OpsManagerUser       string
-		OpsManagerPass       string
-		OpsManagerPassphrase string
-		ClientID             string
-		ClientSecret         string
-		ArchiveDirectory     string
-		CryptKey             string
-		ClearBoshManifest    bool
-		PluginArgs           string
-		NFS                  string
+		OpsManagerHost       string                  `json:"ops_manager_host"`
+		AdminUser            string                  `json:"admin_user"`
+		AdminPass            string                  `json:"admin_pass"`
+		AdminToken           string                  `json:"admin_token"`
+		OpsManagerUser       string                  `json:"ops_manager_user"`
+		OpsManagerPass       string                  `json:"ops_manager_pass"`
+		OpsManagerPassphrase string                  `json:"ops_manager_passphrase"`
+		OpsManagerSSHKey     string                  `json:"ops_manager_ssh_key"`
+		OpsManagerSSHPort    int                     `json:"ops_manager_ssh_port"`
+		ClientID             string                  `json:"client_id"`
+		ClientSecret         string                  `json:"client_secret"`
+		ArchiveDirectory     string                  `json:"archive_directory"`
+		CryptKey             string                  `json:"crypt_key"`
+		ClearBoshManifest    bool                    `json:"clear_bosh_manifest"`
+		PluginArgs           string                  `json:"plugin_args"`
+		NFS                  string                  `json:"nfs"`
+		MergeOnRestore       bool                    `json:"merge_on_restore"`
+		PhaseTimeouts        cfbackup.PhaseTimeouts  `json:"phase_timeouts"`
+		Concurrency          int                     `json:"concurrency"`
+		CCToggleConcurrency  int                     `json:"cc_toggle_concurrency"`
+		CCJobPatterns        []string                `json:"cc_job_patterns"`
+		Online               bool                    `json:"online"`
+		TLS                  cfbackup.TLSConfig      `json:"tls"`
+		IncludeComponents    []string                `json:"include_components"`
+		ExcludeComponents    []string                `json:"exclude_components"`
+		RemapRoles           bool                    `json:"remap_roles"`
+		Resume               bool                    `json:"resume"`
+		LockKey              string                  `json:"lock_key"`
+		LockDirectory        string                  `json:"lock_directory"`
+		LockTTL              time.Duration           `json:"lock_ttl"`
+		ForceUnlock          bool                    `json:"force_unlock"`
+		Hooks                *cfbackup.HookSet       `json:"-"`
+		BackupContext        *cfbackup.BackupContext `json:"-"`
 	}
 )
@@ -0,0 +1,291 @@
+package boshdirector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-community/go-cfenv"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/gtils/command"
+	"github.com/pivotalservices/gtils/log"
+	"github.com/xchapter7x/lo"
+)
+
+var (
+	//NewRemoteExecuter - creates the executer used to control the director processes
+	NewRemoteExecuter = command.NewRemoteExecutor
+
+	//GetPersistanceBackup - builds the persistence backup for one of the director's systemdumps
+	GetPersistanceBackup = func(dump cfbackup.SystemDump) (cfbackup.PersistanceBackup, error) {
+		return dump.GetPersistanceBackup()
+	}
+)
+
+//NewBoshDirector - initializes a bosh director tile from the given installation settings
+func NewBoshDirector(installationSettings *cfbackup.InstallationSettings, target string, cryptKey string) *BoshDirector {
+	installationSettings.SetPGDumpUtilVersions()
+	return &BoshDirector{
+		InstallationSettings: installationSettings,
+		ProductName:          installationSettings.GetBoshName(),
+		BackupContext:        cfbackup.NewBackupContext(target, cfenv.CurrentEnv(), cryptKey),
+	}
+}
+
+// Backup dumps the director database and tars the internal blobstore
+func (s *BoshDirector) Backup() error {
+	return s.BackupWithContext(context.Background())
+}
+
+// BackupWithContext dumps the director database and tars the internal blobstore, stopping when ctx is done
+func (s *BoshDirector) BackupWithContext(ctx context.Context) (err error) {
+	var dumps []cfbackup.SystemDump
+
+	if dumps, err = s.PersistentSystems(); err == nil {
+		err = s.runDbAction(ctx, dumps, cfbackup.ExportArchive)
+	}
+	return
+}
+
+// Restore stops the director processes, restores the director database and internal blobstore, then starts them again
+func (s *BoshDirector) Restore() error {
+	return s.RestoreWithContext(context.Background())
+}
+
+// RestoreWithContext restores the director like Restore, stopping when ctx is done. The director processes are started again either way
+func (s *BoshDirector) RestoreWithContext(ctx context.Context) (err error) {
+	var (
+		dumps     []cfbackup.SystemDump
+		processes *monit
+	)
+
+	if dumps, err = s.PersistentSystems(); err != nil {
+		return
+	}
+
+	if processes, err = s.monit(dumps[0]); err != nil {
+		return
+	}
+	defer func() {
+		if startErr := processes.start(DirectorProcesses); err == nil {
+			err = startErr
+		}
+	}()
+
+	if err = processes.stop(ctx, DirectorProcesses); err == nil {
+		err = s.runDbAction(ctx, dumps, cfbackup.ImportArchive)
+	}
+	return
+}
+
+// Verify checks the director database dump and blobstore archive can be read back, without restoring them
+func (s *BoshDirector) Verify() (report cfbackup.VerificationReport, err error) {
+	for _, info := range s.systemDumps(cfbackup.VMCredentials{}, "") {
+		filename := fmt.Sprintf(BoshBackupFileFormat, info.Get(cfbackup.SDComponent))
+		report.Record(filename, cfbackup.VerifyArtifact(s, cfbackup.GetArtifactCheck(info), s.archivePath(filename)))
+	}
+
+	if !report.Passed() {
+		lo.G.Error("bosh director backup set failed verification", log.Data{"failures": report.Failures()})
+		err = cfbackup.ErrVerificationFailed
+	}
+	return
+}
+
+//PersistentSystems - returns the director database and blobstore systemdumps, with the director ip and credentials from the installation settings
+func (s *BoshDirector) PersistentSystems() (dumps []cfbackup.SystemDump, err error) {
+	var (
+		ips           []string
+		vmCredentials cfbackup.VMCredentials
+	)
+
+	if ips, err = s.InstallationSettings.FindIPsByProductAndJob(s.ProductName, BoshDirectorJob); err != nil {
+		return
+	}
+
+	if len(ips) == 0 {
+		err = fmt.Errorf("No IPs found for %s, %s", s.ProductName, BoshDirectorJob)
+		return
+	}
+
+	if vmCredentials, err = s.InstallationSettings.FindVMCredentialsByProductAndJob(s.ProductName, BoshDirectorJob); err == nil {
+		dumps = s.systemDumps(vmCredentials, ips[0])
+		err = s.assignDatabaseCredentials(dumps[0])
+	}
+	return
+}
+
+func (s *BoshDirector) systemDumps(vmCredentials cfbackup.VMCredentials, ip string) []cfbackup.SystemDump {
+	systemInfo := cfbackup.SystemInfo{
+		Product:           s.ProductName,
+		Ip:                ip,
+		VcapUser:          vmCredentials.UserID,
+		VcapPass:          vmCredentials.Password,
+		SSHPrivateKey:     vmCredentials.SSLKey,
+		RemoteArchivePath: BoshRemoteArchivePath,
+	}
+	database := &cfbackup.DirectorInfo{
+		SystemInfo: systemInfo,
+		Database:   BoshDirectorDatabase,
+	}
+	database.Component = BoshDatabaseComponent
+	database.Identifier = BoshPostgresCredentials
+
+	blobstore := &cfbackup.RemoteCommandInfo{
+		SystemInfo:     systemInfo,
+		DumpCommand:    fmt.Sprintf("cd %s && tar cz %s", BoshStoreDir, BoshBlobstoreDir),
+		RestoreCommand: fmt.Sprintf("cd %s && rm -rf %s && tar zxf {{.RemoteArchivePath}}", BoshStoreDir, BoshBlobstoreDir),
+		Format:         cfbackup.ArtifactFormatTarGz,
+	}
+	blobstore.Component = BoshBlobstoreComponent
+	blobstore.Identifier = "vm_credentials"
+	blobstore.User = vmCredentials.UserID
+	blobstore.Pass = vmCredentials.Password
+	return []cfbackup.SystemDump{database, blobstore}
+}
+
+func (s *BoshDirector) assignDatabaseCredentials(database cfbackup.SystemDump) (err error) {
+	var propertyMap map[string]string
+
+	if propertyMap, err = s.InstallationSettings.FindPropertyValues(s.ProductName, BoshDirectorJob, BoshPostgresCredentials); err == nil {
+		database.Set(cfbackup.SDUser, propertyMap["identity"])
+		database.Set(cfbackup.SDPass, propertyMap["password"])
+	}
+	return
+}
+
+func (s *BoshDirector) runDbAction(ctx context.Context, dumps []cfbackup.SystemDump, action int) (err error) {
+	for _, info := range dumps {
+		if err = info.Error(); err == nil {
+			err = s.readWriterArchive(ctx, info, action)
+		}
+
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			lo.G.Error("Error running db action for director ", info.Get(cfbackup.SDComponent), err)
+			err = cfbackup.ErrERDBBackup
+			break
+		}
+	}
+	return
+}
+
+func (s *BoshDirector) readWriterArchive(ctx context.Context, info cfbackup.SystemDump, action int) (err error) {
+	var pb cfbackup.PersistanceBackup
+	filepath := s.archivePath(fmt.Sprintf(BoshBackupFileFormat, info.Get(cfbackup.SDComponent)))
+
+	if pb, err = GetPersistanceBackup(info); err == nil {
+		switch action {
+		case cfbackup.ImportArchive:
+			lo.G.Debug("Restoring %s", info.Get(cfbackup.SDComponent))
+			var backupReader io.ReadCloser
+
+			if backupReader, err = s.Reader(filepath); err == nil {
+				defer backupReader.Close()
+				err = cfbackup.ImportWithContext(ctx, pb, backupReader)
+			}
+		case cfbackup.ExportArchive:
+			lo.G.Info("Exporting %s", info.Get(cfbackup.SDComponent))
+			var backupWriter io.WriteCloser
+
+			if backupWriter, err = s.Writer(filepath); err == nil {
+				defer backupWriter.Close()
+				err = cfbackup.DumpWithContext(ctx, pb, backupWriter)
+			}
+		}
+	}
+	return
+}
+
+func (s *BoshDirector) archivePath(filename string) string {
+	return path.Join(s.TargetDir, BoshBackupDir, filename)
+}
+
+func (s *BoshDirector) monit(info cfbackup.SystemDump) (processes *monit, err error) {
+	var caller command.Executer
+	config := command.SshConfig{
+		Username: info.Get(cfbackup.SDVcapUser),
+		Password: info.Get(cfbackup.SDVcapPass),
+		Host:     info.Get(cfbackup.SDIP),
+		Port:     22,
+		SSLKey:   s.sshKey(info),
+	}
+
+	if caller, err = NewRemoteExecuter(config); err == nil {
+		processes = &monit{caller: caller, password: config.Password}
+	}
+	return
+}
+
+func (s *BoshDirector) sshKey(info cfbackup.SystemDump) string {
+	if directorInfo, ok := info.(*cfbackup.DirectorInfo); ok {
+		return directorInfo.SSHPrivateKey
+	}
+	return ""
+}
+
+func (s *monit) stop(ctx context.Context, processes []string) (err error) {
+	for _, process := range processes {
+		if err = cfbackup.ExecuteWithContext(ctx, s.caller, ioutil.Discard, s.command("stop "+process)); err != nil {
+			return
+		}
+	}
+
+	for attempt := 0; attempt < ProcessPollAttempts; attempt++ {
+		var stopped bool
+
+		if stopped, err = s.stopped(processes); err != nil || stopped {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(ProcessPollInterval):
+		}
+	}
+	return ErrDirectorProcesses
+}
+
+func (s *monit) start(processes []string) (err error) {
+	for _, process := range processes {
+		if startErr := s.run("start " + process); startErr != nil {
+			lo.G.Error("failed to start director process ", process, startErr)
+			err = startErr
+		}
+	}
+	return
+}
+
+func (s *monit) stopped(processes []string) (stopped bool, err error) {
+	var summary bytes.Buffer
+
+	if err = s.caller.Execute(&summary, fmt.Sprintf("echo '%s' | sudo -S %s summary", s.password, BoshMonitBin)); err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(summary.String(), "\n") {
+		for _, process := range processes {
+			if strings.Contains(line, "'"+process+"'") && !strings.Contains(line, "not monitored") {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+func (s *monit) run(action string) error {
+	return s.caller.Execute(ioutil.Discard, s.command(action))
+}
+
+func (s *monit) command(action string) string {
+	return fmt.Sprintf("echo '%s' | sudo -S %s %s", s.password, BoshMonitBin, action)
+}
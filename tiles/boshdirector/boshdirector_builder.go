@@ -0,0 +1,32 @@
+package boshdirector
+
+import (
+	"io"
+
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	"github.com/pivotalservices/cfbackup/tiles/opsmanager"
+)
+
+//New -- builds a new bosh director tile pre initialized from the ops manager installation settings
+func (s *BoshDirectorBuilder) New(tileSpec tileregistry.TileSpec) (directorCloser tileregistry.TileCloser, err error) {
+	var installationSettings io.Reader
+
+	if tileSpec, err = tileSpec.ResolveSecrets(); err != nil {
+		return
+	}
+
+	if installationSettings, err = opsmanager.GetInstallationSettings(tileSpec); err == nil {
+		config := cfbackup.NewConfigurationParserFromReader(installationSettings)
+		director := NewBoshDirector(&config.InstallationSettings, tileSpec.ArchiveDirectory, tileSpec.CryptKey)
+		tileSpec.ApplyBackupContext(&director.BackupContext)
+		directorCloser = struct {
+			*BoshDirector
+			tileregistry.Closer
+		}{
+			director,
+			new(tileregistry.DoNothingCloser),
+		}
+	}
+	return
+}
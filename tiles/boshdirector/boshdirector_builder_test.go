@@ -0,0 +1,49 @@
+package boshdirector_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/fakes"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	. "github.com/pivotalservices/cfbackup/tiles/boshdirector"
+)
+
+var _ = Describe("BoshDirectorBuilder", func() {
+	Describe("given a New() method", func() {
+		fixture := fakes.NewTileBuilderFixture("../../fixtures/installation-settings-1-6-aws.json", &NewRemoteExecuter)
+		fakes.ItBuildsTilesFromOpsManager(fixture, new(BoshDirectorBuilder))
+
+		Context("when ops manager returns the installation settings", func() {
+			var director *BoshDirector
+
+			BeforeEach(func() {
+				tile, err := new(BoshDirectorBuilder).New(fakes.BuilderSpec)
+				Ω(err).ShouldNot(HaveOccurred())
+				director = tile.(struct {
+					*BoshDirector
+					tileregistry.Closer
+				}).BoshDirector
+			})
+
+			It("then it should archive the p-bosh product into the tileSpec's archive directory", func() {
+				Ω(director.TargetDir).Should(Equal(fakes.BuilderSpec.ArchiveDirectory))
+				Ω(director.ProductName).Should(Equal("p-bosh"))
+			})
+
+			It("then it should reach the director vm and database with their credentials", func() {
+				dumps, err := director.PersistentSystems()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(dumps).Should(HaveLen(2))
+
+				for _, dump := range dumps {
+					Ω(dump.Get(cfbackup.SDIP)).Should(Equal("10.0.16.10"))
+					Ω(dump.Get(cfbackup.SDVcapUser)).Should(Equal("vcap"))
+					Ω(dump.Get(cfbackup.SDVcapPass)).Should(Equal("47bb8427bfb7d79d"))
+				}
+				Ω(dumps[0].Get(cfbackup.SDUser)).Should(Equal("postgres"))
+				Ω(dumps[0].Get(cfbackup.SDPass)).Should(Equal("ba4835e26f265eea3eb7"))
+			})
+		})
+	})
+})
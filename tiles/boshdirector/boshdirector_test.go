@@ -0,0 +1,215 @@
+package boshdirector_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/fakes"
+	. "github.com/pivotalservices/cfbackup/tiles/boshdirector"
+	"github.com/pivotalservices/gtils/command"
+)
+
+type directorVM struct {
+	running  map[string]bool
+	stuck    bool
+	commands []string
+}
+
+func (s *directorVM) Execute(dest io.Writer, cmd string) (err error) {
+	s.commands = append(s.commands, cmd)
+	fields := strings.Fields(cmd)
+	process := fields[len(fields)-1]
+
+	switch {
+	case strings.Contains(cmd, "monit stop"):
+		s.running[process] = s.stuck
+	case strings.Contains(cmd, "monit start"):
+		s.running[process] = true
+	case strings.Contains(cmd, "monit summary"):
+		for name, running := range s.running {
+			status := "not monitored"
+
+			if running {
+				status = "running"
+			}
+			fmt.Fprintf(dest, "Process '%s'    %s\n", name, status)
+		}
+	}
+	return
+}
+
+func (s *directorVM) ran(fragment string) bool {
+	for _, cmd := range s.commands {
+		if strings.Contains(cmd, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+type mockDumper struct {
+	contents string
+	imported string
+}
+
+func (s *mockDumper) Dump(dest io.Writer) (err error) {
+	_, err = io.WriteString(dest, s.contents)
+	return
+}
+
+func (s *mockDumper) Import(src io.Reader) (err error) {
+	contents, err := ioutil.ReadAll(src)
+	s.imported = string(contents)
+	return
+}
+
+var _ = Describe("BoshDirector", func() {
+	var (
+		tile                         *BoshDirector
+		dir                          string
+		vm                           *directorVM
+		dumpers                      map[string]*mockDumper
+		sshConfig                    command.SshConfig
+		originalNewRemoteExecuter    = NewRemoteExecuter
+		originalGetPersistanceBackup = GetPersistanceBackup
+		originalProcessPollInterval  = ProcessPollInterval
+	)
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "boshdirector")
+		config := cfbackup.NewConfigurationParser("../../fixtures/installation-settings-1-6-aws.json")
+		tile = NewBoshDirector(&config.InstallationSettings, dir, "")
+		vm = &directorVM{running: make(map[string]bool)}
+
+		for _, process := range DirectorProcesses {
+			vm.running[process] = true
+		}
+		dumpers = map[string]*mockDumper{
+			BoshDatabaseComponent:  {contents: string(fakes.NewPgCustomDump(3))},
+			BoshBlobstoreComponent: {contents: string(fakes.NewTarGz(map[string]string{"blobstore/00/release": "blob"}))},
+		}
+		ProcessPollInterval = 0
+		NewRemoteExecuter = func(config command.SshConfig) (command.Executer, error) {
+			sshConfig = config
+			return vm, nil
+		}
+		GetPersistanceBackup = func(dump cfbackup.SystemDump) (cfbackup.PersistanceBackup, error) {
+			return dumpers[dump.Get(cfbackup.SDComponent)], nil
+		}
+	})
+
+	AfterEach(func() {
+		NewRemoteExecuter = originalNewRemoteExecuter
+		GetPersistanceBackup = originalGetPersistanceBackup
+		ProcessPollInterval = originalProcessPollInterval
+		os.RemoveAll(dir)
+	})
+
+	Describe("given a PersistentSystems() method", func() {
+		Context("when the installation settings contain a director", func() {
+			It("then it should return the director database with its postgres credentials", func() {
+				dumps, err := tile.PersistentSystems()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(dumps).Should(HaveLen(2))
+				database := dumps[0].(*cfbackup.DirectorInfo)
+				Ω(database.Ip).Should(Equal("10.0.16.10"))
+				Ω(database.Database).Should(Equal(BoshDirectorDatabase))
+				Ω(database.User).ShouldNot(BeEmpty())
+				Ω(database.Pass).ShouldNot(BeEmpty())
+				Ω(database.VcapUser).Should(Equal("vcap"))
+			})
+
+			It("then it should return the blobstore as a tarball of the store dir", func() {
+				dumps, _ := tile.PersistentSystems()
+				blobstore := dumps[1].(*cfbackup.RemoteCommandInfo)
+				Ω(blobstore.Format).Should(Equal(cfbackup.ArtifactFormatTarGz))
+				Ω(blobstore.DumpCommand).Should(ContainSubstring("tar cz " + BoshBlobstoreDir))
+				Ω(blobstore.RestoreCommand).Should(ContainSubstring("tar zxf"))
+			})
+		})
+	})
+
+	Describe("given a Backup() method", func() {
+		Context("when the director is reachable", func() {
+			It("then it should write the database and blobstore archives", func() {
+				Ω(tile.Backup()).Should(Succeed())
+
+				for component, dumper := range dumpers {
+					contents, err := ioutil.ReadFile(path.Join(dir, BoshBackupDir, component+".backup"))
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(string(contents)).Should(Equal(dumper.contents))
+				}
+			})
+
+			It("then it should not stop the director processes", func() {
+				tile.Backup()
+				Ω(vm.ran("monit stop")).Should(BeFalse())
+			})
+		})
+	})
+
+	Describe("given a Restore() method", func() {
+		BeforeEach(func() {
+			tile.Backup()
+		})
+
+		Context("when the director processes stop", func() {
+			It("then it should import both archives", func() {
+				Ω(tile.Restore()).Should(Succeed())
+
+				for _, dumper := range dumpers {
+					Ω(dumper.imported).Should(Equal(dumper.contents))
+				}
+			})
+
+			It("then it should stop and start every director process as root", func() {
+				tile.Restore()
+				Ω(sshConfig.Host).Should(Equal("10.0.16.10"))
+
+				for _, process := range DirectorProcesses {
+					Ω(vm.ran("sudo -S " + BoshMonitBin + " stop " + process)).Should(BeTrue())
+					Ω(vm.running[process]).Should(BeTrue())
+				}
+			})
+		})
+
+		Context("when the director processes do not stop", func() {
+			It("then it should not import and start the processes again", func() {
+				vm.stuck = true
+				Ω(tile.Restore()).Should(Equal(ErrDirectorProcesses))
+
+				for _, dumper := range dumpers {
+					Ω(dumper.imported).Should(BeEmpty())
+				}
+				Ω(vm.ran("monit start")).Should(BeTrue())
+			})
+		})
+	})
+
+	Describe("given a Verify() method", func() {
+		Context("when the backup set is complete", func() {
+			It("then it should pass", func() {
+				tile.Backup()
+				report, err := tile.Verify()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(report.Passed()).Should(BeTrue())
+			})
+		})
+
+		Context("when the blobstore archive is corrupt", func() {
+			It("then it should fail verification", func() {
+				dumpers[BoshBlobstoreComponent].contents = "not a tarball"
+				tile.Backup()
+				_, err := tile.Verify()
+				Ω(err).Should(Equal(cfbackup.ErrVerificationFailed))
+			})
+		})
+	})
+})
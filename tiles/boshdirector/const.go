@@ -0,0 +1,47 @@
+package boshdirector
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	//BoshDirectorJob -- job identifier of the director vm
+	BoshDirectorJob = "director"
+	//BoshDirectorDatabase -- name of the director's postgres database
+	BoshDirectorDatabase = "bosh"
+	//BoshPostgresCredentials -- property identifier of the director database credentials
+	BoshPostgresCredentials = "postgres_credentials"
+	//BoshBackupDir -- directory in the backup set holding the director archives
+	BoshBackupDir = "bosh-director"
+	//BoshDatabaseComponent -- component name of the director database dump
+	BoshDatabaseComponent = "director"
+	//BoshBlobstoreComponent -- component name of the internal blobstore archive
+	BoshBlobstoreComponent = "blobstore"
+	//BoshBackupFileFormat -- format of archive filename
+	BoshBackupFileFormat = "%s.backup"
+	//BoshStoreDir -- persistent disk mount of the director vm
+	BoshStoreDir = "/var/vcap/store"
+	//BoshBlobstoreDir -- directory of the internal blobstore under the store dir
+	BoshBlobstoreDir = "blobstore"
+	//BoshRemoteArchivePath -- where archives are uploaded to on the director vm during a restore
+	BoshRemoteArchivePath = "/var/vcap/store/director-restore.backup"
+	//BoshMonitBin -- monit on the director vm
+	BoshMonitBin = "/var/vcap/bosh/bin/monit"
+
+	//ErrDirectorProcessesMsg -- error message for director processes which did not stop
+	ErrDirectorProcessesMsg = "director processes did not stop"
+)
+
+var (
+	//ErrDirectorProcesses - error for director processes which did not stop
+	ErrDirectorProcesses = errors.New(ErrDirectorProcessesMsg)
+
+	//DirectorProcesses - monit processes which write to the director database or blobstore, stopped during a restore
+	DirectorProcesses = []string{"director", "worker_1", "worker_2", "worker_3", "director_scheduler", "director_nginx", "health_monitor"}
+
+	//ProcessPollInterval - how long to wait between monit summary checks while the director processes stop
+	ProcessPollInterval = 2 * time.Second
+	//ProcessPollAttempts - how many monit summary checks to make before giving up
+	ProcessPollAttempts = 60
+)
@@ -0,0 +1,23 @@
+package boshdirector
+
+import (
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/gtils/command"
+)
+
+type (
+	//BoshDirector - the ops manager director, whose database and internal blobstore are backed up over ssh
+	BoshDirector struct {
+		cfbackup.BackupContext
+		InstallationSettings cfbackup.InstallationInfo
+		ProductName          string
+	}
+
+	//BoshDirectorBuilder -- an object that can build a bosh director tile pre-initialized
+	BoshDirectorBuilder struct{}
+
+	monit struct {
+		caller   command.Executer
+		password string
+	}
+)
@@ -6,6 +6,8 @@ import (
 )
 
 const (
+	//ERTileName - name of the elastic runtime tile in hook events
+	ERTileName = "elastic-runtime"
 	//ERDefaultSystemUser - default user for system vms
 	ERDefaultSystemUser = "vcap"
 	//ERDirectorInfoURL - url format for a director info endpoint
@@ -26,6 +28,10 @@ const (
 	ERMySQL = "MysqldbInfo"
 	//ERNfs -- key
 	ERNfs = "NfsInfo"
+	//ERSourceSettingsFilename -- name of the installation settings kept in the backup set
+	ERSourceSettingsFilename = "installation-settings.json"
+	//ERVMCredentialsIdentifier -- identifier of components which use the vm credentials rather than a database role
+	ERVMCredentialsIdentifier = "vm_credentials"
 	//ERBackupFileFormat -- format of archive filename
 	ERBackupFileFormat = "%s.backup"
 	//ERInvalidDirectorCredsMsg -- error message for invalid creds on director
@@ -36,6 +42,12 @@ const (
 	ERFileDoesNotExist = "file does not exist"
 	//ErrERDBBackupFailure -- error message for backup failure
 	ErrERDBBackupFailure = "failed to backup database"
+	//ERUnknownComponentFormat -- error message for a selected component the installation does not have
+	ERUnknownComponentFormat = "unknown elastic runtime component %s"
+	//EROnlineRestoreWarning -- warning given when restoring a backup set taken while the cloud controllers kept running
+	EROnlineRestoreWarning = "restoring an online backup set, the blobstore and the databases may not match, check apps pushed or deleted while it was taken"
+	//ERNoCCJobsWarningFormat -- warning given when no cloud controller job matches the patterns, by patterns
+	ERNoCCJobsWarningFormat = "no cloud controller job matches %s, the cloud controllers were left running"
 )
 
 var (
@@ -47,4 +59,11 @@ var (
 	ErrERInvalidPath = &os.PathError{Err: errors.New(ERFileDoesNotExist)}
 	//ErrERDBBackup - error for db backup failures
 	ErrERDBBackup = errors.New(ErrERDBBackupFailure)
+	//ERCloudControllerComponents - components whose backup or restore needs the cloud controllers stopped
+	ERCloudControllerComponents = []string{"ccdb", "mysql", "nfs_server"}
+	//EROnlineCaveats - what a backup taken while the cloud controllers kept running can not guarantee
+	EROnlineCaveats = []string{
+		"online backup: the nfs blobstore was copied while in use, the copy is only crash consistent",
+		"online backup: each database dump is consistent, but the databases and the blobstore were captured at different times, apps pushed or deleted during the backup may be missing or left behind after a restore",
+	}
 )
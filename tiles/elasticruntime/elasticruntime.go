@@ -1,20 +1,28 @@
 package elasticruntime
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/cloudfoundry-community/go-cfenv"
 	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/tiles/opsmanager"
 	ghttp "github.com/pivotalservices/gtils/http"
 	"github.com/pivotalservices/gtils/log"
 	"github.com/xchapter7x/lo"
 )
 
+var noDeadline = context.Background()
+
 // NewElasticRuntime initializes an ElasticRuntime intance
 var NewElasticRuntime = func(jsonFile string, target string, sshKey string, cryptKey string, nfs string) *ElasticRuntime {
 
@@ -35,42 +43,143 @@ var NewElasticRuntime = func(jsonFile string, target string, sshKey string, cryp
 
 // Backup performs a backup of a Pivotal Elastic Runtime deployment
 func (context *ElasticRuntime) Backup() (err error) {
-	return context.backupRestore(cfbackup.ExportArchive)
+	return context.BackupWithContext(noDeadline)
 }
 
 // Restore performs a restore of a Pivotal Elastic Runtime deployment
 func (context *ElasticRuntime) Restore() (err error) {
-	err = context.backupRestore(cfbackup.ImportArchive)
+	err = context.RestoreWithContext(noDeadline)
+	return
+}
+
+// BackupWithContext performs a backup which stops when ctx is done, the cloud controllers are restarted either way
+func (context *ElasticRuntime) BackupWithContext(ctx context.Context) (err error) {
+	return context.Lock.Run(ctx, context.backup)
+}
+
+// RestoreWithContext performs a restore which stops when ctx is done, the cloud controllers are restarted either way
+func (context *ElasticRuntime) RestoreWithContext(ctx context.Context) (err error) {
+	return context.Lock.Run(ctx, context.restore)
+}
+
+// Verify checks every persistence archive in the backup set can be read back, without restoring it
+func (context *ElasticRuntime) Verify() (report cfbackup.VerificationReport, err error) {
+	var systems []cfbackup.SystemDump
+
+	if systems, _, err = context.SelectedSystems(); err != nil {
+		return
+	}
+
+	for _, info := range systems {
+		filename := fmt.Sprintf(ERBackupFileFormat, info.Get(cfbackup.SDComponent))
+		lo.G.Debug("Verifying %s", filename)
+		report.Record(filename, cfbackup.VerifyArtifact(context, cfbackup.GetArtifactCheck(info), context.TargetDir, filename))
+	}
+
+	if !report.Passed() {
+		lo.G.Error("elastic runtime backup set failed verification", log.Data{"failures": report.Failures()})
+		err = cfbackup.ErrVerificationFailed
+	}
 	return
 }
 
-func (context *ElasticRuntime) backupRestore(action int) (err error) {
+// RunReport returns the report of the last backup or restore, nil before the first one
+func (context *ElasticRuntime) RunReport() *cfbackup.RunReport {
+	return context.report
+}
+
+func (context *ElasticRuntime) backup(ctx context.Context) error {
+	return context.backupRestore(ctx, cfbackup.ExportArchive)
+}
+
+func (context *ElasticRuntime) restore(ctx context.Context) error {
+	return context.backupRestore(ctx, cfbackup.ImportArchive)
+}
+
+func (context *ElasticRuntime) backupRestore(ctx context.Context, action int) (err error) {
 	var (
-		ccJobs []cfbackup.CCJob
+		ccJobs   []cfbackup.CCJob
+		systems  []cfbackup.SystemDump
+		toggleCC bool
+		dbErr    error
 	)
+	report := cfbackup.NewRunReport(ERTileName, hookAction(action))
+	context.report = report
+	defer func() {
+		report.Finish(err, dbErr)
+
+		if writeErr := report.Write(context, context.TargetDir, fmt.Sprintf(cfbackup.RunReportFileFormat, report.Action)); writeErr != nil {
+			lo.G.Error("failed to store the run report", writeErr)
+		}
+	}()
+
+	if systems, toggleCC, err = context.SelectedSystems(); err != nil {
+		return
+	}
 
 	if err = context.ReadAllUserCredentials(); err == nil && context.directorCredentialsValid() {
-		lo.G.Debug("Retrieving All CC VMs")
-		manifest, erro := context.getManifest()
+		if action == cfbackup.ExportArchive {
+			if err = context.storeInstallationSettings(); err == nil {
+				systems, toggleCC = context.resumeCheckpoint(systems, toggleCC, report)
+			}
+		} else {
+			context.warnOnlineBackupSet(report)
+
+			if context.RemapRoles {
+				err = context.remapRoles(systems, report)
+			}
+		}
+
 		if err != nil {
-			return erro
+			return
+		}
+		lo.G.Debug("Retrieving All CC VMs")
+		var manifest string
+		if manifest, err = context.getManifest(); err != nil {
+			return
 		}
-		if ccJobs, err = context.getAllCloudControllerVMs(); err == nil {
+		if action == cfbackup.ExportArchive && context.Online {
+			context.backupOnline(systems, report)
+		} else if !toggleCC {
+			lo.G.Info("no selected component affects the cloud controllers, leaving them running")
+		} else if ccJobs, err = context.getAllCloudControllerVMs(); err != nil {
+			lo.G.Error("failed to list the cloud controller vms", err)
+			return
+		} else if len(ccJobs) > 0 {
 			directorInfo := context.SystemsInfo.SystemDumps[cfbackup.ERDirector]
-			cloudController := cfbackup.NewCloudController(directorInfo.Get(cfbackup.SDIP), directorInfo.Get(cfbackup.SDUser), directorInfo.Get(cfbackup.SDPass), context.InstallationName, manifest, ccJobs)
+			cloudController := cfbackup.NewCloudController(directorInfo.Get(cfbackup.SDIP), directorInfo.Get(cfbackup.SDUser), directorInfo.Get(cfbackup.SDPass), context.InstallationName, manifest, ccJobs, context.TLS)
+			cloudController.Concurrency = context.ccToggleConcurrency()
+			context.ccSupervisor = cfbackup.NewCCSupervisor(cloudController)
+			context.ccSupervisor.Timeout = context.PhaseTimeouts.CCStop
+			context.ccSupervisor.Concurrency = context.ccToggleConcurrency()
 			lo.G.Debug("Setting up CC jobs")
-			defer cloudController.Start()
-			cloudController.Stop()
+			defer context.startCloudControllers(cloudController, report)
+			if err = context.stopCloudControllers(ctx, cloudController, report); err != nil {
+				return
+			}
+		} else {
+			context.runWithoutCloudControllers(action, systems, report)
 		}
 		lo.G.Debug("Running db action")
-		if len(context.PersistentSystems) > 0 {
-			err = context.RunDbAction(context.PersistentSystems, action)
-			if err != nil {
+		if len(systems) > 0 {
+			err = context.RunDbActionWithContext(ctx, systems, action)
+			if err != nil && ctx.Err() != nil {
+				lo.G.Error("db action cancelled", err)
+				err = ctx.Err()
+			} else if err == cfbackup.ErrPreHookFailed {
+				lo.G.Error("db action aborted by a pre component hook")
+			} else if err != nil {
 				lo.G.Error("Error backing up db", err)
-				err = ErrERDBBackup
+				dbErr, err = err, ErrERDBBackup
 			}
 		} else {
 			lo.G.Info("There is no internal persistent system used by ERT, skip db action")
+			report.Warn("there is no internal persistent system used by ERT, skipped db action")
+		}
+
+		if action == cfbackup.ExportArchive && err == nil {
+			context.checkpoint.Finished = true
+			context.writeCheckpoint()
 		}
 	} else if err == nil {
 		err = cfbackup.ErrERDirectorCreds
@@ -78,53 +187,500 @@ func (context *ElasticRuntime) backupRestore(action int) (err error) {
 	return
 }
 
+//backupOnline - prepares a backup which leaves the cloud controllers running. Mysql is dumped in a single
+//transaction, and the report marks the backup set online along with the consistency it can not guarantee
+func (context *ElasticRuntime) backupOnline(systems []cfbackup.SystemDump, report *cfbackup.RunReport) {
+	lo.G.Warning("online backup, leaving the cloud controllers running")
+	report.Mode = cfbackup.RunModeOnline
+
+	for _, system := range systems {
+		if mysql, ok := system.(*cfbackup.MysqlInfo); ok {
+			mysql.SingleTransaction = true
+		}
+	}
+
+	for _, caveat := range EROnlineCaveats {
+		report.Warn("%s", caveat)
+	}
+}
+
+//runWithoutCloudControllers - records that no cloud controller job matched the patterns, so whatever cloud
+//controllers the deployment has kept running: a backup is taken and marked online, a restore is marked inconsistent
+func (context *ElasticRuntime) runWithoutCloudControllers(action int, systems []cfbackup.SystemDump, report *cfbackup.RunReport) {
+	warning := fmt.Sprintf(ERNoCCJobsWarningFormat, strings.Join(context.ccJobPatterns(), ", "))
+	lo.G.Warning(warning)
+	report.Warn("%s", warning)
+
+	if action == cfbackup.ExportArchive {
+		context.backupOnline(systems, report)
+	} else {
+		report.Mode = cfbackup.RunModeInconsistent
+	}
+}
+
+//warnOnlineBackupSet - warns the operator when the backup set being restored was taken online
+func (context *ElasticRuntime) warnOnlineBackupSet(report *cfbackup.RunReport) {
+	backupReport, err := cfbackup.ReadRunReport(context, context.TargetDir, fmt.Sprintf(cfbackup.RunReportFileFormat, hookAction(cfbackup.ExportArchive)))
+
+	if err == nil && backupReport.Mode == cfbackup.RunModeOnline {
+		lo.G.Warning(EROnlineRestoreWarning)
+		report.Warn("%s", EROnlineRestoreWarning)
+	}
+}
+
+//storeInstallationSettings - keeps the installation settings of the backed up foundation in the backup set,
+//so a restore into a different foundation can tell which roles the dumps were taken with
+func (context *ElasticRuntime) storeInstallationSettings() (err error) {
+	var (
+		settings *os.File
+		writer   io.WriteCloser
+	)
+
+	if settings, err = os.Open(context.JSONFile); err == nil {
+		defer settings.Close()
+
+		if writer, err = context.Writer(context.TargetDir, ERSourceSettingsFilename); err == nil {
+			defer writer.Close()
+			_, err = io.Copy(writer, settings)
+		}
+	}
+	return
+}
+
+//SourceInstallationSettings - the installation settings of the foundation the backup set was taken from,
+//falling back to the ops manager copy for backup sets taken before the elastic runtime kept its own
+func (context *ElasticRuntime) SourceInstallationSettings() (installationSettings cfbackup.InstallationSettings, err error) {
+	var reader io.ReadCloser
+
+	if reader, err = context.Reader(context.TargetDir, ERSourceSettingsFilename); err != nil {
+		lo.G.Debug("no elastic runtime installation settings in the backup set, trying the ops manager copy", err)
+		reader, err = context.Reader(context.TargetDir, opsmanager.OpsMgrBackupDir, opsmanager.OpsMgrInstallationSettingsFilename)
+	}
+
+	if err == nil {
+		defer reader.Close()
+		installationSettings = cfbackup.NewConfigurationParserFromReader(reader).InstallationSettings
+	}
+	return
+}
+
+//RoleRemaps - maps the database role of every selected component in the source foundation to its role in this one,
+//leaving out the components whose role did not change
+func (context *ElasticRuntime) RoleRemaps(systems []cfbackup.SystemDump, source cfbackup.InstallationSettings) (remaps []*cfbackup.RoleRemap) {
+	for _, info := range systems {
+		product, component, identifier := info.Get(cfbackup.SDProduct), info.Get(cfbackup.SDComponent), info.Get(cfbackup.SDIdentifier)
+
+		if identifier == ERVMCredentialsIdentifier {
+			continue
+		}
+
+		if sourceRole, _, err := context.getUserIDPasswordForIdentifier(source, product, component, identifier); err != nil {
+			lo.G.Error("no source credentials for component", log.Data{"component": component, "error": err})
+
+		} else if targetRole := info.Get(cfbackup.SDUser); sourceRole != "" && sourceRole != targetRole {
+			remaps = append(remaps, &cfbackup.RoleRemap{Component: component, Source: sourceRole, Target: targetRole})
+		}
+	}
+	return
+}
+
+func (context *ElasticRuntime) remapRoles(systems []cfbackup.SystemDump, report *cfbackup.RunReport) (err error) {
+	var source cfbackup.InstallationSettings
+
+	if source, err = context.SourceInstallationSettings(); err != nil {
+		return
+	}
+	remaps := context.RoleRemaps(systems, source)
+
+	if err = context.CheckRemappable(remaps); err != nil {
+		return
+	}
+	context.remaps = remaps
+	report.RecordRemaps(context.remaps...)
+
+	for _, remap := range context.remaps {
+		lo.G.Info("remapping role ", remap)
+	}
+	return
+}
+
+//CheckRemappable - fails, before anything is restored, when the archive of a component whose roles have to be remapped
+//is a custom format pg_dump, as restoring it would leave its objects owned by the source foundation's roles
+func (context *ElasticRuntime) CheckRemappable(remaps []*cfbackup.RoleRemap) (err error) {
+	for _, remap := range remaps {
+		var reader io.ReadCloser
+		filename := fmt.Sprintf(ERBackupFileFormat, remap.Component)
+
+		if reader, err = context.Reader(path.Join(context.TargetDir, filename)); err != nil {
+			return
+		}
+		_, err = cfbackup.NewRoleRemappingReader(reader, nil)
+		reader.Close()
+
+		if err != nil {
+			lo.G.Error("unable to remap the roles of ", filename, err)
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+	return
+}
+
+func (context *ElasticRuntime) componentRemaps(component string) (remaps []*cfbackup.RoleRemap) {
+	for _, remap := range context.remaps {
+		if remap.Component == component {
+			remaps = append(remaps, remap)
+		}
+	}
+	return
+}
+
+//resumeCheckpoint - starts the checkpoint of a backup. In resume mode an unfinished checkpoint left in the backup set
+//is picked up instead, and the components whose artifacts it recorded and which still verify are reused rather than
+//dumped again. The cloud controllers are only toggled when a remaining component needs them stopped.
+func (context *ElasticRuntime) resumeCheckpoint(systems []cfbackup.SystemDump, toggleCC bool, report *cfbackup.RunReport) (remaining []cfbackup.SystemDump, remainingToggleCC bool) {
+	var (
+		reused []string
+		oldest time.Time
+	)
+	context.checkpoint = cfbackup.NewCheckpoint()
+
+	if !context.Resume {
+		return systems, toggleCC
+	}
+	checkpoint, err := cfbackup.ReadCheckpoint(context, context.TargetDir, cfbackup.CheckpointFileName)
+
+	if err != nil || checkpoint.Finished {
+		lo.G.Info("no unfinished backup to resume, backing up every component")
+		return systems, toggleCC
+	}
+	context.checkpoint = checkpoint
+
+	for _, info := range systems {
+		component := info.Get(cfbackup.SDComponent)
+
+		if completed, ok := checkpoint.Completed(component); ok && context.reusable(info, completed) {
+			report.ReuseComponent(component, completed)
+			reused = append(reused, component)
+
+			if oldest.IsZero() || completed.Completed.Before(oldest) {
+				oldest = completed.Completed
+			}
+		} else {
+			remaining = append(remaining, info)
+		}
+	}
+
+	if len(reused) == 0 {
+		return remaining, toggleCC
+	}
+	lo.G.Info("resuming backup, reusing ", strings.Join(reused, ", "))
+
+	if len(remaining) > 0 {
+		gap := time.Since(oldest).Round(time.Second)
+		lo.G.Warning(fmt.Sprintf("resumed backup reuses %s dumped %s ago, the backup set is not a consistent point in time", strings.Join(reused, ", "), gap))
+		report.Warn("resumed backup reuses %s dumped %s before the remaining components, they may not be consistent with each other", strings.Join(reused, ", "), gap)
+	}
+	return remaining, toggleCC && affectsCloudControllers(remaining)
+}
+
+func (context *ElasticRuntime) reusable(info cfbackup.SystemDump, completed *cfbackup.ComponentCheckpoint) bool {
+	filename := fmt.Sprintf(ERBackupFileFormat, info.Get(cfbackup.SDComponent))
+
+	if err := completed.Verify(context); err != nil {
+		lo.G.Info("checkpointed artifact changed, dumping it again ", filename, err)
+		return false
+	}
+
+	if err := cfbackup.VerifyArtifact(context, cfbackup.GetArtifactCheck(info), context.TargetDir, filename); err != nil {
+		lo.G.Info("checkpointed artifact failed verification, dumping it again ", filename, err)
+		return false
+	}
+	return true
+}
+
+func (context *ElasticRuntime) writeCheckpoint() {
+	if err := context.checkpoint.Write(context, context.TargetDir, cfbackup.CheckpointFileName); err != nil {
+		lo.G.Error("failed to store the backup checkpoint", err)
+	}
+}
+
+func affectsCloudControllers(systems []cfbackup.SystemDump) bool {
+	for _, info := range systems {
+		if containsComponent(ERCloudControllerComponents, info.Get(cfbackup.SDComponent)) {
+			return true
+		}
+	}
+	return false
+}
+
+//SelectedSystems - the persistent systems picked by IncludeComponents and ExcludeComponents, in their original order.
+//Every named component has to exist in the installation. toggleCC is false only when a selection was made
+//and none of the selected components is read or written by the cloud controllers.
+func (context *ElasticRuntime) SelectedSystems() (systems []cfbackup.SystemDump, toggleCC bool, err error) {
+	available := make(map[string]bool)
+
+	for _, info := range context.PersistentSystems {
+		available[info.Get(cfbackup.SDComponent)] = true
+	}
+
+	for _, selection := range [][]string{context.IncludeComponents, context.ExcludeComponents} {
+		for _, component := range selection {
+			if !available[component] {
+				err = fmt.Errorf(ERUnknownComponentFormat, component)
+				lo.G.Error("invalid component selection", log.Data{"component": component, "available": available})
+				return
+			}
+		}
+	}
+	toggleCC = len(context.IncludeComponents) == 0 && len(context.ExcludeComponents) == 0
+
+	for _, info := range context.PersistentSystems {
+		component := info.Get(cfbackup.SDComponent)
+
+		if (len(context.IncludeComponents) == 0 || containsComponent(context.IncludeComponents, component)) &&
+			!containsComponent(context.ExcludeComponents, component) {
+			systems = append(systems, info)
+			toggleCC = toggleCC || affectsCloudControllers([]cfbackup.SystemDump{info})
+		}
+	}
+	return
+}
+
+func containsComponent(components []string, component string) bool {
+	for _, c := range components {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
+
 func (context *ElasticRuntime) getAllCloudControllerVMs() (ccvms []cfbackup.CCJob, err error) {
 
 	lo.G.Debug("Entering getAllCloudControllerVMs() function")
 	directorInfo := context.SystemsInfo.SystemDumps[cfbackup.ERDirector]
 	connectionURL := fmt.Sprintf(ERVmsURL, directorInfo.Get(cfbackup.SDIP), context.InstallationName)
-	lo.G.Debug("getAllCloudControllerVMs() function", log.Data{"connectionURL": connectionURL, "directorInfo": directorInfo})
-	gateway := context.HTTPGateway
-	if gateway == nil {
-		gateway = ghttp.NewHttpGateway()
-	}
+	lo.G.Debug("getAllCloudControllerVMs() function", log.Data{"connectionURL": connectionURL})
+	gateway := context.directorGateway(directorInfo)
 	lo.G.Debug("Retrieving CC vms")
-	if resp, err := gateway.Get(ghttp.HttpRequestEntity{
+	var (
+		resp    *http.Response
+		body    []byte
+		jsonObj []cfbackup.VMObject
+	)
+
+	if resp, err = gateway.Get(ghttp.HttpRequestEntity{
 		Url:         connectionURL,
 		Username:    directorInfo.Get(cfbackup.SDUser),
 		Password:    directorInfo.Get(cfbackup.SDPass),
 		ContentType: "application/json",
-	})(); err == nil {
-		var jsonObj []cfbackup.VMObject
-
-		lo.G.Debug("Unmarshalling CC vms")
-		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		if err = json.Unmarshal(body, &jsonObj); err == nil {
-			ccvms, err = cfbackup.GetCCVMs(jsonObj)
-		}
+	})(); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	lo.G.Debug("Unmarshalling CC vms")
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(body, &jsonObj); err == nil {
+		ccvms, err = cfbackup.GetCCVMs(jsonObj, context.ccJobPatterns()...)
+	}
+	return
+}
+
+//ccJobPatterns - the configured CCJobPatterns, or the defaults for the version of the installation
+func (context *ElasticRuntime) ccJobPatterns() []string {
+	if len(context.CCJobPatterns) > 0 {
+		return context.CCJobPatterns
+	}
+	return cfbackup.NewConfigurationParser(context.JSONFile).InstallationSettings.GetCCJobPatterns()
+}
+
+//stopCloudControllers - stops the cloud controller jobs, failing the run when any of them could not be stopped.
+//The deferred startCloudControllers restarts the ones which were
+func (context *ElasticRuntime) stopCloudControllers(ctx context.Context, cloudController *cfbackup.CloudController, report *cfbackup.RunReport) (err error) {
+	stopCtx, cancel := cfbackup.WithPhaseTimeout(ctx, context.PhaseTimeouts.CCStop)
+	defer cancel()
+	cloudController.Tasks = nil
+
+	if err = context.ccSupervisor.Stop(stopCtx); err != nil {
+		lo.G.Error("failed to stop cloud controllers", err)
 	}
+	report.RecordCCTasks(cloudController.Tasks...)
 	return
 }
 
+//startCloudControllers - restarts exactly the cloud controller jobs the run stopped. It is deferred, so it also runs when the run panics
+func (context *ElasticRuntime) startCloudControllers(cloudController *cfbackup.CloudController, report *cfbackup.RunReport) {
+	cloudController.Tasks = nil
+
+	if err := context.ccSupervisor.Restart(); err != nil {
+		report.Warn("failed to restart cloud controllers: %s", err)
+	}
+	report.RecordCCTasks(cloudController.Tasks...)
+	context.ccSupervisor = nil
+}
+
 //RunDbAction - run a db action dump/import against a list of systemdump types
 func (context *ElasticRuntime) RunDbAction(dbInfoList []cfbackup.SystemDump, action int) (err error) {
+	return context.RunDbActionWithContext(noDeadline, dbInfoList, action)
+}
+
+//RunDbActionWithContext - run a db action dump/import against a list of systemdump types, each bounded by its phase timeout and stopping when ctx is done.
+//Backups of components on different vms run in parallel, up to Concurrency at a time. Every component
+//is attempted and recorded in the run report, and the first failure in list order is returned.
+func (context *ElasticRuntime) RunDbActionWithContext(ctx context.Context, dbInfoList []cfbackup.SystemDump, action int) (err error) {
+	var wg sync.WaitGroup
+	errs := make([]error, len(dbInfoList))
+	components := make([]*cfbackup.ComponentReport, len(dbInfoList))
+	workers := make(chan struct{}, context.concurrency())
+	runCtx, abort := cfbackup.WithPhaseTimeout(ctx, 0)
+	defer abort()
+
+	for i, info := range dbInfoList {
+		components[i] = context.report.StartComponent(info.Get(cfbackup.SDComponent), path.Join(context.TargetDir, fmt.Sprintf(ERBackupFileFormat, info.Get(cfbackup.SDComponent))))
+	}
 
-	for _, info := range dbInfoList {
-		lo.G.Debug(fmt.Sprintf("RunDbAction info: %v %v", info.Get("Product"), info.Get("Component")))
+	for _, group := range context.componentGroups(dbInfoList, action) {
+		wg.Add(1)
+		workers <- struct{}{}
 
-		if err = info.Error(); err == nil {
-			err = context.readWriterArchive(info, context.TargetDir, action)
+		go func(group []int) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			defer context.ccSupervisor.RestartOnPanic()
 
+			for _, i := range group {
+				if errs[i] = runCtx.Err(); errs[i] == nil {
+					errs[i] = context.runComponent(runCtx, dbInfoList[i], components[i], action)
+				} else {
+					components[i].Finish(errs[i])
+				}
+
+				if errs[i] == cfbackup.ErrPreHookFailed {
+					abort()
+				}
+			}
+		}(group)
+	}
+	wg.Wait()
+	return firstComponentError(errs)
+}
+
+func (context *ElasticRuntime) runComponent(ctx context.Context, info cfbackup.SystemDump, component *cfbackup.ComponentReport, action int) (err error) {
+	lo.G.Debug(fmt.Sprintf("RunDbAction info: %v %v", info.Get("Product"), info.Get("Component")))
+
+	if err = info.Error(); err != nil {
+		lo.G.Error("readWriterArchive err: ", err)
+		component.Finish(err)
+		return
+	}
+	event := context.hookEvent(info, action)
+
+	if err = context.Hooks.BeforeComponent(ctx, event); err != nil {
+		component.Finish(err)
+		return
+	}
+	component.Started = time.Now()
+	err = context.readWriterArchiveWithTimeout(ctx, info, component, action)
+	component.Finish(err)
+
+	if err == nil && action == cfbackup.ExportArchive && context.checkpoint != nil {
+		context.checkpoint.Complete(component)
+		context.writeCheckpoint()
+	}
+
+	if hookErr := context.Hooks.AfterComponent(ctx, event, err); err == nil {
+		err = hookErr
+	}
+	return
+}
+
+func (context *ElasticRuntime) concurrency() int {
+	if context.Concurrency < 1 {
+		return 1
+	}
+	return context.Concurrency
+}
+
+//ccToggleConcurrency - how many cloud controller jobs are stopped or started at a time, separate from the
+//component concurrency since the toggles queue on the director's deployment lock rather than load the vms
+func (context *ElasticRuntime) ccToggleConcurrency() int {
+	if context.CCToggleConcurrency < 1 {
+		return cfbackup.DefaultCCToggleConcurrency
+	}
+	return context.CCToggleConcurrency
+}
+
+//componentGroups - splits the component list into groups which may run in parallel.
+//Components on the same vm share a group and run in list order. Restores, and
+//runs without concurrency, keep the whole list in a single group so they replay
+//in the same order as before.
+func (context *ElasticRuntime) componentGroups(dbInfoList []cfbackup.SystemDump, action int) (groups [][]int) {
+	byIP := make(map[string]int)
+
+	for i, info := range dbInfoList {
+		key := info.Get(cfbackup.SDIP)
+
+		if context.concurrency() == 1 || action == cfbackup.ImportArchive {
+			key = ""
+		}
+
+		if group, ok := byIP[key]; ok {
+			groups[group] = append(groups[group], i)
 		} else {
-			lo.G.Error("readWriterArchive err: ", err)
-			break
+			byIP[key] = len(groups)
+			groups = append(groups, []int{i})
+		}
+	}
+	return
+}
+
+func firstComponentError(errs []error) (err error) {
+	for _, componentErr := range errs {
+		if componentErr == cfbackup.ErrPreHookFailed {
+			return componentErr
+		}
+
+		if err == nil {
+			err = componentErr
 		}
 	}
 	return
 }
 
-func (context *ElasticRuntime) readWriterArchive(dbInfo cfbackup.SystemDump, databaseDir string, action int) (err error) {
+func (context *ElasticRuntime) hookEvent(info cfbackup.SystemDump, action int) cfbackup.HookEvent {
+	return cfbackup.HookEvent{
+		Action:    hookAction(action),
+		Tile:      ERTileName,
+		Component: info.Get(cfbackup.SDComponent),
+		TargetDir: context.TargetDir,
+	}
+}
+
+func hookAction(action int) string {
+	if action == cfbackup.ImportArchive {
+		return cfbackup.HookActionRestore
+	}
+	return cfbackup.HookActionBackup
+}
+
+func (context *ElasticRuntime) readWriterArchiveWithTimeout(ctx context.Context, dbInfo cfbackup.SystemDump, component *cfbackup.ComponentReport, action int) (err error) {
+	timeout := context.PhaseTimeouts.DBDump
+
+	if _, isNfs := dbInfo.(*cfbackup.NfsInfo); isNfs {
+		timeout = context.PhaseTimeouts.NFSDump
+	}
+	phaseCtx, cancel := cfbackup.WithPhaseTimeout(ctx, timeout)
+	defer cancel()
+	return context.readWriterArchive(phaseCtx, dbInfo, component, context.TargetDir, action)
+}
+
+func (context *ElasticRuntime) readWriterArchive(ctx context.Context, dbInfo cfbackup.SystemDump, component *cfbackup.ComponentReport, databaseDir string, action int) (err error) {
 	filename := fmt.Sprintf(ERBackupFileFormat, dbInfo.Get(cfbackup.SDComponent))
 	filepath := path.Join(databaseDir, filename)
 
@@ -137,7 +693,15 @@ func (context *ElasticRuntime) readWriterArchive(dbInfo cfbackup.SystemDump, dat
 			var backupReader io.ReadCloser
 			if backupReader, err = context.Reader(filepath); err == nil {
 				defer backupReader.Close()
-				err = pb.Import(backupReader)
+				reader := component.Reader(backupReader)
+
+				if remaps := context.componentRemaps(dbInfo.Get(cfbackup.SDComponent)); len(remaps) > 0 {
+					if reader, err = cfbackup.NewRoleRemappingReader(reader, remaps); err != nil {
+						lo.G.Error("unable to remap the roles of ", filename, err)
+						return
+					}
+				}
+				err = cfbackup.ImportWithContext(ctx, pb, reader)
 				lo.G.Debug("Done restoring %s", dbInfo.Get(cfbackup.SDComponent))
 			}
 		case cfbackup.ExportArchive:
@@ -145,7 +709,7 @@ func (context *ElasticRuntime) readWriterArchive(dbInfo cfbackup.SystemDump, dat
 			var backupWriter io.WriteCloser
 			if backupWriter, err = context.Writer(filepath); err == nil {
 				defer backupWriter.Close()
-				err = pb.Dump(backupWriter)
+				err = cfbackup.DumpWithContext(ctx, pb, component.Writer(backupWriter))
 				lo.G.Debug("Done backing up ", dbInfo.Get(cfbackup.SDComponent), err)
 			}
 		}
@@ -164,11 +728,29 @@ func (context *ElasticRuntime) ReadAllUserCredentials() (err error) {
 func (context *ElasticRuntime) assignCredentialsAndInstallationName(installationSettings cfbackup.InstallationSettings) (err error) {
 
 	if err = context.assignCredentials(installationSettings); err == nil {
+		context.assignDirectorClient(installationSettings)
 		context.InstallationName, err = context.getDeploymentName(installationSettings)
 	}
 	return
 }
 
+//assignDirectorClient - hands the director's uaa client from the installation settings to its DirectorAuth,
+//for directors which take uaa tokens
+func (context *ElasticRuntime) assignDirectorClient(installationSettings cfbackup.InstallationSettings) {
+	directorInfo, ok := context.SystemsInfo.SystemDumps[cfbackup.ERDirector]
+
+	if !ok {
+		return
+	}
+
+	for _, identifier := range cfbackup.DirectorUAAClientIdentifiers {
+		if clientID, clientSecret, err := context.getUserIDPasswordForIdentifier(installationSettings, directorInfo.Get(cfbackup.SDProduct), directorInfo.Get(cfbackup.SDComponent), identifier); err == nil && clientID != "" {
+			cfbackup.DirectorAuthFor(directorInfo.Get(cfbackup.SDIP), directorInfo.Get(cfbackup.SDUser), directorInfo.Get(cfbackup.SDPass)).SetClientCredentials(clientID, clientSecret)
+			return
+		}
+	}
+}
+
 func (context *ElasticRuntime) assignCredentials(installationSettings cfbackup.InstallationSettings) (err error) {
 
 	for name, sysInfo := range context.SystemsInfo.SystemDumps {
@@ -185,7 +767,7 @@ func (context *ElasticRuntime) assignCredentials(installationSettings cfbackup.I
 			sysInfo.Set(cfbackup.SDIP, ip)
 			sysInfo.Set(cfbackup.SDVcapPass, pass)
 			sysInfo.Set(cfbackup.SDVcapUser, userID)
-			if identifier == "vm_credentials" {
+			if identifier == ERVMCredentialsIdentifier {
 				sysInfo.Set(cfbackup.SDUser, userID)
 				sysInfo.Set(cfbackup.SDPass, pass)
 			} else if userID, pass, err = context.getUserIDPasswordForIdentifier(installationSettings, productName, jobName, identifier); err == nil {
@@ -203,31 +785,34 @@ func (context *ElasticRuntime) directorCredentialsValid() (ok bool) {
 
 	if directorInfo, ok = context.SystemsInfo.SystemDumps[cfbackup.ERDirector]; ok {
 		connectionURL := fmt.Sprintf(cfbackup.ERDirectorInfoURL, directorInfo.Get(cfbackup.SDIP))
-		gateway := context.HTTPGateway
-		userId := directorInfo.Get(cfbackup.SDUser)
-		password := directorInfo.Get(cfbackup.SDPass)
-		if gateway == nil {
-			gateway = ghttp.NewHttpGateway()
-		}
+		gateway := context.directorGateway(directorInfo)
 		if _, err := gateway.Get(ghttp.HttpRequestEntity{
 			Url:         connectionURL,
-			Username:    userId,
-			Password:    password,
+			Username:    directorInfo.Get(cfbackup.SDUser),
+			Password:    directorInfo.Get(cfbackup.SDPass),
 			ContentType: "application/json",
 		})(); err == nil {
 			ok = true
 		} else {
 			ok = false
-			lo.G.Debug("Error connecting to server %v", err)
-			lo.G.Debug("Error connecting to director using %s, UserId-[%s] and Password[%s]", connectionURL, userId, password)
+			lo.G.Debug("Error connecting to director using ", connectionURL, err)
 		}
 	}
 	return
 }
 
+//directorGateway - the gateway to the director, authenticating with uaa tokens when the director takes them
+func (context *ElasticRuntime) directorGateway(directorInfo cfbackup.SystemDump) ghttp.HttpGateway {
+	gateway := context.HTTPGateway
+	if gateway == nil {
+		gateway = cfbackup.NewTLSGateway(cfbackup.TLSTargetDirector, context.TLS)
+	}
+	return cfbackup.NewDirectorGateway(gateway, cfbackup.DirectorAuthFor(directorInfo.Get(cfbackup.SDIP), directorInfo.Get(cfbackup.SDUser), directorInfo.Get(cfbackup.SDPass)))
+}
+
 func (context *ElasticRuntime) getManifest() (manifest string, err error) {
 	directorInfo, _ := context.SystemsInfo.SystemDumps[cfbackup.ERDirector]
-	director := cfbackup.NewDirector(directorInfo.Get(cfbackup.SDIP), directorInfo.Get(cfbackup.SDUser), directorInfo.Get(cfbackup.SDPass), 25555)
+	director := cfbackup.NewDirector(directorInfo.Get(cfbackup.SDIP), directorInfo.Get(cfbackup.SDUser), directorInfo.Get(cfbackup.SDPass), 25555, context.TLS)
 	mfs, err := director.GetDeploymentManifest(context.InstallationName)
 	if err != nil {
 		return
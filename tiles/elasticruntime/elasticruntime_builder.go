@@ -6,6 +6,7 @@ import (
 	"github.com/pivotalservices/cfbackup"
 	"github.com/pivotalservices/cfbackup/tileregistry"
 	"github.com/pivotalservices/cfbackup/tiles/opsmanager"
+	"github.com/xchapter7x/lo"
 )
 
 //New -- method to generate an initialized elastic runtime
@@ -13,8 +14,18 @@ func (s *ElasticRuntimeBuilder) New(tileSpec tileregistry.TileSpec) (elasticRunt
 	var (
 		installationSettings io.Reader
 		tmpfile              *TempFile
+		tlsClients           *cfbackup.TLSClients
 		sshKey               = ""
 	)
+
+	if tileSpec, err = tileSpec.ResolveSecrets(); err != nil {
+		return
+	}
+
+	if tlsClients, err = tileSpec.TLSClients(); err != nil {
+		return
+	}
+
 	if tmpfile, err = NewTempFile(opsmanager.OpsMgrInstallationSettingsFilename); err == nil {
 
 		if installationSettings, err = GetInstallationSettings(tileSpec); err == nil {
@@ -25,9 +36,22 @@ func (s *ElasticRuntimeBuilder) New(tileSpec tileregistry.TileSpec) (elasticRunt
 				sshKey = iaas.SSHPrivateKey
 			}
 			elasticRuntime := NewElasticRuntime(tmpfile.FileRef.Name(), tileSpec.ArchiveDirectory, sshKey, tileSpec.CryptKey, tileSpec.NFS)
+			tileSpec.ApplyBackupContext(&elasticRuntime.BackupContext)
+			elasticRuntime.TLS = trustDirectorCA(tileSpec, tlsClients)
+			elasticRuntime.PhaseTimeouts = tileSpec.PhaseTimeouts
+			elasticRuntime.Hooks = tileSpec.Hooks
+			elasticRuntime.Concurrency = tileSpec.Concurrency
+			elasticRuntime.CCToggleConcurrency = tileSpec.CCToggleConcurrency
+			elasticRuntime.CCJobPatterns = tileSpec.CCJobPatterns
+			elasticRuntime.Online = tileSpec.Online
+			elasticRuntime.IncludeComponents = tileSpec.IncludeComponents
+			elasticRuntime.ExcludeComponents = tileSpec.ExcludeComponents
+			elasticRuntime.RemapRoles = tileSpec.RemapRoles
+			elasticRuntime.Resume = tileSpec.Resume
+			elasticRuntime.Lock = tileregistry.NewRunLock(tileSpec, elasticRuntime.BackupContext)
 			elasticRuntimeCloser = struct {
-				tileregistry.Tile
-				tileregistry.Closer
+				*ElasticRuntime
+				*TempFile
 			}{
 				elasticRuntime,
 				tmpfile,
@@ -37,14 +61,28 @@ func (s *ElasticRuntimeBuilder) New(tileSpec tileregistry.TileSpec) (elasticRunt
 	return
 }
 
-//GetInstallationSettings - makes a call to ops manager and returns a io.reader containing the contents of the installation settings file.
-var GetInstallationSettings = func(tileSpec tileregistry.TileSpec) (settings io.Reader, err error) {
-	var (
-		opsManager *opsmanager.OpsManager
-	)
+//trustDirectorCA - the tileSpec's clients verifying the director with the CA ops manager issued its certificate with,
+//when the tileSpec names no director CA. Without it the director is verified against the system roots and pins alone
+func trustDirectorCA(tileSpec tileregistry.TileSpec, tlsClients *cfbackup.TLSClients) *cfbackup.TLSClients {
+	if tileSpec.TLS.DirectorCA != "" || tileSpec.TLS.Insecure {
+		return tlsClients
+	}
+	ca, err := GetDirectorCA(tileSpec)
+
+	if err == nil {
+		var directorClients *cfbackup.TLSClients
+		tileSpec.TLS.DirectorCA = ca
 
-	if opsManager, err = opsmanager.NewOpsManager(tileSpec.OpsManagerHost, tileSpec.AdminUser, tileSpec.AdminPass, tileSpec.AdminToken, tileSpec.OpsManagerUser, tileSpec.OpsManagerPass, tileSpec.OpsManagerPassphrase, tileSpec.ClientID, tileSpec.ClientSecret, tileSpec.ArchiveDirectory, tileSpec.CryptKey); err == nil {
-		settings, err = opsManager.GetInstallationSettings()
+		if directorClients, err = tileSpec.TLSClients(); err == nil {
+			return directorClients
+		}
 	}
-	return
+	lo.G.Warning("could not use the director CA from ops manager, verifying the director against the system roots: ", err)
+	return tlsClients
 }
+
+//GetInstallationSettings - makes a call to ops manager and returns a io.reader containing the contents of the installation settings file.
+var GetInstallationSettings = opsmanager.GetInstallationSettings
+
+//GetDirectorCA - makes a call to ops manager and returns the pem of the CA it issued the director's certificate with.
+var GetDirectorCA = opsmanager.GetDirectorCA
@@ -1,8 +1,17 @@
 package elasticruntime_test
 
 import (
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
 	"github.com/pivotalservices/cfbackup/tileregistry"
 	. "github.com/pivotalservices/cfbackup/tiles/elasticruntime"
 )
@@ -20,5 +29,69 @@ var _ = Describe("ElasticRuntimeBuilder", func() {
 				Ω(err).Should(HaveOccurred())
 			})
 		})
+
+		Context("when ops manager returns the installation settings", func() {
+			var (
+				director                        *httptest.Server
+				directorCAErr                   error
+				target                          string
+				originalGetInstallationSettings = GetInstallationSettings
+				originalGetDirectorCA           = GetDirectorCA
+			)
+
+			BeforeEach(func() {
+				director = httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+				directorCAErr = nil
+				target, _ = ioutil.TempDir("", "spec")
+				GetInstallationSettings = func(tileregistry.TileSpec) (io.Reader, error) {
+					return os.Open("../../fixtures/installation-settings-1-6-aws.json")
+				}
+				GetDirectorCA = func(tileregistry.TileSpec) (string, error) {
+					return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: director.Certificate().Raw})), directorCAErr
+				}
+			})
+
+			AfterEach(func() {
+				GetInstallationSettings = originalGetInstallationSettings
+				GetDirectorCA = originalGetDirectorCA
+				director.Close()
+				os.RemoveAll(target)
+			})
+
+			build := func(tileSpec tileregistry.TileSpec) *ElasticRuntime {
+				tile, err := new(ElasticRuntimeBuilder).New(tileSpec)
+				Ω(err).ShouldNot(HaveOccurred())
+				tile.Close()
+				return tile.(struct {
+					*ElasticRuntime
+					*TempFile
+				}).ElasticRuntime
+			}
+
+			verifies := func(er *ElasticRuntime, target string) error {
+				resp, err := er.TLS.Client(target).Get(director.URL)
+				if err == nil {
+					resp.Body.Close()
+				}
+				return err
+			}
+
+			It("then it should verify the director with the CA ops manager issued its certificate with", func() {
+				er := build(tileregistry.TileSpec{ArchiveDirectory: target})
+				Ω(verifies(er, cfbackup.TLSTargetDirector)).Should(Succeed())
+				Ω(verifies(er, cfbackup.TLSTargetOpsManager)).ShouldNot(Succeed())
+			})
+
+			It("then it should verify the director against the system roots when the CA can not be had", func() {
+				directorCAErr = errors.New("ops manager is down")
+				er := build(tileregistry.TileSpec{ArchiveDirectory: target})
+				Ω(verifies(er, cfbackup.TLSTargetDirector)).ShouldNot(Succeed())
+			})
+
+			It("then it should return an error when the tileSpec's tls config can not be read", func() {
+				_, err := new(ElasticRuntimeBuilder).New(tileregistry.TileSpec{ArchiveDirectory: target, TLS: cfbackup.TLSConfig{Pins: []string{"not-a-pin"}}})
+				Ω(err).Should(MatchError(cfbackup.ErrTLSInvalidPin))
+			})
+		})
 	})
 })
@@ -1,17 +1,25 @@
 package elasticruntime_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path"
+	"strings"
+	"sync"
+	"time"
 
 	cfenv "github.com/cloudfoundry-community/go-cfenv"
 	"github.com/pivotalservices/cfbackup"
 	"github.com/pivotalservices/cfbackup/fakes"
 	. "github.com/pivotalservices/cfbackup/tiles/elasticruntime"
+	"github.com/pivotalservices/gtils/bosh"
+	ghttp "github.com/pivotalservices/gtils/http"
 	"github.com/pivotalservices/gtils/osutils"
 
 	. "github.com/onsi/ginkgo"
@@ -21,8 +29,49 @@ import (
 var (
 	ErrorImport = errors.New("failed import")
 	ErrorDump   = errors.New("failed dump")
+	ErrorVMs    = errors.New("failed to list vms")
+	ErrorStop   = errors.New("failed to stop job")
 )
 
+//stopFailingDirector - a director which fails to stop the cloud controller job with index failIndex
+type stopFailingDirector struct {
+	mutex     sync.Mutex
+	failIndex int
+	changes   []string
+}
+
+func (s *stopFailingDirector) GetDeploymentManifest(deploymentName string) (io.Reader, error) {
+	return strings.NewReader("manifest"), nil
+}
+
+func (s *stopFailingDirector) ChangeJobState(deploymentName, jobName, state string, index int, manifest io.Reader) (int, error) {
+	if state == cfbackup.CCJobStopped && index == s.failIndex {
+		return 0, ErrorStop
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.changes = append(s.changes, fmt.Sprintf("%s/%d %s", jobName, index, state))
+	return len(s.changes), nil
+}
+
+func (s *stopFailingDirector) RetrieveTaskStatus(int) (*bosh.Task, error) {
+	return &bosh.Task{State: "done"}, nil
+}
+
+type failingVMsGateway struct {
+	fakes.MockHTTPGateway
+	err error
+}
+
+func (s *failingVMsGateway) Get(entity ghttp.HttpRequestEntity) ghttp.RequestAdaptor {
+	if !strings.HasSuffix(entity.Url, "/vms") {
+		return s.MockHTTPGateway.Get(entity)
+	}
+	return func() (*http.Response, error) {
+		return nil, s.err
+	}
+}
+
 type DBInfoMock struct {
 	cfbackup.SystemInfo
 	ErrState   error
@@ -68,6 +117,35 @@ func (s mockDumper) Import(i io.Reader) (err error) {
 	return
 }
 
+type vmInfoMock struct {
+	cfbackup.SystemInfo
+	dumper *blockingDumper
+}
+
+func (s *vmInfoMock) GetPersistanceBackup() (cfbackup.PersistanceBackup, error) {
+	return s.dumper, nil
+}
+
+type blockingDumper struct {
+	component string
+	started   chan string
+	release   chan struct{}
+	err       error
+}
+
+func (s *blockingDumper) Dump(i io.Writer) error {
+	s.started <- s.component
+	<-s.release
+	i.Write([]byte("sometext"))
+	return s.err
+}
+
+func (s *blockingDumper) Import(i io.Reader) error {
+	s.started <- s.component
+	<-s.release
+	return s.err
+}
+
 var _ = Describe("ElasticRuntime", func() {
 	Describe("given: NewElasticRuntime", func() {
 		Context("when: calling ReadAllUserCredentials on the given elastic runtime", func() {
@@ -127,6 +205,293 @@ var _ = Describe("ElasticRuntime", func() {
 			})
 		})
 	})
+	Describe("given: a Verify method", func() {
+		var (
+			target string
+			er     *ElasticRuntime
+			report cfbackup.VerificationReport
+			err    error
+		)
+		writeArtifact := func(component string, contents []byte) {
+			ioutil.WriteFile(path.Join(target, fmt.Sprintf("%s.backup", component)), contents, 0600)
+		}
+
+		BeforeEach(func() {
+			target, _ = ioutil.TempDir("/tmp", "spec")
+			er = &ElasticRuntime{
+				BackupContext: cfbackup.NewBackupContext(target, cfenv.CurrentEnv(), ""),
+				PersistentSystems: []cfbackup.SystemDump{
+					&cfbackup.PgInfo{SystemInfo: cfbackup.SystemInfo{Component: "ccdb"}},
+					&cfbackup.MysqlInfo{SystemInfo: cfbackup.SystemInfo{Component: "mysql"}},
+					&cfbackup.NfsInfo{SystemInfo: cfbackup.SystemInfo{Component: "nfs_server"}},
+				},
+			}
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(target)
+		})
+
+		Context("when: every archive in the backup set is valid", func() {
+			BeforeEach(func() {
+				writeArtifact("ccdb", fakes.NewPgCustomDump(3))
+				writeArtifact("mysql", []byte(fakes.MysqlDumpContents))
+				writeArtifact("nfs_server", fakes.NewTarGz(map[string]string{"shared/cc-droplets/droplet": "droplet"}))
+				report, err = er.Verify()
+			})
+
+			It("then: it should pass every artifact", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(report.Passed()).Should(BeTrue())
+				Ω(report.Artifacts).Should(HaveLen(3))
+			})
+		})
+
+		Context("when: the mysql dump is incomplete", func() {
+			BeforeEach(func() {
+				writeArtifact("ccdb", fakes.NewPgCustomDump(3))
+				writeArtifact("mysql", []byte("-- MySQL dump 10.13\nCREATE TABLE"))
+				writeArtifact("nfs_server", fakes.NewTarGz(map[string]string{"shared/cc-droplets/droplet": "droplet"}))
+				report, err = er.Verify()
+			})
+
+			It("then: it should fail only the mysql artifact", func() {
+				Ω(err).Should(Equal(cfbackup.ErrVerificationFailed))
+				Ω(report.Failures()).Should(HaveLen(1))
+				Ω(report.Failures()[0].Artifact).Should(Equal("mysql.backup"))
+			})
+		})
+	})
+	Describe("given: a component selection", func() {
+		var (
+			target   string
+			er       *ElasticRuntime
+			systems  []cfbackup.SystemDump
+			toggleCC bool
+			err      error
+		)
+		BeforeEach(func() {
+			target, _ = ioutil.TempDir("/tmp", "spec")
+			er = &ElasticRuntime{
+				BackupContext: cfbackup.NewBackupContext(target, cfenv.CurrentEnv(), ""),
+				PersistentSystems: []cfbackup.SystemDump{
+					&cfbackup.SystemInfo{Component: "ccdb"},
+					&cfbackup.SystemInfo{Component: "uaadb"},
+					&cfbackup.SystemInfo{Component: "nfs_server"},
+				},
+			}
+		})
+		AfterEach(func() {
+			os.RemoveAll(target)
+		})
+		components := func() (names []string) {
+			for _, info := range systems {
+				names = append(names, info.Get(cfbackup.SDComponent))
+			}
+			return
+		}
+
+		Context("when: nothing is selected", func() {
+			It("then: it should keep every component and toggle the cloud controllers", func() {
+				systems, toggleCC, err = er.SelectedSystems()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(components()).Should(Equal([]string{"ccdb", "uaadb", "nfs_server"}))
+				Ω(toggleCC).Should(BeTrue())
+			})
+		})
+
+		Context("when: only uaadb is included", func() {
+			It("then: it should leave the cloud controllers running", func() {
+				er.IncludeComponents = []string{"uaadb"}
+				systems, toggleCC, err = er.SelectedSystems()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(components()).Should(Equal([]string{"uaadb"}))
+				Ω(toggleCC).Should(BeFalse())
+			})
+		})
+
+		Context("when: nfs_server is excluded", func() {
+			It("then: it should keep the remaining components in order and toggle the cloud controllers for ccdb", func() {
+				er.ExcludeComponents = []string{"nfs_server"}
+				systems, toggleCC, err = er.SelectedSystems()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(components()).Should(Equal([]string{"ccdb", "uaadb"}))
+				Ω(toggleCC).Should(BeTrue())
+			})
+		})
+
+		Context("when: a component the installation does not have is selected", func() {
+			It("then: it should fail the selection", func() {
+				er.IncludeComponents = []string{"consoledb"}
+				_, _, err = er.SelectedSystems()
+				Ω(err).Should(MatchError(fmt.Sprintf(ERUnknownComponentFormat, "consoledb")))
+			})
+
+			It("then: it should fail a restore before touching anything", func() {
+				er.ExcludeComponents = []string{"mysql"}
+				Ω(er.Restore()).Should(MatchError(fmt.Sprintf(ERUnknownComponentFormat, "mysql")))
+			})
+		})
+	})
+	Describe("given: a restore into a different foundation", func() {
+		var (
+			target string
+			er     *ElasticRuntime
+			source cfbackup.InstallationSettings
+		)
+		settingsFile := "../../fixtures/installation-settings-1-6.json"
+
+		BeforeEach(func() {
+			target, _ = ioutil.TempDir("/tmp", "spec")
+			er = NewElasticRuntime(settingsFile, target, "", "", cfbackup.NFSBackupTypeFull)
+			er.ReadAllUserCredentials()
+			source = cfbackup.NewConfigurationParser(settingsFile).InstallationSettings
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(target)
+		})
+
+		Context("when: the backup set keeps no installation settings", func() {
+			It("then: it should fail to read the source installation settings", func() {
+				_, err := er.SourceInstallationSettings()
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+
+		Context("when: only the ops manager backup keeps installation settings", func() {
+			It("then: it should read the source installation settings from the ops manager backup", func() {
+				settings, _ := ioutil.ReadFile(settingsFile)
+				os.MkdirAll(path.Join(target, "opsmanager"), 0700)
+				ioutil.WriteFile(path.Join(target, "opsmanager", "installation.json"), settings, 0600)
+				installationSettings, err := er.SourceInstallationSettings()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(installationSettings.Products).Should(Equal(source.Products))
+			})
+		})
+
+		Context("when: the roles are the same in both foundations", func() {
+			It("then: it should not remap anything", func() {
+				Ω(er.RoleRemaps(er.PersistentSystems, source)).Should(BeEmpty())
+			})
+		})
+
+		Context("when: a database role differs in the target foundation", func() {
+			It("then: it should map the source role to the target role", func() {
+				ccdb := er.SystemsInfo.SystemDumps[cfbackup.ERCc]
+				sourceRole := ccdb.Get(cfbackup.SDUser)
+				ccdb.Set(cfbackup.SDUser, "dr_ccdb")
+				remaps := er.RoleRemaps(er.PersistentSystems, source)
+				Ω(remaps).Should(Equal([]*cfbackup.RoleRemap{{Component: "ccdb", Source: sourceRole, Target: "dr_ccdb"}}))
+			})
+		})
+
+		Context("when: the archive of a remapped component is a custom format pg_dump", func() {
+			It("then: it should refuse to restore it with the source roles", func() {
+				remaps := []*cfbackup.RoleRemap{{Component: "ccdb", Source: "prod_ccdb", Target: "dr_ccdb"}}
+				ioutil.WriteFile(path.Join(target, "ccdb.backup"), []byte("PGDMP\x01\x0c\x00"), 0600)
+				err := er.CheckRemappable(remaps)
+				Ω(errors.Is(err, cfbackup.ErrRoleRemapCustomFormat)).Should(BeTrue())
+			})
+		})
+
+		Context("when: the archive of a remapped component is a plain sql dump", func() {
+			It("then: it should allow its roles to be remapped", func() {
+				remaps := []*cfbackup.RoleRemap{{Component: "ccdb", Source: "prod_ccdb", Target: "dr_ccdb"}}
+				ioutil.WriteFile(path.Join(target, "ccdb.backup"), []byte("ALTER TABLE public.apps OWNER TO prod_ccdb;\n"), 0600)
+				Ω(er.CheckRemappable(remaps)).Should(Succeed())
+			})
+		})
+	})
+	Describe("given: components on several vms", func() {
+		var (
+			target  string
+			er      ElasticRuntime
+			started chan string
+			release chan struct{}
+		)
+		newComponent := func(component, ip string, err error) cfbackup.SystemDump {
+			return &vmInfoMock{
+				SystemInfo: cfbackup.SystemInfo{Product: "cf", Component: component, Identifier: "credentials", Ip: ip, User: "user", Pass: "pass", VcapUser: "vcap", VcapPass: "pass"},
+				dumper:     &blockingDumper{component: component, started: started, release: release, err: err},
+			}
+		}
+
+		BeforeEach(func() {
+			target, _ = ioutil.TempDir("/tmp", "spec")
+			started = make(chan string, 3)
+			release = make(chan struct{})
+			er = ElasticRuntime{
+				BackupContext: cfbackup.NewBackupContext(target, cfenv.CurrentEnv(), ""),
+				Concurrency:   2,
+			}
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(target)
+		})
+
+		Context("Backup", func() {
+			It("should dump components on different vms at the same time", func() {
+				done := make(chan error)
+				go func() {
+					done <- er.RunDbAction([]cfbackup.SystemDump{
+						newComponent("ccdb", "10.0.0.1", nil),
+						newComponent("uaadb", "10.0.0.2", nil),
+					}, cfbackup.ExportArchive)
+				}()
+				Eventually(started).Should(Receive())
+				Eventually(started).Should(Receive())
+				close(release)
+				Eventually(done).Should(Receive(BeNil()))
+			})
+
+			It("should dump components on the same vm one after another", func() {
+				done := make(chan error)
+				go func() {
+					done <- er.RunDbAction([]cfbackup.SystemDump{
+						newComponent("ccdb", "10.0.0.1", nil),
+						newComponent("uaadb", "10.0.0.1", nil),
+					}, cfbackup.ExportArchive)
+				}()
+				Eventually(started).Should(Receive(Equal("ccdb")))
+				Consistently(started).ShouldNot(Receive())
+				close(release)
+				Eventually(started).Should(Receive(Equal("uaadb")))
+				Eventually(done).Should(Receive(BeNil()))
+			})
+
+			It("should dump every component and return the first failure in list order", func() {
+				close(release)
+				err := er.RunDbAction([]cfbackup.SystemDump{
+					newComponent("ccdb", "10.0.0.1", nil),
+					newComponent("uaadb", "10.0.0.2", ErrorDump),
+					newComponent("consoledb", "10.0.0.3", ErrorImport),
+				}, cfbackup.ExportArchive)
+				Ω(err).Should(Equal(ErrorDump))
+				Ω(started).Should(HaveLen(3))
+			})
+		})
+
+		Context("Restore", func() {
+			It("should import components in list order", func() {
+				close(release)
+				components := []cfbackup.SystemDump{
+					newComponent("ccdb", "10.0.0.1", nil),
+					newComponent("uaadb", "10.0.0.2", nil),
+					newComponent("consoledb", "10.0.0.3", nil),
+				}
+
+				for _, component := range []string{"ccdb", "uaadb", "consoledb"} {
+					ioutil.WriteFile(path.Join(target, fmt.Sprintf("%s.backup", component)), []byte("sometext"), 0600)
+				}
+				Ω(er.RunDbAction(components, cfbackup.ImportArchive)).Should(BeNil())
+				Ω(<-started).Should(Equal("ccdb"))
+				Ω(<-started).Should(Equal("uaadb"))
+				Ω(<-started).Should(Equal("consoledb"))
+			})
+		})
+	})
 	Describe("Elasic Runtime legacy (pre-1.6)", func() {
 		Describe("Elastic Runtime v1.4 file variant with getpassword IP index error", func() {
 			var installationSettingsFilePath = "../../fixtures/installation-settings-1-4-variant.json"
@@ -558,7 +923,7 @@ func testERWithVersionSpecificFile(installationSettingsFilePath string, boshName
 				target, _ = ioutil.TempDir("/tmp", "spec")
 				er = ElasticRuntime{
 					JSONFile:          installationSettingsFilePath,
-					HTTPGateway:       &fakes.MockHTTPGateway{},
+					HTTPGateway:       &fakes.MockHTTPGateway{State: "[]"},
 					BackupContext:     cfbackup.NewBackupContext(target, cfenv.CurrentEnv(), ""),
 					SystemsInfo:       info,
 					PersistentSystems: ps,
@@ -577,6 +942,173 @@ func testERWithVersionSpecificFile(installationSettingsFilePath string, boshName
 					})
 				})
 
+				Context("Backup run report", func() {
+					It("Should report and store the outcome of each component", func() {
+						Ω(er.Backup()).Should(BeNil())
+						report := er.RunReport()
+						Ω(report.Status).Should(Equal(cfbackup.RunStatusSucceeded))
+						Ω(report.Action).Should(Equal(cfbackup.HookActionBackup))
+						Ω(report.Components).Should(HaveLen(1))
+						Ω(report.Components[0].Name).Should(Equal("mysql"))
+						Ω(report.Components[0].Bytes).Should(Equal(int64(len("sometext"))))
+						Ω(report.Components[0].SHA256).ShouldNot(BeEmpty())
+						exists, _ := osutils.Exists(path.Join(target, "backup-report.json"))
+						Ω(exists).Should(BeTrue())
+					})
+				})
+
+				Context("Backup resume", func() {
+					var mysql *DBInfoMock
+
+					BeforeEach(func() {
+						mysql = ps[0].(*DBInfoMock)
+						Ω(er.Backup()).Should(BeNil())
+						checkpoint, err := cfbackup.ReadCheckpoint(er, target, cfbackup.CheckpointFileName)
+						Ω(err).ShouldNot(HaveOccurred())
+						Ω(checkpoint.Finished).Should(BeTrue())
+						checkpoint.Finished = false
+						checkpoint.Write(er, target, cfbackup.CheckpointFileName)
+						er.Resume = true
+						mysql.failDump = true
+					})
+
+					AfterEach(func() {
+						mysql.failDump = false
+					})
+
+					It("Should reuse the components an unfinished backup completed", func() {
+						Ω(er.Backup()).Should(BeNil())
+						Ω(er.RunReport().Components).Should(HaveLen(1))
+						Ω(er.RunReport().Components[0].Reused).Should(BeTrue())
+					})
+
+					It("Should dump a component again when its artifact changed", func() {
+						ioutil.WriteFile(path.Join(target, "mysql.backup"), []byte("othertext"), 0600)
+						Ω(er.Backup()).Should(Equal(ErrERDBBackup))
+					})
+				})
+
+				Context("Backup with component hooks", func() {
+					It("Should run the hooks around each component", func() {
+						var components []string
+						record := cfbackup.HookFunc(func(ctx context.Context, event cfbackup.HookEvent) error {
+							components = append(components, event.Point+" "+event.Component)
+							return nil
+						})
+						er.Hooks = &cfbackup.HookSet{PreComponent: []cfbackup.Hook{record}, PostComponent: []cfbackup.Hook{record}}
+						Ω(er.Backup()).Should(BeNil())
+						Ω(components).Should(Equal([]string{"pre-component mysql", "post-component mysql"}))
+					})
+
+					It("Should abort when a pre component hook fails", func() {
+						er.Hooks = &cfbackup.HookSet{PreComponent: []cfbackup.Hook{cfbackup.HookFunc(func(context.Context, cfbackup.HookEvent) error {
+							return errors.New("snapshot failed")
+						})}}
+						Ω(er.Backup()).Should(Equal(cfbackup.ErrPreHookFailed))
+					})
+				})
+
+				Context("Online backup", func() {
+					BeforeEach(func() {
+						er.Online = true
+					})
+
+					It("Should mark the backup set online and record its consistency caveats", func() {
+						Ω(er.Backup()).Should(BeNil())
+						Ω(er.RunReport().Mode).Should(Equal(cfbackup.RunModeOnline))
+						Ω(er.RunReport().CCTasks).Should(BeEmpty())
+						Ω(er.RunReport().Warnings).Should(Equal(EROnlineCaveats))
+						stored, err := cfbackup.ReadRunReport(er, target, "backup-report.json")
+						Ω(err).ShouldNot(HaveOccurred())
+						Ω(stored.Mode).Should(Equal(cfbackup.RunModeOnline))
+					})
+
+					It("Should warn the operator restoring it", func() {
+						Ω(er.Backup()).Should(BeNil())
+						er.Online = false
+						Ω(er.Restore()).Should(BeNil())
+						Ω(er.RunReport().Warnings).Should(ContainElement(EROnlineRestoreWarning))
+					})
+				})
+
+				Context("No cloud controller job matches", func() {
+					BeforeEach(func() {
+						er.HTTPGateway = &fakes.MockHTTPGateway{State: `[{"Job":"router","Index":0}]`}
+						er.CCJobPatterns = []string{"cloud_controller"}
+					})
+
+					It("Should warn and mark the backup online", func() {
+						Ω(er.Backup()).Should(BeNil())
+						Ω(er.RunReport().Mode).Should(Equal(cfbackup.RunModeOnline))
+						Ω(er.RunReport().CCTasks).Should(BeEmpty())
+						Ω(er.RunReport().Warnings).Should(ContainElement(fmt.Sprintf(ERNoCCJobsWarningFormat, "cloud_controller")))
+						Ω(er.RunReport().Warnings).Should(ContainElement(EROnlineCaveats[0]))
+					})
+
+					It("Should warn and mark the restore inconsistent", func() {
+						ioutil.WriteFile(path.Join(target, "mysql.backup"), []byte("sometext"), 0600)
+						Ω(er.Restore()).Should(BeNil())
+						Ω(er.RunReport().Mode).Should(Equal(cfbackup.RunModeInconsistent))
+						Ω(er.RunReport().Warnings).Should(ContainElement(fmt.Sprintf(ERNoCCJobsWarningFormat, "cloud_controller")))
+					})
+				})
+
+				Context("Stopping a cloud controller job fails", func() {
+					var (
+						director        *stopFailingDirector
+						taskPingFreq    = cfbackup.TaskPingFreq
+						newDirectorOrig = cfbackup.NewDirector
+					)
+
+					BeforeEach(func() {
+						director = &stopFailingDirector{failIndex: 1}
+						newDirectorOrig = cfbackup.NewDirector
+						cfbackup.NewDirector = func(ip, username, password string, port int, clients *cfbackup.TLSClients) bosh.Bosh {
+							return director
+						}
+						cfbackup.TaskPingFreq = time.Millisecond
+						er.HTTPGateway = &fakes.MockHTTPGateway{State: `[{"Job":"cloud_controller","Index":0},{"Job":"cloud_controller","Index":1}]`}
+						er.CCJobPatterns = []string{"cloud_controller"}
+						er.CCToggleConcurrency = 1
+					})
+
+					AfterEach(func() {
+						cfbackup.NewDirector = newDirectorOrig
+						cfbackup.TaskPingFreq = taskPingFreq
+					})
+
+					It("Should abort the run and restart the jobs which were stopped", func() {
+						Ω(er.Backup()).Should(Equal(ErrorStop))
+						Ω(er.RunReport().Components).Should(BeEmpty())
+						Ω(er.RunReport().Status).Should(Equal(cfbackup.RunStatusFailed))
+						Ω(director.changes).Should(Equal([]string{"cloud_controller/0 stopped", "cloud_controller/0 started"}))
+					})
+				})
+
+				Context("Listing the cloud controller vms fails", func() {
+					It("Should return the director error without running the db action", func() {
+						er.HTTPGateway = &failingVMsGateway{MockHTTPGateway: fakes.MockHTTPGateway{State: "[]"}, err: ErrorVMs}
+						Ω(er.Backup()).Should(Equal(ErrorVMs))
+						Ω(er.RunReport().Components).Should(BeEmpty())
+					})
+
+					It("Should return the error for a vm list which is not json", func() {
+						er.HTTPGateway = &fakes.MockHTTPGateway{State: "not json"}
+						err := er.Restore()
+						Ω(err).Should(BeAssignableToTypeOf(&json.SyntaxError{}))
+						Ω(er.RunReport().Components).Should(BeEmpty())
+					})
+				})
+
+				Context("BackupWithContext", func() {
+					It("Should return the context error when cancelled", func() {
+						ctx, cancel := context.WithCancel(context.Background())
+						cancel()
+						err := er.BackupWithContext(ctx)
+						Ω(err).Should(Equal(context.Canceled))
+					})
+				})
+
 				Context("Restore", func() {
 					var filename = fmt.Sprintf("%s.backup", "mysql")
 
@@ -655,6 +1187,16 @@ func testERWithVersionSpecificFile(installationSettingsFilePath string, boshName
 					})
 				})
 
+				Context("Backup run report", func() {
+					It("should report the underlying error", func() {
+						er.Backup()
+						report := er.RunReport()
+						Ω(report.Status).Should(Equal(cfbackup.RunStatusFailed))
+						Ω(report.Errors).Should(HaveLen(2))
+						Ω(report.Errors[0]).Should(Equal(ErrERDBBackupFailure))
+					})
+				})
+
 				Context("Restore", func() {
 					It("should return error if db backup fails", func() {
 						err := er.Backup()
@@ -12,13 +12,29 @@ type (
 	//ElasticRuntime contains information about a Pivotal Elastic Runtime deployment
 	ElasticRuntime struct {
 		cfbackup.BackupContext
-		JSONFile          string
-		SystemsInfo       cfbackup.SystemsInfo
-		PersistentSystems []cfbackup.SystemDump
-		HTTPGateway       ghttp.HttpGateway
-		InstallationName  string
-		SSHPrivateKey     string
-		NFS               string
+		JSONFile            string
+		SystemsInfo         cfbackup.SystemsInfo
+		PersistentSystems   []cfbackup.SystemDump
+		HTTPGateway         ghttp.HttpGateway
+		InstallationName    string
+		SSHPrivateKey       string
+		NFS                 string
+		PhaseTimeouts       cfbackup.PhaseTimeouts
+		Hooks               *cfbackup.HookSet
+		Concurrency         int
+		CCToggleConcurrency int
+		CCJobPatterns       []string
+		Online              bool
+		IncludeComponents   []string
+		ExcludeComponents   []string
+		RemapRoles          bool
+		Resume              bool
+		Lock                *cfbackup.LeaseLock
+		TLS                 *cfbackup.TLSClients
+		report              *cfbackup.RunReport
+		remaps              []*cfbackup.RoleRemap
+		checkpoint          *cfbackup.Checkpoint
+		ccSupervisor        *cfbackup.CCSupervisor
 	}
 
 	//ElasticRuntimeBuilder -- an object that can build an elastic runtime pre-initialized
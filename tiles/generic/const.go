@@ -0,0 +1,35 @@
+package generic
+
+import (
+	"errors"
+
+	"github.com/pivotalservices/cfbackup"
+)
+
+const (
+	//BackupFileFormat -- format of the default archive filename for a job
+	BackupFileFormat = "%s.backup"
+	//DefaultRemoteArchivePath -- where archives are uploaded to on the job vm during a restore
+	DefaultRemoteArchivePath = "/tmp/archive.backup"
+	//VMCredentialsIdentifier -- credentials identifier which selects the job's vm credentials
+	VMCredentialsIdentifier = "vm_credentials"
+	//InvalidSpecMsg -- error message for a spec missing required fields
+	InvalidSpecMsg = "invalid tile spec"
+	//ErrDBBackupFailureMsg -- error message for a failed dump or restore
+	ErrDBBackupFailureMsg = "failed to backup job persistence"
+	//ErrTileRegisteredMsg -- error message for a spec whose name another tile is already registered under
+	ErrTileRegisteredMsg = "a tile is already registered under this name"
+	//SpecDirEnvVarname -- environment variable naming the directory whose yaml specs are registered with the other tiles
+	SpecDirEnvVarname = "CFBACKUP_TILE_SPEC_DIR"
+)
+
+var (
+	//ArchiveFormats - the formats a job's archive can be verified as, an archive without a format is only checked to be readable
+	ArchiveFormats = []string{cfbackup.ArtifactFormatMysqlDump, cfbackup.ArtifactFormatPgDump, cfbackup.ArtifactFormatTarGz}
+	//CredentialFields - the cfbackup.SystemInfo fields a command template may only write into a command through quote
+	CredentialFields = []string{"User", "Pass", "VcapUser", "VcapPass"}
+	//ErrDBBackup - error for dump or restore failures
+	ErrDBBackup = errors.New(ErrDBBackupFailureMsg)
+	//ErrTileRegistered - error for a spec whose name another tile is already registered under
+	ErrTileRegistered = errors.New(ErrTileRegisteredMsg)
+)
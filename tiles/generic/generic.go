@@ -0,0 +1,226 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/cloudfoundry-community/go-cfenv"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/gtils/log"
+	"github.com/xchapter7x/lo"
+)
+
+//GetPersistanceBackup - builds the persistence backup for a job's systemdump
+var GetPersistanceBackup = func(dump cfbackup.SystemDump) (cfbackup.PersistanceBackup, error) {
+	return dump.GetPersistanceBackup()
+}
+
+//NewTile - initializes a tile which backs up the jobs described in the spec
+func NewTile(spec Spec, installationSettings cfbackup.InstallationInfo, target string, cryptKey string) *Tile {
+	return &Tile{
+		Spec:                 spec,
+		InstallationSettings: installationSettings,
+		BackupContext:        cfbackup.NewBackupContext(target, cfenv.CurrentEnv(), cryptKey),
+	}
+}
+
+// Backup dumps every job in the spec into the backup set
+func (s *Tile) Backup() error {
+	return s.BackupWithContext(context.Background())
+}
+
+// BackupWithContext dumps every job in the spec into the backup set, stopping when ctx is done
+func (s *Tile) BackupWithContext(ctx context.Context) error {
+	return s.runDbAction(ctx, cfbackup.ExportArchive)
+}
+
+// Restore imports every job in the spec from the backup set
+func (s *Tile) Restore() error {
+	return s.RestoreWithContext(context.Background())
+}
+
+// RestoreWithContext imports every job in the spec from the backup set, stopping when ctx is done
+func (s *Tile) RestoreWithContext(ctx context.Context) error {
+	return s.runDbAction(ctx, cfbackup.ImportArchive)
+}
+
+// Verify checks the archive of every instance of every job in the backup set can be read back, without restoring it
+func (s *Tile) Verify() (report cfbackup.VerificationReport, err error) {
+	for _, job := range s.Spec.Jobs {
+		check := cfbackup.GetArtifactCheckForFormat(job.Format)
+
+		for _, archive := range s.archiveNames(job) {
+			lo.G.Debug("Verifying %s", archive)
+			report.Record(archive, cfbackup.VerifyArtifact(s, check, s.archivePath(archive)))
+		}
+	}
+
+	if !report.Passed() {
+		lo.G.Error(fmt.Sprintf("%s backup set failed verification", s.Spec.Product), log.Data{"failures": report.Failures()})
+		err = cfbackup.ErrVerificationFailed
+	}
+	return
+}
+
+//SystemDumps - builds a systemdump for each instance of each job in the spec, with ips and credentials from the installation settings
+func (s *Tile) SystemDumps() (dumps []cfbackup.SystemDump, err error) {
+	var instances []jobInstance
+
+	if instances, err = s.jobInstances(); err == nil {
+		for _, instance := range instances {
+			dumps = append(dumps, instance.dump)
+		}
+	}
+	return
+}
+
+func (s *Tile) jobInstances() (instances []jobInstance, err error) {
+	for _, job := range s.Spec.Jobs {
+		var dumps []*cfbackup.RemoteCommandInfo
+
+		if dumps, err = s.newSystemDumps(job); err != nil {
+			return
+		}
+
+		for index, dump := range dumps {
+			instances = append(instances, jobInstance{
+				job:     job,
+				dump:    dump,
+				archive: job.InstanceArchiveName(index, len(dumps)),
+			})
+		}
+	}
+	return
+}
+
+func (s *Tile) archiveNames(job JobSpec) (archives []string) {
+	ips, err := s.InstallationSettings.FindIPsByProductAndJob(s.Spec.Product, job.Name)
+
+	if err != nil || len(ips) == 0 {
+		return []string{job.ArchiveName()}
+	}
+
+	for index := range ips {
+		archives = append(archives, job.InstanceArchiveName(index, len(ips)))
+	}
+	return
+}
+
+func (s *Tile) newSystemDumps(job JobSpec) (dumps []*cfbackup.RemoteCommandInfo, err error) {
+	var (
+		ips           []string
+		vmCredentials cfbackup.VMCredentials
+		propertyMap   map[string]string
+	)
+	identifier, remoteArchivePath := job.Credentials, job.RemoteArchivePath
+
+	if identifier == "" {
+		identifier = VMCredentialsIdentifier
+	}
+
+	if remoteArchivePath == "" {
+		remoteArchivePath = DefaultRemoteArchivePath
+	}
+
+	if ips, err = s.InstallationSettings.FindIPsByProductAndJob(s.Spec.Product, job.Name); err != nil {
+		return
+	}
+
+	if len(ips) == 0 {
+		err = fmt.Errorf("No IPs found for %s, %s", s.Spec.Product, job.Name)
+		return
+	}
+
+	if vmCredentials, err = s.InstallationSettings.FindVMCredentialsByProductAndJob(s.Spec.Product, job.Name); err != nil {
+		return
+	}
+	user, pass := vmCredentials.UserID, vmCredentials.Password
+
+	if identifier != VMCredentialsIdentifier {
+		if propertyMap, err = s.InstallationSettings.FindPropertyValues(s.Spec.Product, job.Name, identifier); err != nil {
+			return
+		}
+		user, pass = propertyMap["identity"], propertyMap["password"]
+	}
+
+	for _, ip := range ips {
+		dumps = append(dumps, &cfbackup.RemoteCommandInfo{
+			SystemInfo: cfbackup.SystemInfo{
+				Product:           s.Spec.Product,
+				Component:         job.Name,
+				Identifier:        identifier,
+				Ip:                ip,
+				User:              user,
+				Pass:              pass,
+				VcapUser:          vmCredentials.UserID,
+				VcapPass:          vmCredentials.Password,
+				SSHPrivateKey:     vmCredentials.SSLKey,
+				RemoteArchivePath: remoteArchivePath,
+			},
+			DumpCommand:    job.DumpCommand,
+			RestoreCommand: job.RestoreCommand,
+			Format:         job.Format,
+		})
+	}
+	return
+}
+
+func (s *Tile) runDbAction(ctx context.Context, action int) (err error) {
+	var instances []jobInstance
+
+	if instances, err = s.jobInstances(); err != nil {
+		lo.G.Error("unable to read job credentials from installation settings: ", err)
+		return
+	}
+
+	for _, instance := range instances {
+		if err = instance.dump.Error(); err == nil {
+			err = s.readWriterArchive(ctx, instance.dump, instance.job, instance.archive, action)
+		}
+
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			lo.G.Error(fmt.Sprintf("Error running db action for %s %s on %s", s.Spec.Product, instance.job.Name, instance.dump.Ip), err)
+			err = ErrDBBackup
+			break
+		}
+	}
+	return
+}
+
+func (s *Tile) readWriterArchive(ctx context.Context, info cfbackup.SystemDump, job JobSpec, archive string, action int) (err error) {
+	var pb cfbackup.PersistanceBackup
+
+	if pb, err = GetPersistanceBackup(info); err == nil {
+		switch action {
+		case cfbackup.ImportArchive:
+			lo.G.Debug("Restoring %s", archive)
+			var backupReader io.ReadCloser
+
+			if backupReader, err = s.Reader(s.archivePath(archive)); err == nil {
+				defer backupReader.Close()
+				err = cfbackup.ImportWithContext(ctx, pb, backupReader)
+				lo.G.Debug("Done restoring %s", job.Name)
+			}
+		case cfbackup.ExportArchive:
+			lo.G.Info("Exporting %s", archive)
+			var backupWriter io.WriteCloser
+
+			if backupWriter, err = s.Writer(s.archivePath(archive)); err == nil {
+				defer backupWriter.Close()
+				err = cfbackup.DumpWithContext(ctx, pb, backupWriter)
+				lo.G.Debug("Done backing up ", job.Name, err)
+			}
+		}
+	}
+	return
+}
+
+func (s *Tile) archivePath(archive string) string {
+	return path.Join(s.TargetDir, s.Spec.Product, archive)
+}
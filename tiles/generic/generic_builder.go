@@ -0,0 +1,73 @@
+package generic
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	"github.com/pivotalservices/cfbackup/tiles/opsmanager"
+	"github.com/xchapter7x/lo"
+)
+
+//New -- builds a generic tile for the generator's spec, using the installation settings from ops manager
+func (s *TileGenerator) New(tileSpec tileregistry.TileSpec) (genericTileCloser tileregistry.TileCloser, err error) {
+	var installationSettings io.Reader
+
+	if tileSpec, err = tileSpec.ResolveSecrets(); err != nil {
+		return
+	}
+
+	if installationSettings, err = opsmanager.GetInstallationSettings(tileSpec); err == nil {
+		config := cfbackup.NewConfigurationParserFromReader(installationSettings)
+		tile := NewTile(s.Spec, &config.InstallationSettings, tileSpec.ArchiveDirectory, tileSpec.CryptKey)
+		tileSpec.ApplyBackupContext(&tile.BackupContext)
+		genericTileCloser = struct {
+			*Tile
+			tileregistry.Closer
+		}{
+			tile,
+			new(tileregistry.DoNothingCloser),
+		}
+	}
+	return
+}
+
+//Register -- loads the spec files at the given paths and registers a tile for each under its name.
+//A spec never replaces a tile already registered under the same name, such as a built in one
+func Register(specPaths ...string) (err error) {
+	for _, specPath := range specPaths {
+		var spec Spec
+
+		if spec, err = LoadSpec(specPath); err != nil {
+			lo.G.Error("unable to load tile spec: ", specPath, err)
+			return
+		}
+
+		if _, registered := tileregistry.GetRegistry()[spec.TileName()]; registered {
+			return fmt.Errorf("%w: %s in %s", ErrTileRegistered, spec.TileName(), specPath)
+		}
+		lo.G.Debug("registering generic tile: ", spec.TileName())
+		tileregistry.Register(spec.TileName(), &TileGenerator{Spec: spec})
+	}
+	return
+}
+
+//RegisterDir -- registers a tile for every yaml spec file in the given directory
+func RegisterDir(specDir string) (err error) {
+	var files, specPaths []string
+
+	if _, err = os.Stat(specDir); err != nil {
+		return
+	}
+
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		if files, err = filepath.Glob(filepath.Join(specDir, pattern)); err != nil {
+			return
+		}
+		specPaths = append(specPaths, files...)
+	}
+	return Register(specPaths...)
+}
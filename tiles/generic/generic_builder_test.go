@@ -0,0 +1,107 @@
+package generic_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	. "github.com/pivotalservices/cfbackup/tiles/generic"
+	"github.com/pivotalservices/cfbackup/tiles/opsmanager"
+)
+
+var _ = Describe("TileGenerator", func() {
+	AfterEach(func() {
+		tileregistry.Repo = make(map[string]tileregistry.TileGenerator)
+	})
+
+	Describe("given a New() method", func() {
+		var originalGetInstallationSettings = opsmanager.GetInstallationSettings
+
+		AfterEach(func() {
+			opsmanager.GetInstallationSettings = originalGetInstallationSettings
+		})
+
+		Context("when ops manager returns the installation settings", func() {
+			It("then it should return a tile for the spec", func() {
+				opsmanager.GetInstallationSettings = func(tileregistry.TileSpec) (io.Reader, error) {
+					return os.Open("../../fixtures/installation-settings-1-6-aws.json")
+				}
+				spec, _ := LoadSpec("../../fixtures/generic-tile-mysql.yml")
+				tile, err := (&TileGenerator{Spec: spec}).New(tileregistry.TileSpec{})
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(tile).ShouldNot(BeNil())
+			})
+
+			It("then it should build a tile whose backup and restore stop with their context", func() {
+				opsmanager.GetInstallationSettings = func(tileregistry.TileSpec) (io.Reader, error) {
+					return os.Open("../../fixtures/installation-settings-1-6-aws.json")
+				}
+				spec, _ := LoadSpec("../../fixtures/generic-tile-mysql.yml")
+				tile, err := (&TileGenerator{Spec: spec}).New(tileregistry.TileSpec{})
+				Ω(err).ShouldNot(HaveOccurred())
+				_, isContextTile := tile.(tileregistry.ContextTile)
+				Ω(isContextTile).Should(BeTrue())
+			})
+		})
+
+		Context("when called with invalid tileSpec connection credentials", func() {
+			It("then it should return an error", func() {
+				_, err := new(TileGenerator).New(tileregistry.TileSpec{})
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("given a Register() method", func() {
+		Context("when called with a valid spec file", func() {
+			It("then it should register the tile under the spec's name", func() {
+				Ω(Register("../../fixtures/generic-tile-mysql.yml")).Should(Succeed())
+				Ω(tileregistry.GetRegistry()).Should(HaveKey("generic-mysql"))
+			})
+		})
+
+		Context("when a tile is already registered under the spec's name", func() {
+			It("then it should return an error and keep the registered tile", func() {
+				tileregistry.Register("generic-mysql", &TileGenerator{})
+				builtin := tileregistry.GetRegistry()["generic-mysql"]
+				Ω(Register("../../fixtures/generic-tile-mysql.yml")).Should(MatchError(ContainSubstring(ErrTileRegisteredMsg)))
+				Ω(tileregistry.GetRegistry()["generic-mysql"]).Should(BeIdenticalTo(builtin))
+			})
+		})
+
+		Context("when called with an invalid spec file", func() {
+			It("then it should return an error", func() {
+				Ω(Register("../../fixtures/installation-settings-1-6-aws.json")).ShouldNot(Succeed())
+			})
+		})
+	})
+
+	Describe("given a RegisterDir() method", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir, _ = ioutil.TempDir("", "specs")
+			spec, _ := ioutil.ReadFile("../../fixtures/generic-tile-mysql.yml")
+			ioutil.WriteFile(path.Join(dir, "generic-mysql.yml"), spec, 0644)
+			ioutil.WriteFile(path.Join(dir, "README.md"), []byte("not a spec"), 0644)
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("then it should register every yaml spec in the directory", func() {
+			Ω(RegisterDir(dir)).Should(Succeed())
+			Ω(tileregistry.GetRegistry()).Should(HaveLen(1))
+			Ω(tileregistry.GetRegistry()).Should(HaveKey("generic-mysql"))
+		})
+
+		It("then it should return an error for a missing directory", func() {
+			Ω(RegisterDir(path.Join(dir, "missing"))).ShouldNot(Succeed())
+		})
+	})
+})
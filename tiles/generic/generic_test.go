@@ -0,0 +1,185 @@
+package generic_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/fakes"
+	. "github.com/pivotalservices/cfbackup/tiles/generic"
+)
+
+type mockDumper struct {
+	dumped   string
+	imported string
+	err      error
+}
+
+func (s *mockDumper) Dump(dest io.Writer) error {
+	io.WriteString(dest, s.dumped)
+	return s.err
+}
+
+func (s *mockDumper) Import(src io.Reader) error {
+	contents, _ := ioutil.ReadAll(src)
+	s.imported = string(contents)
+	return s.err
+}
+
+//multiInstanceSettings - installation settings in which the job runs on more than one vm
+type multiInstanceSettings struct {
+	*cfbackup.InstallationSettings
+	ips []string
+}
+
+func (s *multiInstanceSettings) FindIPsByProductAndJob(productName, jobName string) ([]string, error) {
+	return s.ips, nil
+}
+
+var _ = Describe("Tile", func() {
+	var (
+		tile                         *Tile
+		dir                          string
+		dumper                       *mockDumper
+		systemDumps                  []cfbackup.SystemDump
+		originalGetPersistanceBackup = GetPersistanceBackup
+	)
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "generic")
+		spec, _ := LoadSpec("../../fixtures/generic-tile-mysql.yml")
+		config := cfbackup.NewConfigurationParser("../../fixtures/installation-settings-1-6-aws.json")
+		tile = NewTile(spec, &config.InstallationSettings, dir, "")
+		dumper = &mockDumper{dumped: fakes.MysqlDumpContents}
+		systemDumps = nil
+		GetPersistanceBackup = func(dump cfbackup.SystemDump) (cfbackup.PersistanceBackup, error) {
+			systemDumps = append(systemDumps, dump)
+			return dumper, nil
+		}
+	})
+
+	AfterEach(func() {
+		GetPersistanceBackup = originalGetPersistanceBackup
+		os.RemoveAll(dir)
+	})
+
+	Describe("given a SystemDumps() method", func() {
+		Context("when the product and job exist in the installation settings", func() {
+			It("then it should build a remote command systemdump with the job's ip and credentials", func() {
+				dumps, err := tile.SystemDumps()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(dumps).Should(HaveLen(1))
+				Ω(dumps[0].Error()).ShouldNot(HaveOccurred())
+				Ω(dumps[0].Get(cfbackup.SDIP)).ShouldNot(BeEmpty())
+				Ω(dumps[0].Get(cfbackup.SDUser)).Should(Equal("root"))
+				Ω(dumps[0].Get(cfbackup.SDVcapUser)).Should(Equal("vcap"))
+				Ω(dumps[0]).Should(BeAssignableToTypeOf(new(cfbackup.RemoteCommandInfo)))
+			})
+		})
+
+		Context("when the product is not in the installation settings", func() {
+			It("then it should return an error", func() {
+				tile.Spec.Product = "p-unknown"
+				_, err := tile.SystemDumps()
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("given a Backup() method", func() {
+		Context("when the dump succeeds", func() {
+			It("then it should write the job archive under the product directory", func() {
+				Ω(tile.Backup()).Should(Succeed())
+				contents, err := ioutil.ReadFile(path.Join(dir, "p-mysql", "mysql.backup"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(string(contents)).Should(Equal(fakes.MysqlDumpContents))
+				Ω(systemDumps).Should(HaveLen(1))
+			})
+		})
+
+		Context("when the dump fails", func() {
+			It("then it should return an error", func() {
+				dumper.err = io.ErrUnexpectedEOF
+				Ω(tile.Backup()).Should(Equal(ErrDBBackup))
+			})
+		})
+	})
+
+	Describe("given a job running on several instances", func() {
+		BeforeEach(func() {
+			tile.InstallationSettings = &multiInstanceSettings{
+				InstallationSettings: tile.InstallationSettings.(*cfbackup.InstallationSettings),
+				ips:                  []string{"10.0.0.1", "10.0.0.2"},
+			}
+		})
+
+		It("then it should back up every instance into its own archive", func() {
+			Ω(tile.Backup()).Should(Succeed())
+			Ω(systemDumps).Should(HaveLen(2))
+			Ω(systemDumps[0].Get(cfbackup.SDIP)).Should(Equal("10.0.0.1"))
+			Ω(systemDumps[1].Get(cfbackup.SDIP)).Should(Equal("10.0.0.2"))
+
+			for _, archive := range []string{"mysql-0.backup", "mysql-1.backup"} {
+				contents, err := ioutil.ReadFile(path.Join(dir, "p-mysql", archive))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(string(contents)).Should(Equal(fakes.MysqlDumpContents))
+			}
+		})
+
+		It("then it should verify every instance's archive", func() {
+			tile.Backup()
+			os.Remove(path.Join(dir, "p-mysql", "mysql-1.backup"))
+			report, err := tile.Verify()
+			Ω(err).Should(Equal(cfbackup.ErrVerificationFailed))
+			Ω(report.Failures()).Should(HaveLen(1))
+		})
+
+		It("then it should fail the restore when an instance has no archive", func() {
+			os.MkdirAll(path.Join(dir, "p-mysql"), 0755)
+			ioutil.WriteFile(path.Join(dir, "p-mysql", "mysql-0.backup"), []byte("restore me"), 0644)
+			Ω(tile.Restore()).Should(Equal(ErrDBBackup))
+		})
+	})
+
+	Describe("given a Restore() method", func() {
+		Context("when the job archive exists", func() {
+			It("then it should import the archive", func() {
+				os.MkdirAll(path.Join(dir, "p-mysql"), 0755)
+				ioutil.WriteFile(path.Join(dir, "p-mysql", "mysql.backup"), []byte("restore me"), 0644)
+				Ω(tile.Restore()).Should(Succeed())
+				Ω(dumper.imported).Should(Equal("restore me"))
+			})
+		})
+
+		Context("when the job archive is missing", func() {
+			It("then it should return an error", func() {
+				Ω(tile.Restore()).Should(Equal(ErrDBBackup))
+			})
+		})
+	})
+
+	Describe("given a Verify() method", func() {
+		Context("when the backup set holds a complete dump", func() {
+			It("then it should pass", func() {
+				tile.Backup()
+				report, err := tile.Verify()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(report.Passed()).Should(BeTrue())
+			})
+		})
+
+		Context("when the dump is truncated", func() {
+			It("then it should fail verification", func() {
+				dumper.dumped = "-- MySQL dump"
+				tile.Backup()
+				report, err := tile.Verify()
+				Ω(err).Should(Equal(cfbackup.ErrVerificationFailed))
+				Ω(report.Failures()).Should(HaveLen(1))
+			})
+		})
+	})
+})
@@ -0,0 +1,164 @@
+package generic
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/pivotalservices/cfbackup"
+	"gopkg.in/yaml.v1"
+)
+
+//LoadSpec - reads a Spec from the yaml file at the given path
+func LoadSpec(specPath string) (spec Spec, err error) {
+	var specFile *os.File
+
+	if specFile, err = os.Open(specPath); err == nil {
+		defer specFile.Close()
+		spec, err = ParseSpec(specFile)
+	}
+	return
+}
+
+//ParseSpec - reads a Spec from the given yaml and validates it
+func ParseSpec(specYaml io.Reader) (spec Spec, err error) {
+	var contents []byte
+
+	if contents, err = ioutil.ReadAll(specYaml); err == nil {
+		if err = yaml.Unmarshal(contents, &spec); err == nil {
+			err = spec.Validate()
+		}
+	}
+	return
+}
+
+//Validate - checks the spec has everything required to run a backup and restore
+func (s Spec) Validate() error {
+	if s.Product == "" {
+		return fmt.Errorf("%s: product is required", InvalidSpecMsg)
+	}
+
+	if len(s.Jobs) == 0 {
+		return fmt.Errorf("%s: %s has no jobs", InvalidSpecMsg, s.Product)
+	}
+
+	for _, job := range s.Jobs {
+		if job.Name == "" || job.DumpCommand == "" || job.RestoreCommand == "" {
+			return fmt.Errorf("%s: %s jobs require a name, dump_command and restore_command", InvalidSpecMsg, s.Product)
+		}
+
+		if job.Format != "" && !knownFormat(job.Format) {
+			return fmt.Errorf("%s: %s job %s has an unknown format %q, expected one of %s", InvalidSpecMsg, s.Product, job.Name, job.Format, strings.Join(ArchiveFormats, ", "))
+		}
+
+		for _, command := range []string{job.DumpCommand, job.RestoreCommand} {
+			if err := validateCommand(command); err != nil {
+				return fmt.Errorf("%s: %s job %s: %s", InvalidSpecMsg, s.Product, job.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+//validateCommand - checks the command template parses and passes every credential through quote,
+//so a credential can not break out of the command it is written into
+func validateCommand(command string) (err error) {
+	var tmpl *template.Template
+
+	if tmpl, err = template.New("command").Funcs(cfbackup.CommandFuncs).Parse(command); err != nil {
+		return
+	}
+
+	if fields := unquotedCredentials(tmpl.Tree.Root); len(fields) > 0 {
+		err = fmt.Errorf("%s written into the command unquoted, use eg. {{quote .%s}}", strings.Join(fields, ", "), fields[0])
+	}
+	return
+}
+
+func unquotedCredentials(node parse.Node) (fields []string) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node != nil {
+			for _, child := range node.Nodes {
+				fields = append(fields, unquotedCredentials(child)...)
+			}
+		}
+	case *parse.IfNode:
+		fields = append(unquotedCredentials(node.List), unquotedCredentials(node.ElseList)...)
+	case *parse.RangeNode:
+		fields = append(unquotedCredentials(node.List), unquotedCredentials(node.ElseList)...)
+	case *parse.WithNode:
+		fields = append(unquotedCredentials(node.List), unquotedCredentials(node.ElseList)...)
+	case *parse.ActionNode:
+		if quoted(node.Pipe) {
+			return
+		}
+
+		for _, cmd := range node.Pipe.Cmds {
+			for _, arg := range cmd.Args {
+				if field, ok := arg.(*parse.FieldNode); ok && isCredential(field.Ident) {
+					fields = append(fields, strings.Join(field.Ident, "."))
+				}
+			}
+		}
+	}
+	return
+}
+
+//quoted - whether the pipeline's output is the output of quote
+func quoted(pipe *parse.PipeNode) bool {
+	last := pipe.Cmds[len(pipe.Cmds)-1]
+	identifier, ok := last.Args[0].(*parse.IdentifierNode)
+	return ok && identifier.Ident == "quote"
+}
+
+func isCredential(ident []string) bool {
+	for _, credential := range CredentialFields {
+		if len(ident) == 1 && ident[0] == credential {
+			return true
+		}
+	}
+	return false
+}
+
+//TileName - the name the tile is registered under
+func (s Spec) TileName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Product
+}
+
+func knownFormat(format string) bool {
+	for _, known := range ArchiveFormats {
+		if format == known {
+			return true
+		}
+	}
+	return false
+}
+
+//ArchiveName - the filename of the job's archive in the backup set
+func (s JobSpec) ArchiveName() string {
+	if s.Archive != "" {
+		return s.Archive
+	}
+	return fmt.Sprintf(BackupFileFormat, s.Name)
+}
+
+//InstanceArchiveName - the filename of the archive of the job's instance at index, out of instances. A job with a
+//single instance keeps its ArchiveName, the others carry the index before the extension, eg. mysql-1.backup
+func (s JobSpec) InstanceArchiveName(index, instances int) string {
+	archive := s.ArchiveName()
+
+	if instances <= 1 {
+		return archive
+	}
+	extension := path.Ext(archive)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(archive, extension), index, extension)
+}
@@ -0,0 +1,113 @@
+package generic_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotalservices/cfbackup/tiles/generic"
+)
+
+var _ = Describe("Spec", func() {
+	Describe("given a LoadSpec() method", func() {
+		Context("when called with a valid spec file", func() {
+			It("then it should parse the product and its jobs", func() {
+				spec, err := LoadSpec("../../fixtures/generic-tile-mysql.yml")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(spec.Product).Should(Equal("p-mysql"))
+				Ω(spec.TileName()).Should(Equal("generic-mysql"))
+				Ω(spec.Jobs).Should(HaveLen(1))
+				Ω(spec.Jobs[0].Name).Should(Equal("mysql"))
+				Ω(spec.Jobs[0].Credentials).Should(Equal("mysql_admin_password"))
+				Ω(spec.Jobs[0].RemoteArchivePath).Should(Equal("/var/vcap/store/mysql/archive.backup"))
+				Ω(spec.Jobs[0].DumpCommand).Should(ContainSubstring("--all-databases"))
+			})
+		})
+
+		Context("when called with a file which does not exist", func() {
+			It("then it should return an error", func() {
+				_, err := LoadSpec("../../fixtures/does-not-exist.yml")
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("given a ParseSpec() method", func() {
+		Context("when the spec has no product", func() {
+			It("then it should return an error", func() {
+				_, err := ParseSpec(strings.NewReader("jobs:\n- name: mysql\n  dump_command: dump\n  restore_command: restore\n"))
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+
+		Context("when the spec has no jobs", func() {
+			It("then it should return an error", func() {
+				_, err := ParseSpec(strings.NewReader("product: p-mysql\n"))
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+
+		Context("when a job has no restore command", func() {
+			It("then it should return an error", func() {
+				_, err := ParseSpec(strings.NewReader("product: p-mysql\njobs:\n- name: mysql\n  dump_command: dump\n"))
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+
+		Context("when a job has an unknown format", func() {
+			It("then it should name the format instead of falling back to a readability check", func() {
+				_, err := ParseSpec(strings.NewReader("product: p-mysql\njobs:\n- name: mysql\n  format: mysqldumb\n  dump_command: dump\n  restore_command: restore\n"))
+				Ω(err).Should(MatchError(ContainSubstring(`unknown format "mysqldumb"`)))
+			})
+		})
+	})
+
+	Describe("given a ParseSpec() method with command templates", func() {
+		parse := func(dumpCommand string) error {
+			_, err := ParseSpec(strings.NewReader("product: p-mysql\njobs:\n- name: mysql\n  dump_command: " + dumpCommand + "\n  restore_command: restore\n"))
+			return err
+		}
+
+		Context("when a command writes a credential into it unquoted", func() {
+			It("then it should return an error naming the credential", func() {
+				Ω(parse("'dump -p{{.Pass}}'")).Should(MatchError(ContainSubstring("Pass written into the command unquoted")))
+				Ω(parse("'dump -u {{.User | printf \"%s\"}}'")).Should(MatchError(ContainSubstring("User written into the command unquoted")))
+				Ω(parse("'dump {{if .Ip}}-p {{.VcapPass}}{{end}}'")).Should(MatchError(ContainSubstring("VcapPass written into the command unquoted")))
+			})
+		})
+
+		Context("when a command quotes every credential", func() {
+			It("then it should accept it", func() {
+				Ω(parse("'MYSQL_PWD={{quote .Pass}} dump -u {{.User | quote}} -h {{.Ip}}'")).Should(Succeed())
+			})
+		})
+
+		Context("when a command is not a valid template", func() {
+			It("then it should return an error", func() {
+				Ω(parse("'dump {{.Pass'")).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("given a Spec TileName() method", func() {
+		It("then it should default to the product", func() {
+			Ω(Spec{Product: "p-mysql"}.TileName()).Should(Equal("p-mysql"))
+			Ω(Spec{Name: "generic-mysql", Product: "p-mysql"}.TileName()).Should(Equal("generic-mysql"))
+		})
+	})
+
+	Describe("given a JobSpec InstanceArchiveName() method", func() {
+		It("then it should keep the archive name of a single instance and index the others", func() {
+			Ω(JobSpec{Name: "mysql"}.InstanceArchiveName(0, 1)).Should(Equal("mysql.backup"))
+			Ω(JobSpec{Name: "mysql"}.InstanceArchiveName(1, 3)).Should(Equal("mysql-1.backup"))
+			Ω(JobSpec{Name: "mysql", Archive: "dump.sql"}.InstanceArchiveName(0, 2)).Should(Equal("dump-0.sql"))
+		})
+	})
+
+	Describe("given a JobSpec ArchiveName() method", func() {
+		It("then it should default to the job name", func() {
+			Ω(JobSpec{Name: "mysql"}.ArchiveName()).Should(Equal("mysql.backup"))
+			Ω(JobSpec{Name: "mysql", Archive: "dump.sql"}.ArchiveName()).Should(Equal("dump.sql"))
+		})
+	})
+})
@@ -0,0 +1,44 @@
+package generic
+
+import "github.com/pivotalservices/cfbackup"
+
+type (
+	//Spec - a declarative description of how to back up a product deployed through ops manager.
+	//The tile is registered under Name, or Product when it has none
+	Spec struct {
+		Name    string    `yaml:"name"`
+		Product string    `yaml:"product"`
+		Jobs    []JobSpec `yaml:"jobs"`
+	}
+
+	//JobSpec - how to dump and restore the persistence of a single job in the product.
+	//Commands are templates rendered against the job's cfbackup.SystemInfo, eg. {{.User}}, {{.Pass}}, {{.Ip}} and {{.RemoteArchivePath}}.
+	//The credentials have to be shell quoted, eg. {{quote .Pass}}
+	JobSpec struct {
+		Name              string `yaml:"name"`
+		Credentials       string `yaml:"credentials"`
+		Archive           string `yaml:"archive"`
+		Format            string `yaml:"format"`
+		RemoteArchivePath string `yaml:"remote_archive_path"`
+		DumpCommand       string `yaml:"dump_command"`
+		RestoreCommand    string `yaml:"restore_command"`
+	}
+
+	//Tile - a tile whose backup and restore are driven by a Spec
+	Tile struct {
+		cfbackup.BackupContext
+		Spec                 Spec
+		InstallationSettings cfbackup.InstallationInfo
+	}
+
+	jobInstance struct {
+		job     JobSpec
+		dump    *cfbackup.RemoteCommandInfo
+		archive string
+	}
+
+	//TileGenerator -- an object that can build a generic tile for its spec
+	TileGenerator struct {
+		Spec Spec
+	}
+)
@@ -1,12 +1,37 @@
 package cfbackup
 
 import (
+	"os"
+
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	"github.com/pivotalservices/cfbackup/tiles/boshdirector"
 	"github.com/pivotalservices/cfbackup/tiles/elasticruntime"
+	"github.com/pivotalservices/cfbackup/tiles/generic"
 	"github.com/pivotalservices/cfbackup/tiles/opsmanager"
-	"github.com/pivotalservices/cfbackup/tileregistry"
+	"github.com/pivotalservices/cfbackup/tiles/plugin"
+	"github.com/pivotalservices/cfbackup/tiles/pmysql"
+	"github.com/pivotalservices/cfbackup/tiles/prabbitmq"
+	"github.com/pivotalservices/cfbackup/tiles/predis"
+	"github.com/xchapter7x/lo"
 )
 
 func init() {
 	tileregistry.Register("ops-manager", new(opsmanager.OpsManagerBuilder))
 	tileregistry.Register("elastic-runtime", new(elasticruntime.ElasticRuntimeBuilder))
+	tileregistry.Register("p-mysql", new(pmysql.PMysqlBuilder))
+	tileregistry.Register("p-rabbitmq", new(prabbitmq.PRabbitMQBuilder))
+	tileregistry.Register("p-redis", new(predis.PRedisBuilder))
+	tileregistry.Register("bosh-director", new(boshdirector.BoshDirectorBuilder))
+
+	if pluginDir := os.Getenv(plugin.PluginDirEnvVarname); pluginDir != "" {
+		if err := plugin.Register(pluginDir); err != nil {
+			lo.G.Error("could not register the plugins in ", pluginDir, ": ", err)
+		}
+	}
+
+	if specDir := os.Getenv(generic.SpecDirEnvVarname); specDir != "" {
+		if err := generic.RegisterDir(specDir); err != nil {
+			lo.G.Error("could not register the generic tile specs in ", specDir, ": ", err)
+		}
+	}
 }
@@ -1,10 +1,13 @@
 package opsmanager
 
+import "errors"
+
 //OpsManager constants
 const (
 	OpsMgrInstallationSettingsFilename    string = "installation.json"
 	OpsMgrInstallationAssetsFileName      string = "installation.zip"
 	OpsMgrInstallationAssetsPostFieldName string = "installation[file]"
+	OpsMgrInstallationYmlFileName         string = "installation.yml"
 	OpsMgrDeploymentsFileName             string = "deployments.tar.gz"
 	OpsMgrEncryptionKeyFileName           string = "cc_db_encryption_key.txt"
 	OpsMgrBackupDir                       string = "opsmanager"
@@ -12,5 +15,11 @@ const (
 	OpsMgrDefaultSSHPort                  int    = 22
 	OpsMgrInstallationSettingsURL         string = "https://%s/api/installation_settings"
 	OpsMgrInstallationAssetsURL           string = "https://%s/api/installation_asset_collection"
+	OpsMgrCertificateAuthoritiesURL       string = "https://%s/api/v0/certificate_authorities"
 	OpsMgrDeploymentsFile                 string = "/var/tempest/workspaces/default/deployments/bosh-deployments.yml"
 )
+
+var (
+	//ErrNoActiveCA - error for an ops manager which has no active certificate authority
+	ErrNoActiveCA = errors.New("ops manager has no active certificate authority")
+)
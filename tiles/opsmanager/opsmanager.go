@@ -3,23 +3,28 @@ package opsmanager
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	urllib "net/url"
+	"os"
 	"path"
+	"strings"
 
 	"github.com/cloudfoundry-community/go-cfenv"
 	"github.com/pivotalservices/cfbackup"
 	"github.com/pivotalservices/gtils/command"
 	ghttp "github.com/pivotalservices/gtils/http"
 	"github.com/pivotalservices/gtils/log"
-	"github.com/pivotalservices/gtils/uaa"
 	"github.com/xchapter7x/lo"
 )
 
+var noDeadline = context.Background()
+
 // NewOpsManager initializes an OpsManager instance
 var NewOpsManager = func(opsManagerHostname,
 	adminUsername,
@@ -34,16 +39,8 @@ var NewOpsManager = func(opsManagerHostname,
 	cryptKey string) (context *OpsManager, err error) {
 
 	backupContext := cfbackup.NewBackupContext(target, cfenv.CurrentEnv(), cryptKey)
-	settingsHTTPRequestor := ghttp.NewHttpGateway()
-	settingsMultiHTTPRequestor := httpUploader(cfbackup.GetUploader(backupContext))
-	assetsHTTPRequestor := ghttp.NewHttpGateway()
-	assetsMultiHTTPRequestor := httpUploader(cfbackup.GetUploader(backupContext))
 
 	context = &OpsManager{
-		SettingsUploader:    settingsMultiHTTPRequestor,
-		AssetsUploader:      assetsMultiHTTPRequestor,
-		SettingsRequestor:   settingsHTTPRequestor,
-		AssetsRequestor:     assetsHTTPRequestor,
 		DeploymentDir:       path.Join(target, OpsMgrBackupDir, OpsMgrDeploymentsDir),
 		Hostname:            opsManagerHostname,
 		Username:            adminUsername,
@@ -60,10 +57,21 @@ var NewOpsManager = func(opsManagerHostname,
 		SSHPort:             OpsMgrDefaultSSHPort,
 		ClearBoshManifest:   false,
 	}
+	context.SetTLSClients(nil)
 	err = context.createExecuter()
 	return
 }
 
+//SetTLSClients - sets the clients ops manager and its uaa are verified with and rebuilds the requestors and uploaders
+//associated with the opsmanager, nil clients verify them against the system roots
+func (context *OpsManager) SetTLSClients(clients *cfbackup.TLSClients) {
+	context.TLS = clients
+	context.SettingsRequestor = cfbackup.NewTLSGateway(cfbackup.TLSTargetOpsManager, clients)
+	context.SettingsUploader = httpUploader(cfbackup.GetUploader(context.BackupContext, clients))
+	context.AssetsRequestor = cfbackup.NewTLSGateway(cfbackup.TLSTargetOpsManager, clients)
+	context.AssetsUploader = httpUploader(cfbackup.GetUploader(context.BackupContext, clients))
+}
+
 //SetSSHPrivateKey - sets the private key in the ops manager object and rebuilds the remote executer associated with the opsmanager
 func (context *OpsManager) SetSSHPrivateKey(key string) {
 	lo.G.Debug("Setting SSHKey")
@@ -89,44 +97,83 @@ func (context *OpsManager) GetInstallationSettings() (settings io.Reader, err er
 	url := fmt.Sprintf(OpsMgrInstallationSettingsURL, context.Hostname)
 	lo.G.Debug(fmt.Sprintf("Exporting url '%s'", url))
 
-	if err = context.saveHTTPResponse(url, bytesBuffer); err == nil {
+	if err = context.saveHTTPResponse(noDeadline, url, bytesBuffer); err == nil {
 		settings = bytesBuffer
 	}
 	return
 }
 
+// GetDirectorCA retrieves the pem of the active certificate authorities ops manager
+// issued the director's certificate with
+func (context *OpsManager) GetDirectorCA() (ca string, err error) {
+	var (
+		bytesBuffer = new(bytes.Buffer)
+		authorities certificateAuthorities
+	)
+	url := fmt.Sprintf(OpsMgrCertificateAuthoritiesURL, context.Hostname)
+	lo.G.Debug(fmt.Sprintf("Reading certificate authorities from '%s'", url))
+
+	if err = context.saveHTTPResponse(noDeadline, url, bytesBuffer); err != nil {
+		return
+	}
+
+	if err = json.NewDecoder(bytesBuffer).Decode(&authorities); err != nil {
+		return
+	}
+
+	for _, authority := range authorities.CertificateAuthorities {
+		if authority.Active {
+			ca += strings.TrimSpace(authority.CertPEM) + "\n"
+		}
+	}
+
+	if ca == "" {
+		err = ErrNoActiveCA
+	}
+	return
+}
+
 //~ Backup Operations
 
 // Backup performs a backup of a Pivotal Ops Manager instance
 func (context *OpsManager) Backup() (err error) {
-	if err = context.saveDeployments(); err == nil {
-		err = context.saveInstallation()
+	return context.BackupWithContext(noDeadline)
+}
+
+// BackupWithContext performs a backup of a Pivotal Ops Manager instance which stops when ctx is done
+func (context *OpsManager) BackupWithContext(ctx context.Context) (err error) {
+	return context.Lock.Run(ctx, context.backup)
+}
+
+func (context *OpsManager) backup(ctx context.Context) (err error) {
+	if err = context.saveDeployments(ctx); err == nil {
+		err = context.saveInstallation(ctx)
 	}
 	return
 }
 
-func (context *OpsManager) saveDeployments() (err error) {
+func (context *OpsManager) saveDeployments(ctx context.Context) (err error) {
 	var backupWriter io.WriteCloser
 	if backupWriter, err = context.Writer(context.TargetDir, context.OpsmanagerBackupDir, OpsMgrDeploymentsFileName); err == nil {
 		defer backupWriter.Close()
 		command := "cd /var/tempest/workspaces/default && tar cz deployments"
-		err = context.Executer.Execute(backupWriter, command)
+		err = cfbackup.ExecuteWithContext(ctx, context.Executer, backupWriter, command)
 	}
 	return
 }
 
-func (context *OpsManager) saveInstallation() error {
-	return context.saveInstallationSettingsAndAssets()
+func (context *OpsManager) saveInstallation(ctx context.Context) error {
+	return context.saveInstallationSettingsAndAssets(ctx)
 }
 
-func (context *OpsManager) saveInstallationSettingsAndAssets() (err error) {
-	if err = context.exportFile(OpsMgrInstallationSettingsURL, OpsMgrInstallationSettingsFilename); err == nil {
-		err = context.exportFile(OpsMgrInstallationAssetsURL, OpsMgrInstallationAssetsFileName)
+func (context *OpsManager) saveInstallationSettingsAndAssets(ctx context.Context) (err error) {
+	if err = context.exportFile(ctx, OpsMgrInstallationSettingsURL, OpsMgrInstallationSettingsFilename); err == nil {
+		err = context.exportFile(ctx, OpsMgrInstallationAssetsURL, OpsMgrInstallationAssetsFileName)
 	}
 	return
 }
 
-func (context *OpsManager) exportFile(urlFormat string, filename string) (err error) {
+func (context *OpsManager) exportFile(ctx context.Context, urlFormat string, filename string) (err error) {
 	url := fmt.Sprintf(urlFormat, context.Hostname)
 
 	lo.G.Debug("Exporting file", log.Data{"url": url, "filename": filename})
@@ -134,29 +181,43 @@ func (context *OpsManager) exportFile(urlFormat string, filename string) (err er
 
 	if backupWriter, err = context.Writer(context.TargetDir, context.OpsmanagerBackupDir, filename); err == nil {
 		defer backupWriter.Close()
-		err = context.saveHTTPResponse(url, backupWriter)
+		err = context.saveHTTPResponse(ctx, url, backupWriter)
 	}
 	return
 }
 
-func (context *OpsManager) saveHTTPResponse(url string, dest io.Writer) (err error) {
-	var resp *http.Response
+//saveHTTPResponse - copies the response to the url into dest. The request is bound to ctx and its body is
+//closed by the call making it, so a response arriving after ctx is done is not leaked
+func (context *OpsManager) saveHTTPResponse(ctx context.Context, url string, dest io.Writer) (err error) {
 	lo.G.Debug("attempting to auth against", url)
+	requestor := cfbackup.GatewayWithContext(ctx, context.SettingsRequestor)
 
-	if resp, err = context.oauthHTTPGet(url); err != nil {
-		lo.G.Info("falling back to basic auth for legacy system")
-		lo.G.Debug(err)
-		resp, err = context.legacyHTTPGet(url)
-	}
+	err = cfbackup.RunWithContext(ctx, func() (err error) {
+		var resp *http.Response
 
-	if err == nil && resp.StatusCode == http.StatusOK {
-		defer resp.Body.Close()
-		_, err = io.Copy(dest, resp.Body)
+		if resp, err = context.oauthHTTPGet(requestor, url); err != nil {
+			lo.G.Info("falling back to basic auth for legacy system")
+			lo.G.Debug(err)
 
-	} else if resp != nil && resp.StatusCode != http.StatusOK {
-		errMsg, _ := ioutil.ReadAll(resp.Body)
-		err = errors.New(string(errMsg[:]))
-	}
+			if resp != nil {
+				resp.Body.Close()
+			}
+			resp, err = context.legacyHTTPGet(requestor, url)
+		}
+
+		if resp != nil {
+			defer resp.Body.Close()
+		}
+
+		if err == nil && resp.StatusCode == http.StatusOK {
+			_, err = io.Copy(cfbackup.NewContextWriter(ctx, dest), resp.Body)
+
+		} else if resp != nil && resp.StatusCode != http.StatusOK {
+			errMsg, _ := ioutil.ReadAll(resp.Body)
+			err = errors.New(string(errMsg[:]))
+		}
+		return
+	})
 
 	if err != nil {
 		lo.G.Error("error in save http request", err)
@@ -164,8 +225,7 @@ func (context *OpsManager) saveHTTPResponse(url string, dest io.Writer) (err err
 	return
 }
 
-func (context *OpsManager) legacyHTTPGet(url string) (resp *http.Response, err error) {
-	requestor := context.SettingsRequestor
+func (context *OpsManager) legacyHTTPGet(requestor httpRequestor, url string) (resp *http.Response, err error) {
 	resp, err = requestor.Get(ghttp.HttpRequestEntity{
 		Url:         url,
 		Username:    context.Username,
@@ -176,7 +236,7 @@ func (context *OpsManager) legacyHTTPGet(url string) (resp *http.Response, err e
 	return
 }
 
-func (context *OpsManager) oauthHTTPGet(urlString string) (resp *http.Response, err error) {
+func (context *OpsManager) oauthHTTPGet(requestor httpRequestor, urlString string) (resp *http.Response, err error) {
 	var token = context.Token
 	var uaaURL, _ = urllib.Parse(urlString)
 	var opsManagerUsername = context.Username
@@ -196,13 +256,13 @@ func (context *OpsManager) oauthHTTPGet(urlString string) (resp *http.Response,
 	lo.G.Debug("aquiring your token from: ", uaaURL, urlString)
 
 	if token == "" {
-		if token, err = uaa.GetToken("https://"+uaaURL.Host+"/uaa", opsManagerUsername, opsManagerPassword, clientID, clientSecret, grantType); err != nil {
+		if token, err = cfbackup.GetUAAToken(context.TLS, "https://"+uaaURL.Host+"/uaa", opsManagerUsername, opsManagerPassword, clientID, clientSecret, grantType); err != nil {
 			return nil, err
 		}
+		cfbackup.RedactSecrets(token)
 		lo.G.Debug("token acquired")
 	}
 
-	requestor := context.SettingsRequestor
 	resp, err = requestor.Get(ghttp.HttpRequestEntity{
 		Url:           urlString,
 		ContentType:   "application/octet-stream",
@@ -216,12 +276,20 @@ func (context *OpsManager) oauthHTTPGet(urlString string) (resp *http.Response,
 
 // Restore performs a restore of a Pivotal Ops Manager instance
 func (context *OpsManager) Restore() (err error) {
+	return context.RestoreWithContext(noDeadline)
+}
+
+// RestoreWithContext performs a restore of a Pivotal Ops Manager instance, giving up on the upload when ctx is done or the upload timeout passes
+func (context *OpsManager) RestoreWithContext(ctx context.Context) (err error) {
+	return context.Lock.Run(ctx, context.restore)
+}
+
+func (context *OpsManager) restore(ctx context.Context) error {
 	lo.G.Info("Starting restore for Opsman")
-	err = context.importInstallation()
-	return
+	return context.importInstallation(ctx)
 }
 
-func (context *OpsManager) importInstallation() (err error) {
+func (context *OpsManager) importInstallation(ctx context.Context) (err error) {
 	defer func() {
 		if err == nil && context.ClearBoshManifest {
 			lo.G.Debug("removing deployment files")
@@ -230,11 +298,13 @@ func (context *OpsManager) importInstallation() (err error) {
 	}()
 	installAssetsURL := fmt.Sprintf(OpsMgrInstallationAssetsURL, context.Hostname)
 	lo.G.Debug("uploading installation assets installAssetsURL: %s", installAssetsURL)
-	err = context.importInstallationPart(installAssetsURL, OpsMgrInstallationAssetsFileName, OpsMgrInstallationAssetsPostFieldName, context.AssetsUploader)
+	uploadCtx, cancel := cfbackup.WithPhaseTimeout(ctx, context.PhaseTimeouts.Upload)
+	defer cancel()
+	err = context.importInstallationPart(uploadCtx, installAssetsURL, OpsMgrInstallationAssetsFileName, OpsMgrInstallationAssetsPostFieldName, context.AssetsUploader)
 	return
 }
 
-func (context *OpsManager) importInstallationPart(url, filename, fieldname string, upload httpUploader) (err error) {
+func (context *OpsManager) importInstallationPart(ctx context.Context, url, filename, fieldname string, upload httpUploader) (err error) {
 	var backupReader io.ReadCloser
 
 	if backupReader, err = context.Reader(context.TargetDir, context.OpsmanagerBackupDir, filename); err == nil {
@@ -246,18 +316,21 @@ func (context *OpsManager) importInstallationPart(url, filename, fieldname strin
 			Password: context.Password,
 		}
 		filePath := path.Join(context.TargetDir, context.OpsmanagerBackupDir, filename)
-		bufferedReader := bufio.NewReader(backupReader)
+		bufferedReader := cfbackup.NewContextReader(ctx, bufio.NewReader(backupReader))
 		lo.G.Debug("upload request", log.Data{"fieldname": fieldname, "filePath": filePath})
 		creds := map[string]string{
 			"password":   context.Password,
 			"passphrase": context.Passphrase,
 		}
-		resp, err = upload(conn, fieldname, filePath, -1, bufferedReader, creds)
+		err = cfbackup.RunWithContext(ctx, func() (err error) {
+			resp, err = upload(conn, fieldname, filePath, artifactSize(backupReader), bufferedReader, creds)
+			return
+		})
 
 		if err == nil && resp.StatusCode == http.StatusOK {
 			lo.G.Debug("Request for %s succeeded with status: %s", url, resp.Status)
 
-		} else if resp != nil && resp.StatusCode != http.StatusOK {
+		} else if ctx.Err() == nil && resp != nil && resp.StatusCode != http.StatusOK {
 			err = fmt.Errorf("Request for %s failed with status: %s", url, resp.Status)
 		}
 
@@ -268,6 +341,36 @@ func (context *OpsManager) importInstallationPart(url, filename, fieldname strin
 	return
 }
 
+//artifactSize - the size of the backup artifact being read, so its upload carries its length. -1 when the reader
+//can not tell, as for encrypted or s3 backup sets, and the upload is sent chunked
+func artifactSize(reader io.Reader) int64 {
+	if file, ok := reader.(interface{ Stat() (os.FileInfo, error) }); ok {
+		if info, err := file.Stat(); err == nil {
+			return info.Size()
+		}
+	}
+	return -1
+}
+
+//~ Verify Operations
+
+// Verify checks the Ops Manager backup set can be read back, without restoring it
+func (context *OpsManager) Verify() (report cfbackup.VerificationReport, err error) {
+	lo.G.Info("Starting verify for Opsman")
+	report.Record(OpsMgrInstallationAssetsFileName, context.verifyArtifact(cfbackup.VerifyZipContains(OpsMgrInstallationYmlFileName), OpsMgrInstallationAssetsFileName))
+	report.Record(OpsMgrDeploymentsFileName, context.verifyArtifact(cfbackup.VerifyTarGz, OpsMgrDeploymentsFileName))
+
+	if !report.Passed() {
+		lo.G.Error("ops manager backup set failed verification", log.Data{"failures": report.Failures()})
+		err = cfbackup.ErrVerificationFailed
+	}
+	return
+}
+
+func (context *OpsManager) verifyArtifact(check cfbackup.ArtifactCheck, filename string) error {
+	return cfbackup.VerifyArtifact(context, check, context.TargetDir, context.OpsmanagerBackupDir, filename)
+}
+
 func (context *OpsManager) removeExistingDeploymentFiles() (err error) {
 	var w bytes.Buffer
 	command := fmt.Sprintf("if [ -f %s ]; then sudo rm %s;fi", OpsMgrDeploymentsFile, OpsMgrDeploymentsFile)
@@ -1,6 +1,8 @@
 package opsmanager
 
 import (
+	"io"
+
 	"github.com/pivotalservices/cfbackup"
 	"github.com/pivotalservices/cfbackup/tileregistry"
 	"github.com/xchapter7x/lo"
@@ -8,7 +10,18 @@ import (
 
 //New -- builds a new ops manager object pre initialized
 func (s *OpsManagerBuilder) New(tileSpec tileregistry.TileSpec) (opsManagerTileCloser tileregistry.TileCloser, err error) {
-	var opsManager *OpsManager
+	var (
+		opsManager *OpsManager
+		tlsClients *cfbackup.TLSClients
+	)
+
+	if tileSpec, err = tileSpec.ResolveSecrets(); err != nil {
+		return
+	}
+
+	if tlsClients, err = tileSpec.TLSClients(); err != nil {
+		return
+	}
 	opsManager, err = NewOpsManager(
 		tileSpec.OpsManagerHost,
 		tileSpec.AdminUser,
@@ -21,21 +34,34 @@ func (s *OpsManagerBuilder) New(tileSpec tileregistry.TileSpec) (opsManagerTileC
 		tileSpec.ClientSecret,
 		tileSpec.ArchiveDirectory,
 		tileSpec.CryptKey)
+	tileSpec.ApplyBackupContext(&opsManager.BackupContext)
+	opsManager.SetTLSClients(tlsClients)
 	opsManager.ClearBoshManifest = tileSpec.ClearBoshManifest
+	opsManager.PhaseTimeouts = tileSpec.PhaseTimeouts
+	opsManager.Lock = tileregistry.NewRunLock(tileSpec, opsManager.BackupContext)
+
+	if tileSpec.OpsManagerSSHPort > 0 {
+		opsManager.SSHPort = tileSpec.OpsManagerSSHPort
+	}
+	sshPrivateKey := tileSpec.OpsManagerSSHKey
 
-	if installationSettings, err := opsManager.GetInstallationSettings(); err == nil {
+	if sshPrivateKey != "" {
+		lo.G.Debug("using the ssh private key of the tileSpec")
+
+	} else if installationSettings, err := opsManager.GetInstallationSettings(); err == nil {
 		config := cfbackup.NewConfigurationParserFromReader(installationSettings)
 
 		if iaas, hasKey := config.GetIaaS(); hasKey {
 			lo.G.Debug("we found a iaas info block")
-			opsManager.SetSSHPrivateKey(iaas.SSHPrivateKey)
+			sshPrivateKey = iaas.SSHPrivateKey
 
 		} else {
 			lo.G.Debug("No IaaS PEM key found. Defaulting to using ssh username and password credentials")
 		}
 	}
+	opsManager.SetSSHPrivateKey(sshPrivateKey)
 	opsManagerTileCloser = struct {
-		tileregistry.Tile
+		*OpsManager
 		tileregistry.Closer
 	}{
 		opsManager,
@@ -43,3 +69,40 @@ func (s *OpsManagerBuilder) New(tileSpec tileregistry.TileSpec) (opsManagerTileC
 	}
 	return
 }
+
+//GetInstallationSettings - makes a call to the ops manager described by the tileSpec, verified with the tileSpec's tls config, and returns a io.reader containing the contents of the installation settings file.
+var GetInstallationSettings = func(tileSpec tileregistry.TileSpec) (settings io.Reader, err error) {
+	var (
+		opsManager *OpsManager
+	)
+
+	if opsManager, err = newTileSpecOpsManager(tileSpec); err == nil {
+		settings, err = opsManager.GetInstallationSettings()
+	}
+	return
+}
+
+//GetDirectorCA - makes a call to the ops manager described by the tileSpec, verified with the tileSpec's tls config, and returns the pem of the certificate authorities it issued the director's certificate with.
+var GetDirectorCA = func(tileSpec tileregistry.TileSpec) (ca string, err error) {
+	var (
+		opsManager *OpsManager
+	)
+
+	if opsManager, err = newTileSpecOpsManager(tileSpec); err == nil {
+		ca, err = opsManager.GetDirectorCA()
+	}
+	return
+}
+
+func newTileSpecOpsManager(tileSpec tileregistry.TileSpec) (opsManager *OpsManager, err error) {
+	var tlsClients *cfbackup.TLSClients
+
+	if tlsClients, err = tileSpec.TLSClients(); err != nil {
+		return
+	}
+
+	if opsManager, err = NewOpsManager(tileSpec.OpsManagerHost, tileSpec.AdminUser, tileSpec.AdminPass, tileSpec.AdminToken, tileSpec.OpsManagerUser, tileSpec.OpsManagerPass, tileSpec.OpsManagerPassphrase, tileSpec.ClientID, tileSpec.ClientSecret, tileSpec.ArchiveDirectory, tileSpec.CryptKey); err == nil {
+		opsManager.SetTLSClients(tlsClients)
+	}
+	return
+}
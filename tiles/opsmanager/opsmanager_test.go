@@ -1,6 +1,9 @@
 package opsmanager_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -8,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"time"
 
 	"github.com/cloudfoundry-community/go-cfenv"
 	. "github.com/onsi/ginkgo"
@@ -23,6 +27,29 @@ import (
 	"github.com/pivotalservices/gtils/osutils"
 )
 
+type trackingBody struct {
+	*bytes.Buffer
+	closed chan struct{}
+}
+
+func (s *trackingBody) Close() error {
+	close(s.closed)
+	return nil
+}
+
+type slowGateway struct {
+	fakes.MockHTTPGateway
+	release chan struct{}
+	body    *trackingBody
+}
+
+func (s *slowGateway) Get(entity ghttp.HttpRequestEntity) ghttp.RequestAdaptor {
+	return func() (*http.Response, error) {
+		<-s.release
+		return &http.Response{StatusCode: http.StatusOK, Body: s.body}, nil
+	}
+}
+
 var _ = Describe("OpsManager object", func() {
 	var (
 		opsManager *OpsManager
@@ -51,6 +78,7 @@ var _ = Describe("OpsManager object", func() {
 				urlString, _ := url.Parse(server.URL())
 				fmt.Println(server.URL())
 				opsManager, _ := NewOpsManager(urlString.Host, "", "", httpTokenAcquiredManually, "opsUser", "opsPass", "opsPassphrase", "", "", tmpDir, "")
+				opsManager.SetTLSClients(trustTestServer(server))
 				installationSettings, err = opsManager.GetInstallationSettings()
 			})
 
@@ -104,6 +132,103 @@ var _ = Describe("OpsManager object", func() {
 			})
 		})
 	})
+	Describe("Given a GetDirectorCA method", func() {
+		var (
+			ca  string
+			err error
+		)
+
+		getDirectorCA := func(response string) {
+			opsManager = &OpsManager{
+				SettingsRequestor: &fakes.MockHTTPGateway{StatusCode: 200, State: response},
+				Hostname:          "localhost",
+				Username:          "user",
+				Password:          "password",
+			}
+			ca, err = opsManager.GetDirectorCA()
+		}
+
+		Context("when ops manager has an active certificate authority", func() {
+			It("then it should return the pem of the active ones only", func() {
+				getDirectorCA(`{"certificate_authorities": [
+					{"active": false, "cert_pem": "-----BEGIN CERTIFICATE-----\nold\n-----END CERTIFICATE-----"},
+					{"active": true, "cert_pem": "-----BEGIN CERTIFICATE-----\nnew\n-----END CERTIFICATE-----\n"}]}`)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(ca).Should(Equal("-----BEGIN CERTIFICATE-----\nnew\n-----END CERTIFICATE-----\n"))
+			})
+		})
+
+		Context("when ops manager has no active certificate authority", func() {
+			It("then it should return an error", func() {
+				getDirectorCA(`{"certificate_authorities": []}`)
+				Ω(err).Should(MatchError(ErrNoActiveCA))
+			})
+		})
+	})
+	Describe("Given a Verify method", func() {
+		var (
+			opsMgr *OpsManager
+			report cfbackup.VerificationReport
+			err    error
+		)
+		writeArtifact := func(filename string, contents []byte) {
+			f, _ := osutils.SafeCreate(opsMgr.TargetDir, opsMgr.OpsmanagerBackupDir, filename)
+			f.Write(contents)
+			f.Close()
+		}
+
+		BeforeEach(func() {
+			tmpDir, _ = ioutil.TempDir("/tmp", "test")
+			opsMgr = &OpsManager{
+				BackupContext:       fakes.NewFakeBackupContext(path.Join(tmpDir, "backup"), cfenv.CurrentEnv(), new(cfbackup.DiskProvider)),
+				OpsmanagerBackupDir: "opsmanager",
+			}
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tmpDir)
+		})
+
+		Context("when called against a valid backup set", func() {
+			BeforeEach(func() {
+				writeArtifact(OpsMgrInstallationAssetsFileName, fakes.NewZip(map[string]string{OpsMgrInstallationYmlFileName: "---"}))
+				writeArtifact(OpsMgrDeploymentsFileName, fakes.NewTarGz(map[string]string{"deployments/bosh-deployments.yml": "---"}))
+				report, err = opsMgr.Verify()
+			})
+
+			It("then it should pass every artifact", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(report.Passed()).Should(BeTrue())
+				Ω(report.Artifacts).Should(HaveLen(2))
+			})
+		})
+
+		Context("when called against a backup set with an installation zip missing installation.yml", func() {
+			BeforeEach(func() {
+				writeArtifact(OpsMgrInstallationAssetsFileName, fakes.NewZip(map[string]string{"metadata/cf.yml": "---"}))
+				writeArtifact(OpsMgrDeploymentsFileName, fakes.NewTarGz(map[string]string{"deployments/bosh-deployments.yml": "---"}))
+				report, err = opsMgr.Verify()
+			})
+
+			It("then it should fail only the installation zip", func() {
+				Ω(err).Should(Equal(cfbackup.ErrVerificationFailed))
+				Ω(report.Failures()).Should(HaveLen(1))
+				Ω(report.Failures()[0].Artifact).Should(Equal(OpsMgrInstallationAssetsFileName))
+			})
+		})
+
+		Context("when called against a backup set with missing artifacts", func() {
+			BeforeEach(func() {
+				report, err = opsMgr.Verify()
+			})
+
+			It("then it should fail every artifact", func() {
+				Ω(err).Should(HaveOccurred())
+				Ω(report.Failures()).Should(HaveLen(2))
+			})
+		})
+	})
+
 	Describe("Given a Restore method", func() {
 
 		Context("when called", func() {
@@ -111,6 +236,7 @@ var _ = Describe("OpsManager object", func() {
 				opsMgr                  *OpsManager
 				fakeSettingsUploader    *fake.MultiPart
 				fakeAssetsUploader      *fake.MultiPart
+				assetsSize              int64
 				controlAssetsContents   = []byte(`test assets`)
 				controlSettingsContents = []byte(`test bytes`)
 			)
@@ -125,8 +251,11 @@ var _ = Describe("OpsManager object", func() {
 				gw := &fakes.MockHTTPGateway{}
 
 				opsMgr = &OpsManager{
-					SettingsUploader:    fakeSettingsUploader.Upload,
-					AssetsUploader:      fakeAssetsUploader.Upload,
+					SettingsUploader: fakeSettingsUploader.Upload,
+					AssetsUploader: func(conn ghttp.ConnAuth, paramName, filename string, fileSize int64, fileRef io.Reader, params map[string]string) (*http.Response, error) {
+						assetsSize = fileSize
+						return fakeAssetsUploader.Upload(conn, paramName, filename, fileSize, fileRef, params)
+					},
 					SettingsRequestor:   gw,
 					AssetsRequestor:     gw,
 					Hostname:            "localhost",
@@ -153,6 +282,9 @@ var _ = Describe("OpsManager object", func() {
 				Ω(fakeAssetsUploader.UploadCallCount).ShouldNot(Equal(0))
 				Ω(fakeAssetsUploader.SpyFileContents).Should(Equal(controlAssetsContents))
 			})
+			It("then it should upload the assets archive with its size", func() {
+				Ω(assetsSize).Should(Equal(int64(len(controlAssetsContents))))
+			})
 			It("then it should not import the settings archive", func() {
 				Ω(fakeSettingsUploader.UploadCallCount).Should(Equal(0))
 				Ω(fakeSettingsUploader.SpyFileContents).Should(BeNil())
@@ -391,6 +523,27 @@ var _ = Describe("OpsManager object", func() {
 				Ω(osutils.Exists(filepath)).Should(BeTrue())
 			})
 		})
+
+		Context("when the context is done before ops manager responds", func() {
+			It("should return the context's error and close the late response's body", func() {
+				tmpDir, _ = ioutil.TempDir("/tmp", "test")
+				gw := &slowGateway{release: make(chan struct{}), body: &trackingBody{bytes.NewBufferString(fakes.SuccessString), make(chan struct{})}}
+				opsManager = &OpsManager{
+					SettingsRequestor:   gw,
+					Hostname:            "localhost",
+					Token:               "token",
+					BackupContext:       fakes.NewFakeBackupContext(path.Join(tmpDir, "backup"), cfenv.CurrentEnv(), new(cfbackup.DiskProvider)),
+					Executer:            &fakes.SuccessExecuter{},
+					LocalExecuter:       fakes.NewLocalMockExecuter(),
+					OpsmanagerBackupDir: "opsmanager",
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(time.Millisecond, cancel)
+				Ω(opsManager.BackupWithContext(ctx)).Should(Equal(context.Canceled))
+				close(gw.release)
+				Eventually(gw.body.closed).Should(BeClosed())
+			})
+		})
 	})
 })
 
@@ -406,6 +559,7 @@ var checkAuthorizationMechanismSupport = func(method string, oauthStatusCode, ap
 			urlString, _ := url.Parse(server.URL())
 			fmt.Println(server.URL())
 			opsManager, _ := NewOpsManager(urlString.Host, "user", "pass", "", "opsUser", "opsPass", "opsPassphrase", "", "", tmpDir, "")
+			opsManager.SetTLSClients(trustTestServer(server))
 			installationSettings, err = opsManager.GetInstallationSettings()
 		})
 
@@ -426,3 +580,13 @@ var checkAuthorizationMechanismSupport = func(method string, oauthStatusCode, ap
 		})
 	})
 }
+
+func trustTestServer(server *testhttp.Server) *cfbackup.TLSClients {
+	clients, err := cfbackup.NewTLSClients(cfbackup.TLSConfig{OpsManagerCA: testServerCA(server)})
+	Ω(err).ShouldNot(HaveOccurred())
+	return clients
+}
+
+func testServerCA(server *testhttp.Server) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.HTTPTestServer.Certificate().Raw}))
+}
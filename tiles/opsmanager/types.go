@@ -36,11 +36,21 @@ type (
 		SSHPassword         string
 		SSHPort             int
 		ClearBoshManifest   bool
+		PhaseTimeouts       cfbackup.PhaseTimeouts
+		Lock                *cfbackup.LeaseLock
+		TLS                 *cfbackup.TLSClients
 	}
 
 	//OpsManagerBuilder - an object that can build ops manager objects
 	OpsManagerBuilder struct{}
 
+	certificateAuthorities struct {
+		CertificateAuthorities []struct {
+			Active  bool   `json:"active"`
+			CertPEM string `json:"cert_pem"`
+		} `json:"certificate_authorities"`
+	}
+
 	httpUploader func(conn ghttp.ConnAuth, paramName, filename string, fileSize int64, fileRef io.Reader, params map[string]string) (res *http.Response, err error)
 
 	httpRequestor interface {
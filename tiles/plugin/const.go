@@ -0,0 +1,33 @@
+package plugin
+
+import "errors"
+
+//Plugin protocol constants
+const (
+	ActionBackup  string = "backup"
+	ActionRestore string = "restore"
+	ActionVerify  string = "verify"
+
+	MsgRequest   string = "request"
+	MsgOpenWrite string = "open_write"
+	MsgWrite     string = "write"
+	MsgClose     string = "close"
+	MsgAck       string = "ack"
+	MsgRead      string = "read"
+	MsgData      string = "data"
+	MsgEOF       string = "eof"
+	MsgLog       string = "log"
+	MsgDone      string = "done"
+
+	PluginChunkSize int = 32 * 1024
+
+	//PluginDirEnvVarname - environment variable naming the directory whose plugins are registered with the other tiles
+	PluginDirEnvVarname string = "CFBACKUP_PLUGIN_DIR"
+)
+
+var (
+	//ErrPluginExited - error for a plugin which exited before reporting a result
+	ErrPluginExited = errors.New("plugin exited without sending a done message")
+	//ErrUnknownStream - error for a message referencing a stream which was never opened
+	ErrUnknownStream = errors.New("message references an unknown stream")
+)
@@ -0,0 +1,27 @@
+/*
+Package plugin lets a tile be backed up by an external executable instead of
+code compiled into the tileregistry.
+
+Every executable found in a plugin directory is registered as a TileGenerator,
+named after its filename. The tiles package registers the plugins of the
+directory named by CFBACKUP_PLUGIN_DIR along with the built in tiles. When a tile action runs, the host starts the
+executable and the two sides exchange newline delimited json Messages, the
+host writing to the plugin's stdin and reading from its stdout:
+
+	host   -> plugin  {"type":"request","action":"backup","tile_spec":{...}}
+	plugin -> host    {"type":"open_write","stream":1,"path":["dir","file"]}
+	plugin -> host    {"type":"write","stream":1,"data":"<base64>"}
+	plugin -> host    {"type":"close","stream":1}
+	host   -> plugin  {"type":"ack","stream":1,"error":""}
+	plugin -> host    {"type":"read","stream":2,"path":["dir","file"]}
+	host   -> plugin  {"type":"data","stream":2,"data":"<base64>"}
+	host   -> plugin  {"type":"eof","stream":2,"error":""}
+	plugin -> host    {"type":"log","message":"..."}
+	plugin -> host    {"type":"done","error":"","report":{...}}
+
+Paths are handed to the host's StorageProvider untouched, so the plugin sees
+the same encryption and storage target as any other tile. The tile spec's
+PluginArgs are passed along for the plugin's own settings. Go plugins can use
+Serve, which implements the plugin side of the protocol around a Handler.
+*/
+package plugin
@@ -0,0 +1,247 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/cloudfoundry-community/go-cfenv"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	"github.com/xchapter7x/lo"
+)
+
+//NewPluginCommand - builds the command used to start a plugin executable
+var NewPluginCommand = exec.Command
+
+//NewTile - initializes a tile which runs its actions through the plugin at the given path
+func NewTile(pluginPath string, tileSpec tileregistry.TileSpec) *Tile {
+	return &Tile{
+		Path:          pluginPath,
+		TileSpec:      tileSpec,
+		BackupContext: cfbackup.NewBackupContext(tileSpec.ArchiveDirectory, cfenv.CurrentEnv(), tileSpec.CryptKey),
+	}
+}
+
+// Backup runs the backup action of the plugin
+func (s *Tile) Backup() error {
+	return s.BackupWithContext(context.Background())
+}
+
+// BackupWithContext runs the backup action of the plugin, killing the plugin when ctx is done
+func (s *Tile) BackupWithContext(ctx context.Context) (err error) {
+	_, err = s.run(ctx, ActionBackup)
+	return
+}
+
+// Restore runs the restore action of the plugin
+func (s *Tile) Restore() error {
+	return s.RestoreWithContext(context.Background())
+}
+
+// RestoreWithContext runs the restore action of the plugin, killing the plugin when ctx is done
+func (s *Tile) RestoreWithContext(ctx context.Context) (err error) {
+	_, err = s.run(ctx, ActionRestore)
+	return
+}
+
+// Verify runs the verify action of the plugin and returns the report it produced
+func (s *Tile) Verify() (report cfbackup.VerificationReport, err error) {
+	return s.run(context.Background(), ActionVerify)
+}
+
+func (s *Tile) run(ctx context.Context, action string) (report cfbackup.VerificationReport, err error) {
+	var (
+		stdin  io.WriteCloser
+		stdout io.ReadCloser
+	)
+	cmd := NewPluginCommand(s.Path)
+	lo.G.Debug("starting plugin", s.Path, action)
+
+	if stdin, err = cmd.StdinPipe(); err != nil {
+		return
+	}
+
+	if stdout, err = cmd.StdoutPipe(); err != nil {
+		return
+	}
+
+	if err = cmd.Start(); err != nil {
+		return
+	}
+	finished := make(chan struct{})
+	go killOnDone(ctx, finished, cmd, stdout)
+	report, err = NewSession(s.BackupContext, stdin, stdout).Run(action, s.TileSpec)
+	close(finished)
+	stdin.Close()
+
+	if waitErr := cmd.Wait(); err == nil && waitErr != nil {
+		err = waitErr
+	}
+
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		lo.G.Errorf("plugin %s failed to %s: %s", filepath.Base(s.Path), action, err)
+	}
+	return
+}
+
+//killOnDone - kills the plugin and closes its output once ctx is done, so a session blocked on the plugin returns
+func killOnDone(ctx context.Context, finished <-chan struct{}, cmd *exec.Cmd, stdout io.Closer) {
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		stdout.Close()
+	}
+}
+
+//NewSession - creates the host side of a plugin invocation over the given pipes
+func NewSession(storageProvider cfbackup.StorageProvider, pluginStdin io.Writer, pluginStdout io.Reader) *Session {
+	return &Session{
+		storageProvider: storageProvider,
+		encoder:         newMessageEncoder(pluginStdin),
+		decoder:         json.NewDecoder(pluginStdout),
+		writers:         make(map[int]*hostWriter),
+	}
+}
+
+//Run - sends the request for the given action and serves the plugin's storage requests until it is done
+func (s *Session) Run(action string, tileSpec tileregistry.TileSpec) (report cfbackup.VerificationReport, err error) {
+	tileSpec.CryptKey = ""
+
+	if err = s.encoder.Send(Message{Type: MsgRequest, Action: action, TileSpec: &tileSpec}); err != nil {
+		return
+	}
+	defer s.closeWriters()
+
+	for {
+		var msg Message
+
+		if err = s.decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				err = ErrPluginExited
+			}
+			return
+		}
+
+		if msg.Type == MsgDone {
+			s.readers.Wait()
+
+			if msg.Report != nil {
+				report = *msg.Report
+			}
+
+			if msg.Error != "" {
+				err = errors.New(msg.Error)
+			}
+			return
+		}
+
+		if err = s.handle(msg); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Session) handle(msg Message) (err error) {
+	switch msg.Type {
+	case MsgOpenWrite:
+		writer := new(hostWriter)
+		writer.WriteCloser, writer.err = s.storageProvider.Writer(msg.Path...)
+		s.writers[msg.Stream] = writer
+
+	case MsgWrite:
+		if writer, ok := s.writers[msg.Stream]; !ok {
+			err = ErrUnknownStream
+
+		} else if writer.err == nil {
+			_, writer.err = writer.Write(msg.Data)
+		}
+
+	case MsgClose:
+		if writer, ok := s.writers[msg.Stream]; !ok {
+			err = ErrUnknownStream
+
+		} else {
+			delete(s.writers, msg.Stream)
+
+			if writer.err == nil {
+				writer.err = writer.Close()
+			}
+			err = s.encoder.Send(Message{Type: MsgAck, Stream: msg.Stream, Error: errorString(writer.err)})
+		}
+
+	case MsgRead:
+		s.readers.Add(1)
+		go s.streamArtifact(msg.Stream, msg.Path)
+
+	case MsgLog:
+		lo.G.Info("plugin: ", msg.Message)
+
+	default:
+		lo.G.Debug("ignoring unknown plugin message type: ", msg.Type)
+	}
+	return
+}
+
+func (s *Session) streamArtifact(stream int, filepath []string) {
+	defer s.readers.Done()
+	var (
+		reader io.ReadCloser
+		err    error
+	)
+
+	if reader, err = s.storageProvider.Reader(filepath...); err == nil {
+		defer reader.Close()
+		buffer := make([]byte, PluginChunkSize)
+
+		for err == nil {
+			var n int
+
+			if n, err = reader.Read(buffer); n > 0 {
+				if sendErr := s.encoder.Send(Message{Type: MsgData, Stream: stream, Data: buffer[:n]}); sendErr != nil {
+					err = sendErr
+				}
+			}
+		}
+
+		if err == io.EOF {
+			err = nil
+		}
+	}
+	s.encoder.Send(Message{Type: MsgEOF, Stream: stream, Error: errorString(err)})
+}
+
+func (s *Session) closeWriters() {
+	for stream, writer := range s.writers {
+		if writer.err == nil {
+			writer.Close()
+		}
+		delete(s.writers, stream)
+	}
+}
+
+func newMessageEncoder(w io.Writer) *messageEncoder {
+	return &messageEncoder{encoder: json.NewEncoder(w)}
+}
+
+//Send - writes a single message, safe for concurrent use
+func (s *messageEncoder) Send(msg Message) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.encoder.Encode(msg)
+}
+
+func errorString(err error) (msg string) {
+	if err != nil {
+		msg = err.Error()
+	}
+	return
+}
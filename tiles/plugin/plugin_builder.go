@@ -0,0 +1,66 @@
+package plugin
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	"github.com/xchapter7x/lo"
+)
+
+//New -- builds a new plugin tile for the executable this generator was discovered from. The plugin verifies
+//the servers it calls with the tileSpec's tls config, which has to be readable
+func (s *TileGenerator) New(tileSpec tileregistry.TileSpec) (pluginTileCloser tileregistry.TileCloser, err error) {
+	if tileSpec, err = tileSpec.ResolveSecrets(); err != nil {
+		return
+	}
+
+	if err = tileSpec.TLS.Validate(); err != nil {
+		return
+	}
+	tile := NewTile(s.Path, tileSpec)
+	tileSpec.ApplyBackupContext(&tile.BackupContext)
+	pluginTileCloser = struct {
+		*Tile
+		tileregistry.Closer
+	}{
+		tile,
+		new(tileregistry.DoNothingCloser),
+	}
+	return
+}
+
+//Discover -- finds the plugin executables in the given directory, keyed by
+//their filename without extension
+func Discover(pluginDir string) (generators map[string]*TileGenerator, err error) {
+	generators = make(map[string]*TileGenerator)
+	files, err := ioutil.ReadDir(pluginDir)
+
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		if !file.Mode().IsRegular() || file.Mode().Perm()&0111 == 0 {
+			lo.G.Debug("skipping non executable plugin candidate: ", file.Name())
+			continue
+		}
+		name := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+		generators[name] = &TileGenerator{Path: filepath.Join(pluginDir, file.Name())}
+	}
+	return
+}
+
+//Register -- adds every plugin found in the given directory to the tileregistry
+func Register(pluginDir string) (err error) {
+	var generators map[string]*TileGenerator
+
+	if generators, err = Discover(pluginDir); err == nil {
+		for name, generator := range generators {
+			lo.G.Debug("registering plugin tile: ", name)
+			tileregistry.Register(name, generator)
+		}
+	}
+	return
+}
@@ -0,0 +1,92 @@
+package plugin_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	. "github.com/pivotalservices/cfbackup/tiles/plugin"
+)
+
+var _ = Describe("TileGenerator", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "plugins")
+		ioutil.WriteFile(path.Join(dir, "partner-tile.sh"), []byte("#!/bin/sh\n"), 0755)
+		ioutil.WriteFile(path.Join(dir, "README.md"), []byte("not a plugin"), 0644)
+		os.Mkdir(path.Join(dir, "subdir"), 0755)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+		tileregistry.Repo = make(map[string]tileregistry.TileGenerator)
+	})
+
+	Describe("given a Discover() method", func() {
+		Context("when called with a directory of plugins", func() {
+			It("then it should return a generator for each executable keyed by name", func() {
+				generators, err := Discover(dir)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(generators).Should(HaveLen(1))
+				Ω(generators).Should(HaveKey("partner-tile"))
+				Ω(generators["partner-tile"].Path).Should(Equal(path.Join(dir, "partner-tile.sh")))
+			})
+		})
+
+		Context("when called with a directory which does not exist", func() {
+			It("then it should return an error", func() {
+				_, err := Discover(path.Join(dir, "missing"))
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("given a Register() method", func() {
+		Context("when called with a directory of plugins", func() {
+			It("then it should register each plugin in the tileregistry", func() {
+				Ω(Register(dir)).Should(Succeed())
+				Ω(tileregistry.GetRegistry()).Should(HaveKey("partner-tile"))
+			})
+		})
+	})
+
+	Describe("given a New() method", func() {
+		Context("when called with a tileSpec", func() {
+			It("then it should return a plugin tile for the executable", func() {
+				tile, err := (&TileGenerator{Path: path.Join(dir, "partner-tile.sh")}).New(tileregistry.TileSpec{ArchiveDirectory: dir})
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(tile).ShouldNot(BeNil())
+			})
+		})
+
+		Context("when the tileSpec's tls config can not be read", func() {
+			It("then it should return an error", func() {
+				_, err := (&TileGenerator{Path: path.Join(dir, "partner-tile.sh")}).New(tileregistry.TileSpec{ArchiveDirectory: dir, TLS: cfbackup.TLSConfig{Pins: []string{"not-a-pin"}}})
+				Ω(err).Should(MatchError(cfbackup.ErrTLSInvalidPin))
+			})
+		})
+
+		Context("when the context of a backup is done before the plugin exits", func() {
+			It("then it should kill the plugin and return the context's error", func() {
+				ioutil.WriteFile(path.Join(dir, "hanging-tile.sh"), []byte("#!/bin/sh\nexec sleep 30\n"), 0755)
+				tile, err := (&TileGenerator{Path: path.Join(dir, "hanging-tile.sh")}).New(tileregistry.TileSpec{ArchiveDirectory: dir})
+				Ω(err).ShouldNot(HaveOccurred())
+				_, isContextTile := tile.(tileregistry.ContextTile)
+				Ω(isContextTile).Should(BeTrue())
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+
+				started := time.Now()
+				Ω(tileregistry.BackupWithContext(ctx, tile)).Should(Equal(context.DeadlineExceeded))
+				Ω(time.Since(started)).Should(BeNumerically("<", 5*time.Second))
+			})
+		})
+	})
+})
@@ -0,0 +1,233 @@
+package plugin_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	. "github.com/pivotalservices/cfbackup/tiles/plugin"
+)
+
+type fakeHandler struct {
+	tileSpec tileregistry.TileSpec
+	contents string
+	report   cfbackup.VerificationReport
+	err      error
+}
+
+func (s *fakeHandler) Backup(tileSpec tileregistry.TileSpec, storageProvider cfbackup.StorageProvider) (err error) {
+	var writer io.WriteCloser
+	s.tileSpec = tileSpec
+
+	if writer, err = storageProvider.Writer(tileSpec.ArchiveDirectory, "plugin.backup"); err == nil {
+		if _, err = io.Copy(writer, strings.NewReader(s.contents)); err == nil {
+			err = writer.Close()
+		}
+	}
+
+	if err == nil {
+		err = s.err
+	}
+	return
+}
+
+func (s *fakeHandler) Restore(tileSpec tileregistry.TileSpec, storageProvider cfbackup.StorageProvider) (err error) {
+	var (
+		reader   io.ReadCloser
+		contents []byte
+	)
+	s.tileSpec = tileSpec
+
+	if reader, err = storageProvider.Reader(tileSpec.ArchiveDirectory, "plugin.backup"); err == nil {
+		defer reader.Close()
+
+		if contents, err = ioutil.ReadAll(reader); err == nil {
+			s.contents = string(contents)
+		}
+	}
+	return
+}
+
+func (s *fakeHandler) Verify(tileSpec tileregistry.TileSpec, storageProvider cfbackup.StorageProvider) (cfbackup.VerificationReport, error) {
+	s.tileSpec = tileSpec
+	return s.report, s.err
+}
+
+//interleavedHandler - restores by reading a large artifact a little, then another artifact to the end,
+//then the rest of the first one
+type interleavedHandler struct {
+	fakeHandler
+	first, second string
+}
+
+func (s *interleavedHandler) Restore(tileSpec tileregistry.TileSpec, storageProvider cfbackup.StorageProvider) (err error) {
+	var (
+		first, second io.ReadCloser
+		head          = make([]byte, 1)
+		rest, all     []byte
+	)
+
+	if first, err = storageProvider.Reader(tileSpec.ArchiveDirectory, "first.backup"); err != nil {
+		return
+	}
+	defer first.Close()
+
+	if _, err = io.ReadFull(first, head); err != nil {
+		return
+	}
+
+	if second, err = storageProvider.Reader(tileSpec.ArchiveDirectory, "second.backup"); err != nil {
+		return
+	}
+	defer second.Close()
+
+	if all, err = ioutil.ReadAll(second); err != nil {
+		return
+	}
+
+	if rest, err = ioutil.ReadAll(first); err == nil {
+		s.first, s.second = string(head)+string(rest), string(all)
+	}
+	return
+}
+
+func runSession(action string, tileSpec tileregistry.TileSpec, handler Handler) (report cfbackup.VerificationReport, err error) {
+	hostReader, pluginWriter := io.Pipe()
+	pluginReader, hostWriter := io.Pipe()
+	served := make(chan error, 1)
+
+	go func() {
+		served <- Serve(handler, pluginReader, pluginWriter)
+		pluginWriter.Close()
+	}()
+	report, err = NewSession(cfbackup.NewDiskProvider(), hostWriter, hostReader).Run(action, tileSpec)
+	hostWriter.Close()
+	<-served
+	return
+}
+
+var _ = Describe("Plugin", func() {
+	var (
+		dir      string
+		tileSpec tileregistry.TileSpec
+		handler  *fakeHandler
+	)
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "plugin")
+		tileSpec = tileregistry.TileSpec{
+			ArchiveDirectory: dir,
+			CryptKey:         "secret",
+			PluginArgs:       "--some-flag",
+		}
+		handler = new(fakeHandler)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Describe("given a Session and a plugin served over pipes", func() {
+		Context("when the backup action is run", func() {
+			var err error
+
+			BeforeEach(func() {
+				handler.contents = strings.Repeat("backup-data", PluginChunkSize/4)
+				_, err = runSession(ActionBackup, tileSpec, handler)
+			})
+
+			It("then it should pass the tile spec and plugin args to the plugin", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(handler.tileSpec.ArchiveDirectory).Should(Equal(dir))
+				Ω(handler.tileSpec.PluginArgs).Should(Equal("--some-flag"))
+			})
+
+			It("then it should not pass the crypt key to the plugin", func() {
+				Ω(handler.tileSpec.CryptKey).Should(BeEmpty())
+			})
+
+			It("then it should write the plugin's artifact to the host storage", func() {
+				contents, readErr := ioutil.ReadFile(path.Join(dir, "plugin.backup"))
+				Ω(readErr).ShouldNot(HaveOccurred())
+				Ω(string(contents)).Should(Equal(handler.contents))
+			})
+		})
+
+		Context("when the restore action is run", func() {
+			var (
+				err             error
+				controlContents = strings.Repeat("restore-data", PluginChunkSize/4)
+			)
+
+			BeforeEach(func() {
+				ioutil.WriteFile(path.Join(dir, "plugin.backup"), []byte(controlContents), 0644)
+				_, err = runSession(ActionRestore, tileSpec, handler)
+			})
+
+			It("then it should stream the artifact from the host storage to the plugin", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(handler.contents).Should(Equal(controlContents))
+			})
+		})
+
+		Context("when the restore action reads one artifact while another is streaming", func() {
+			It("then it should not hold up either stream", func() {
+				first := strings.Repeat("first-data", PluginChunkSize*4)
+				ioutil.WriteFile(path.Join(dir, "first.backup"), []byte(first), 0644)
+				ioutil.WriteFile(path.Join(dir, "second.backup"), []byte("second-data"), 0644)
+				interleaved := new(interleavedHandler)
+				done := make(chan error, 1)
+
+				go func() {
+					_, err := runSession(ActionRestore, tileSpec, interleaved)
+					done <- err
+				}()
+				Eventually(done, 10).Should(Receive(BeNil()))
+				Ω(interleaved.first).Should(Equal(first))
+				Ω(interleaved.second).Should(Equal("second-data"))
+			})
+		})
+
+		Context("when the restore action reads an artifact which does not exist", func() {
+			It("then it should return the host's read error", func() {
+				_, err := runSession(ActionRestore, tileSpec, handler)
+				Ω(err).Should(HaveOccurred())
+				Ω(err.Error()).Should(ContainSubstring("plugin.backup"))
+			})
+		})
+
+		Context("when the verify action is run", func() {
+			It("then it should return the report produced by the plugin", func() {
+				handler.report.Record("plugin.backup", errors.New("truncated"))
+				report, err := runSession(ActionVerify, tileSpec, handler)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(report).Should(Equal(handler.report))
+				Ω(report.Passed()).Should(BeFalse())
+			})
+		})
+
+		Context("when the plugin handler fails", func() {
+			It("then it should return the plugin's error", func() {
+				handler.err = errors.New("plugin failure")
+				_, err := runSession(ActionBackup, tileSpec, handler)
+				Ω(err).Should(MatchError("plugin failure"))
+			})
+		})
+
+		Context("when the plugin exits without a result", func() {
+			It("then it should return an error", func() {
+				hostReader, pluginWriter := io.Pipe()
+				pluginWriter.Close()
+				_, err := NewSession(cfbackup.NewDiskProvider(), ioutil.Discard, hostReader).Run(ActionBackup, tileSpec)
+				Ω(err).Should(Equal(ErrPluginExited))
+			})
+		})
+	})
+})
@@ -0,0 +1,233 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pivotalservices/cfbackup"
+)
+
+//Serve - runs the plugin side of the protocol, dispatching the host's request to the given handler.
+//Plugin executables call it with os.Stdin and os.Stdout.
+func Serve(handler Handler, in io.Reader, out io.Writer) (err error) {
+	var request Message
+	decoder := json.NewDecoder(in)
+
+	if err = decoder.Decode(&request); err != nil {
+		return
+	}
+
+	if request.Type != MsgRequest || request.TileSpec == nil {
+		return fmt.Errorf("expected a %s message, got %s", MsgRequest, request.Type)
+	}
+	storageProvider := NewRemoteStorageProvider(out)
+	go storageProvider.dispatch(decoder)
+	done := Message{Type: MsgDone}
+
+	switch request.Action {
+	case ActionBackup:
+		err = handler.Backup(*request.TileSpec, storageProvider)
+	case ActionRestore:
+		err = handler.Restore(*request.TileSpec, storageProvider)
+	case ActionVerify:
+		var report cfbackup.VerificationReport
+		report, err = handler.Verify(*request.TileSpec, storageProvider)
+		done.Report = &report
+	default:
+		err = fmt.Errorf("unsupported plugin action %s", request.Action)
+	}
+	done.Error = errorString(err)
+
+	if sendErr := storageProvider.encoder.Send(done); err == nil {
+		err = sendErr
+	}
+	return
+}
+
+//NewRemoteStorageProvider - creates a storage provider which proxies to the host through the given writer
+func NewRemoteStorageProvider(out io.Writer) *RemoteStorageProvider {
+	return &RemoteStorageProvider{
+		encoder: newMessageEncoder(out),
+		streams: make(map[int]*streamQueue),
+	}
+}
+
+//Log - sends a log line to the host
+func (s *RemoteStorageProvider) Log(message string) error {
+	return s.encoder.Send(Message{Type: MsgLog, Message: message})
+}
+
+//Writer - opens a stream which writes to the given path in the host's storage
+func (s *RemoteStorageProvider) Writer(path ...string) (io.WriteCloser, error) {
+	stream, acks := s.openStream()
+	writer := &remoteWriter{provider: s, stream: stream, acks: acks}
+	return writer, s.encoder.Send(Message{Type: MsgOpenWrite, Stream: stream, Path: path})
+}
+
+//Reader - opens a stream which reads the given path from the host's storage
+func (s *RemoteStorageProvider) Reader(path ...string) (io.ReadCloser, error) {
+	stream, messages := s.openStream()
+	reader := &remoteReader{provider: s, stream: stream, messages: messages}
+	return reader, s.encoder.Send(Message{Type: MsgRead, Stream: stream, Path: path})
+}
+
+func (s *RemoteStorageProvider) openStream() (stream int, messages chan Message) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.nextStream++
+	stream = s.nextStream
+	queue := newStreamQueue()
+	s.streams[stream] = queue
+	go queue.deliver()
+	return stream, queue.messages
+}
+
+func (s *RemoteStorageProvider) closeStream(stream int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if queue, ok := s.streams[stream]; ok {
+		queue.stop()
+		delete(s.streams, stream)
+	}
+}
+
+//dispatch - hands every message from the host to its stream's queue, never waiting on a stream's reader,
+//so a stream nobody reads right now can not hold up the others
+func (s *RemoteStorageProvider) dispatch(decoder *json.Decoder) {
+	for {
+		var msg Message
+
+		if err := decoder.Decode(&msg); err != nil {
+			s.failStreams(err)
+			return
+		}
+		s.mutex.Lock()
+		queue, ok := s.streams[msg.Stream]
+		s.mutex.Unlock()
+
+		if ok {
+			queue.push(msg)
+		}
+	}
+}
+
+func (s *RemoteStorageProvider) failStreams(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for stream, queue := range s.streams {
+		queue.push(Message{Type: MsgEOF, Stream: stream, Error: fmt.Sprintf("lost connection to host: %s", err)})
+		delete(s.streams, stream)
+	}
+}
+
+func newStreamQueue() *streamQueue {
+	return &streamQueue{
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		messages: make(chan Message),
+	}
+}
+
+func (s *streamQueue) push(msg Message) {
+	s.mutex.Lock()
+	s.pending = append(s.pending, msg)
+	s.mutex.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *streamQueue) stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+//deliver - passes the queued messages on to the stream's reader in order, until the stream is closed or
+//its last message, anything but data, was delivered
+func (s *streamQueue) deliver() {
+	for {
+		s.mutex.Lock()
+		pending := s.pending
+		s.pending = nil
+		s.mutex.Unlock()
+
+		for _, msg := range pending {
+			select {
+			case s.messages <- msg:
+			case <-s.done:
+				return
+			}
+
+			if msg.Type != MsgData {
+				return
+			}
+		}
+
+		select {
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *remoteWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 && err == nil {
+		chunk := p
+
+		if len(chunk) > PluginChunkSize {
+			chunk = chunk[:PluginChunkSize]
+		}
+
+		if err = s.provider.encoder.Send(Message{Type: MsgWrite, Stream: s.stream, Data: chunk}); err == nil {
+			n += len(chunk)
+			p = p[len(chunk):]
+		}
+	}
+	return
+}
+
+func (s *remoteWriter) Close() (err error) {
+	defer s.provider.closeStream(s.stream)
+
+	if err = s.provider.encoder.Send(Message{Type: MsgClose, Stream: s.stream}); err == nil {
+		if ack := <-s.acks; ack.Error != "" {
+			err = errors.New(ack.Error)
+		}
+	}
+	return
+}
+
+func (s *remoteReader) Read(p []byte) (n int, err error) {
+	for len(s.pending) == 0 && s.err == nil {
+		msg := <-s.messages
+
+		switch {
+		case msg.Type == MsgData:
+			s.pending = msg.Data
+		case msg.Error != "":
+			s.err = errors.New(msg.Error)
+		default:
+			s.err = io.EOF
+		}
+	}
+
+	if len(s.pending) > 0 {
+		n = copy(p, s.pending)
+		s.pending = s.pending[n:]
+		return
+	}
+	return 0, s.err
+}
+
+func (s *remoteReader) Close() error {
+	s.provider.closeStream(s.stream)
+	return nil
+}
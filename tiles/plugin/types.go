@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+)
+
+type (
+	//Message - a single line of the plugin protocol, exchanged as newline delimited json over stdin/stdout
+	Message struct {
+		Type     string                       `json:"type"`
+		Action   string                       `json:"action,omitempty"`
+		TileSpec *tileregistry.TileSpec       `json:"tile_spec,omitempty"`
+		Stream   int                          `json:"stream,omitempty"`
+		Path     []string                     `json:"path,omitempty"`
+		Data     []byte                       `json:"data,omitempty"`
+		Message  string                       `json:"message,omitempty"`
+		Error    string                       `json:"error,omitempty"`
+		Report   *cfbackup.VerificationReport `json:"report,omitempty"`
+	}
+
+	//Handler - what a plugin executable implements to back up, restore and verify its tile
+	Handler interface {
+		Backup(tileSpec tileregistry.TileSpec, storageProvider cfbackup.StorageProvider) error
+		Restore(tileSpec tileregistry.TileSpec, storageProvider cfbackup.StorageProvider) error
+		Verify(tileSpec tileregistry.TileSpec, storageProvider cfbackup.StorageProvider) (cfbackup.VerificationReport, error)
+	}
+
+	//TileGenerator - a tile generator backed by an external plugin executable
+	TileGenerator struct {
+		Path string
+	}
+
+	//Tile - a tile whose backup, restore and verify are run by an external plugin executable
+	Tile struct {
+		Path     string
+		TileSpec tileregistry.TileSpec
+		cfbackup.BackupContext
+	}
+
+	//Session - the host side of a single plugin invocation
+	Session struct {
+		storageProvider cfbackup.StorageProvider
+		encoder         *messageEncoder
+		decoder         *json.Decoder
+		writers         map[int]*hostWriter
+		readers         sync.WaitGroup
+	}
+
+	//RemoteStorageProvider - the plugin side storage provider, proxying reads and writes to the host
+	RemoteStorageProvider struct {
+		encoder    *messageEncoder
+		mutex      sync.Mutex
+		nextStream int
+		streams    map[int]*streamQueue
+	}
+
+	streamQueue struct {
+		mutex    sync.Mutex
+		pending  []Message
+		wake     chan struct{}
+		done     chan struct{}
+		stopOnce sync.Once
+		messages chan Message
+	}
+
+	hostWriter struct {
+		io.WriteCloser
+		err error
+	}
+
+	remoteWriter struct {
+		provider *RemoteStorageProvider
+		stream   int
+		acks     chan Message
+	}
+
+	remoteReader struct {
+		provider *RemoteStorageProvider
+		stream   int
+		messages chan Message
+		pending  []byte
+		err      error
+	}
+
+	messageEncoder struct {
+		mutex   sync.Mutex
+		encoder *json.Encoder
+	}
+)
@@ -0,0 +1,53 @@
+package pmysql
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	//PMysqlProduct -- product identifier of the mysql service tile in installation settings
+	PMysqlProduct = "p-mysql"
+	//PMysqlJob -- job identifier of the mysql cluster nodes
+	PMysqlJob = "mysql"
+	//PMysqlAdminCredentials -- property identifier of the mysql admin credentials
+	PMysqlAdminCredentials = "mysql_admin_password"
+	//PMysqlBackupDir -- directory in the backup set holding the p-mysql archives
+	PMysqlBackupDir = "p-mysql"
+	//PMysqlArchive -- filename of the all-databases dump, which holds the service broker's database as well
+	PMysqlArchive = "mysql.backup"
+	//PMysqlRemoteArchivePath -- where the dump is uploaded on the restore node
+	PMysqlRemoteArchivePath = "/var/vcap/store/mysql/archive.backup"
+
+	//PMysqlSQLBin -- mysql client on the cluster nodes
+	PMysqlSQLBin = "/var/vcap/packages/mariadb/bin/mysql"
+	//PMysqlDumpBin -- mysqldump on the cluster nodes
+	PMysqlDumpBin = "/var/vcap/packages/mariadb/bin/mysqldump"
+	//PMysqlMonitBin -- monit on the cluster nodes
+	PMysqlMonitBin = "/var/vcap/bosh/bin/monit"
+	//PMysqlProcess -- monit process name of the galera node
+	PMysqlProcess = "mariadb_ctrl"
+	//PMysqlStateFile -- file read by mariadb_ctrl to decide whether to bootstrap a new cluster
+	PMysqlStateFile = "/var/vcap/store/mysql/state.txt"
+	//PMysqlNeedsBootstrap -- state which makes mariadb_ctrl bootstrap the cluster from this node
+	PMysqlNeedsBootstrap = "NEEDS_BOOTSTRAP"
+	//PMysqlSyncedState -- wsrep_local_state_comment of a healthy galera node
+	PMysqlSyncedState = "Synced"
+
+	//ErrNoHealthyNodeMsg -- error message for a cluster without a synced node
+	ErrNoHealthyNodeMsg = "no synced galera node found in the p-mysql cluster"
+	//ErrNodeNotSyncedMsg -- error message for a node which did not rejoin the cluster
+	ErrNodeNotSyncedMsg = "galera node did not reach the synced state"
+)
+
+var (
+	//ErrNoHealthyNode - error for a cluster without a synced node
+	ErrNoHealthyNode = errors.New(ErrNoHealthyNodeMsg)
+	//ErrNodeNotSynced - error for a node which did not rejoin the cluster
+	ErrNodeNotSynced = errors.New(ErrNodeNotSyncedMsg)
+
+	//SyncPollInterval - how long to wait between galera state checks while a node joins the cluster
+	SyncPollInterval = 10 * time.Second
+	//SyncPollAttempts - how many galera state checks to make before giving up on a node
+	SyncPollAttempts = 60
+)
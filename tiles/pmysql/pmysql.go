@@ -0,0 +1,300 @@
+package pmysql
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-community/go-cfenv"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/gtils/command"
+	"github.com/pivotalservices/gtils/log"
+	"github.com/pivotalservices/gtils/persistence"
+	"github.com/xchapter7x/lo"
+)
+
+var (
+	//NewRemoteExecuter - creates the executer used to run commands on a galera node
+	NewRemoteExecuter = command.NewRemoteExecutor
+
+	//NewMysqlDump - creates the persistence backup used to load a dump onto a galera node
+	NewMysqlDump = func(node Node) (cfbackup.PersistanceBackup, error) {
+		return persistence.NewRemoteMysqlDumpWithPath(node.AdminUser, node.AdminPass, node.SSHConfig, PMysqlRemoteArchivePath)
+	}
+)
+
+//NewPMysql - initializes a p-mysql tile from the given installation settings
+func NewPMysql(installationSettings cfbackup.InstallationInfo, target string, cryptKey string) *PMysql {
+	return &PMysql{
+		InstallationSettings: installationSettings,
+		BackupContext:        cfbackup.NewBackupContext(target, cfenv.CurrentEnv(), cryptKey),
+	}
+}
+
+// Backup dumps every database, the broker's included, from a synced galera node
+func (s *PMysql) Backup() error {
+	return s.BackupWithContext(context.Background())
+}
+
+// BackupWithContext dumps every database like Backup, stopping when ctx is done
+func (s *PMysql) BackupWithContext(ctx context.Context) (err error) {
+	var (
+		nodes []Node
+		node  Node
+	)
+
+	if nodes, err = s.Nodes(); err != nil {
+		return
+	}
+
+	if node, err = healthyNode(nodes); err != nil {
+		return
+	}
+	lo.G.Info("Exporting p-mysql from node ", node.IP)
+
+	return s.dump(ctx, node, PMysqlArchive)
+}
+
+// Restore loads the dump onto a single galera node while the rest of the cluster is stopped,
+// bootstrapping from that node when no synced node is left, then rejoins the remaining nodes
+func (s *PMysql) Restore() error {
+	return s.RestoreWithContext(context.Background())
+}
+
+// RestoreWithContext restores like Restore, stopping when ctx is done. The stopped nodes are rejoined either way
+func (s *PMysql) RestoreWithContext(ctx context.Context) (err error) {
+	var (
+		nodes        []Node
+		restoreNode  Node
+		backupReader io.ReadCloser
+		dumper       cfbackup.PersistanceBackup
+	)
+
+	if nodes, err = s.Nodes(); err != nil {
+		return
+	}
+	restoreNode, err = healthyNode(nodes)
+
+	if err == ErrNoHealthyNode {
+		restoreNode = nodes[0]
+		lo.G.Info("no synced galera node, bootstrapping the cluster from ", restoreNode.IP)
+
+		if err = restoreNode.Bootstrap(ctx); err != nil {
+			return
+		}
+	}
+
+	if err != nil {
+		return
+	}
+	others := otherNodes(nodes, restoreNode)
+	defer func() {
+		if rejoinErr := rejoin(others); err == nil {
+			err = rejoinErr
+		}
+	}()
+
+	for _, node := range others {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		if err = node.Monit("stop"); err != nil {
+			return
+		}
+	}
+
+	if backupReader, err = s.Reader(s.archivePath(PMysqlArchive)); err != nil {
+		return
+	}
+	defer backupReader.Close()
+
+	if dumper, err = NewMysqlDump(restoreNode); err == nil {
+		lo.G.Info("Restoring p-mysql onto node ", restoreNode.IP)
+		err = cfbackup.ImportWithContext(ctx, dumper, backupReader)
+	}
+	return
+}
+
+// Verify checks the p-mysql dump in the backup set is complete, without restoring it
+func (s *PMysql) Verify() (report cfbackup.VerificationReport, err error) {
+	report.Record(PMysqlArchive, cfbackup.VerifyArtifact(s, cfbackup.VerifyMysqlDump, s.archivePath(PMysqlArchive)))
+
+	if !report.Passed() {
+		lo.G.Error("p-mysql backup set failed verification", log.Data{"failures": report.Failures()})
+		err = cfbackup.ErrVerificationFailed
+	}
+	return
+}
+
+//Nodes - returns the galera nodes of the cluster with their ssh and admin credentials from the installation settings
+func (s *PMysql) Nodes() (nodes []Node, err error) {
+	var (
+		ips           []string
+		vmCredentials cfbackup.VMCredentials
+		adminMap      map[string]string
+	)
+
+	if ips, err = s.InstallationSettings.FindIPsByProductAndJob(PMysqlProduct, PMysqlJob); err != nil {
+		return
+	}
+
+	if len(ips) == 0 {
+		err = fmt.Errorf("No IPs found for %s, %s", PMysqlProduct, PMysqlJob)
+		return
+	}
+
+	if vmCredentials, err = s.InstallationSettings.FindVMCredentialsByProductAndJob(PMysqlProduct, PMysqlJob); err != nil {
+		return
+	}
+
+	if adminMap, err = s.InstallationSettings.FindPropertyValues(PMysqlProduct, PMysqlJob, PMysqlAdminCredentials); err != nil {
+		return
+	}
+
+	for _, ip := range ips {
+		node := Node{
+			IP: ip,
+			SSHConfig: command.SshConfig{
+				Username: vmCredentials.UserID,
+				Password: vmCredentials.Password,
+				Host:     ip,
+				Port:     22,
+				SSLKey:   vmCredentials.SSLKey,
+			},
+			AdminUser: adminMap["identity"],
+			AdminPass: adminMap["password"],
+		}
+
+		if node.Executer, err = NewRemoteExecuter(node.SSHConfig); err != nil {
+			return
+		}
+		nodes = append(nodes, node)
+	}
+	return
+}
+
+//State - returns the node's wsrep_local_state_comment
+func (s Node) State() (state string, err error) {
+	var output bytes.Buffer
+	query := fmt.Sprintf(`%s %s -u %s -N -B -e "SHOW STATUS LIKE 'wsrep_local_state_comment'"`, s.mysqlPassword(), PMysqlSQLBin, cfbackup.ShellQuote(s.AdminUser))
+
+	if err = s.Executer.Execute(&output, query); err == nil {
+		if fields := strings.Fields(output.String()); len(fields) > 0 {
+			state = fields[len(fields)-1]
+		}
+	}
+	return
+}
+
+//Synced - true when the node is a healthy member of the cluster
+func (s Node) Synced() bool {
+	state, err := s.State()
+	return err == nil && state == PMysqlSyncedState
+}
+
+//Monit - runs the given monit action against the galera process on the node
+func (s Node) Monit(action string) error {
+	lo.G.Debug(fmt.Sprintf("%s %s on %s", action, PMysqlProcess, s.IP))
+	return s.sudo(fmt.Sprintf("%s %s %s", PMysqlMonitBin, action, PMysqlProcess))
+}
+
+//Bootstrap - starts a new cluster from this node, giving up waiting for it to sync when ctx is done
+func (s Node) Bootstrap(ctx context.Context) (err error) {
+	if err = s.Monit("stop"); err != nil {
+		return
+	}
+
+	if err = s.sudo(fmt.Sprintf("sh -c 'echo -n %s > %s'", PMysqlNeedsBootstrap, PMysqlStateFile)); err != nil {
+		return
+	}
+
+	if err = s.Monit("start"); err == nil {
+		err = s.WaitForSync(ctx)
+	}
+	return
+}
+
+//WaitForSync - polls the node until it reports the synced state or ctx is done
+func (s Node) WaitForSync(ctx context.Context) error {
+	for attempt := 0; attempt < SyncPollAttempts; attempt++ {
+		if s.Synced() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(SyncPollInterval):
+		}
+	}
+	return ErrNodeNotSynced
+}
+
+func (s Node) sudo(cmd string) error {
+	var output bytes.Buffer
+	return s.Executer.Execute(&output, fmt.Sprintf("echo %s | sudo -S %s", cfbackup.ShellQuote(s.SSHConfig.Password), cmd))
+}
+
+//mysqlPassword - hands the admin password to mysql through the environment, shell quoted
+func (s Node) mysqlPassword() string {
+	return "MYSQL_PWD=" + cfbackup.ShellQuote(s.AdminPass)
+}
+
+//dump - runs mysqldump on the node itself rather than through the gtils mysql dump NewMysqlDump builds for the
+//restore, which can not take a --single-transaction dump and would lock the tables of a live galera node
+func (s *PMysql) dump(ctx context.Context, node Node, filename string) (err error) {
+	var backupWriter io.WriteCloser
+	dumpCommand := fmt.Sprintf("%s %s -u %s --single-transaction --all-databases", node.mysqlPassword(), PMysqlDumpBin, cfbackup.ShellQuote(node.AdminUser))
+
+	if backupWriter, err = s.Writer(s.archivePath(filename)); err == nil {
+		defer backupWriter.Close()
+		err = cfbackup.ExecuteWithContext(ctx, node.Executer, backupWriter, dumpCommand)
+		lo.G.Debug("Done backing up ", filename, err)
+	}
+	return
+}
+
+func (s *PMysql) archivePath(filename string) string {
+	return path.Join(s.TargetDir, PMysqlBackupDir, filename)
+}
+
+func healthyNode(nodes []Node) (node Node, err error) {
+	for _, node = range nodes {
+		if node.Synced() {
+			return
+		}
+		lo.G.Debug("skipping galera node which is not synced: ", node.IP)
+	}
+	return Node{}, ErrNoHealthyNode
+}
+
+func otherNodes(nodes []Node, exclude Node) (others []Node) {
+	for _, node := range nodes {
+		if node.IP != exclude.IP {
+			others = append(others, node)
+		}
+	}
+	return
+}
+
+//rejoin - starts the nodes again, which has to happen even when the restore was cancelled
+func rejoin(nodes []Node) (err error) {
+	for _, node := range nodes {
+		nodeErr := node.Monit("start")
+
+		if nodeErr == nil {
+			nodeErr = node.WaitForSync(context.Background())
+		}
+
+		if nodeErr != nil {
+			lo.G.Error("failed to rejoin galera node ", node.IP, nodeErr)
+			err = nodeErr
+		}
+	}
+	return
+}
@@ -0,0 +1,32 @@
+package pmysql
+
+import (
+	"io"
+
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	"github.com/pivotalservices/cfbackup/tiles/opsmanager"
+)
+
+//New -- builds a new p-mysql tile pre initialized from the ops manager installation settings
+func (s *PMysqlBuilder) New(tileSpec tileregistry.TileSpec) (pmysqlCloser tileregistry.TileCloser, err error) {
+	var installationSettings io.Reader
+
+	if tileSpec, err = tileSpec.ResolveSecrets(); err != nil {
+		return
+	}
+
+	if installationSettings, err = opsmanager.GetInstallationSettings(tileSpec); err == nil {
+		config := cfbackup.NewConfigurationParserFromReader(installationSettings)
+		pmysql := NewPMysql(&config.InstallationSettings, tileSpec.ArchiveDirectory, tileSpec.CryptKey)
+		tileSpec.ApplyBackupContext(&pmysql.BackupContext)
+		pmysqlCloser = struct {
+			*PMysql
+			tileregistry.Closer
+		}{
+			pmysql,
+			new(tileregistry.DoNothingCloser),
+		}
+	}
+	return
+}
@@ -0,0 +1,50 @@
+package pmysql_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup/fakes"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	. "github.com/pivotalservices/cfbackup/tiles/pmysql"
+	"github.com/pivotalservices/gtils/command"
+)
+
+var _ = Describe("PMysqlBuilder", func() {
+	Describe("given a New() method", func() {
+		fixture := fakes.NewTileBuilderFixture("../../fixtures/installation-settings-1-6-aws.json", &NewRemoteExecuter)
+		fixture.NewExecuter = func(command.SshConfig) command.Executer {
+			return new(galeraNode)
+		}
+		fakes.ItBuildsTilesFromOpsManager(fixture, new(PMysqlBuilder))
+
+		Context("when ops manager returns the installation settings", func() {
+			var pmysql *PMysql
+
+			BeforeEach(func() {
+				tile, err := new(PMysqlBuilder).New(fakes.BuilderSpec)
+				Ω(err).ShouldNot(HaveOccurred())
+				pmysql = tile.(struct {
+					*PMysql
+					tileregistry.Closer
+				}).PMysql
+			})
+
+			It("then it should archive into the tileSpec's archive directory", func() {
+				Ω(pmysql.TargetDir).Should(Equal(fakes.BuilderSpec.ArchiveDirectory))
+			})
+
+			It("then it should reach the mysql nodes with their vm and admin credentials", func() {
+				nodes, err := pmysql.Nodes()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(nodes).Should(HaveLen(1))
+				Ω(nodes[0].IP).Should(Equal("10.0.16.69"))
+				Ω(fixture.SSHConfigs).Should(HaveLen(1))
+				Ω(nodes[0].SSHConfig.Host).Should(Equal("10.0.16.69"))
+				Ω(nodes[0].SSHConfig.Username).Should(Equal("vcap"))
+				Ω(nodes[0].SSHConfig.Password).Should(Equal("acd82b401456c1f2"))
+				Ω(nodes[0].AdminUser).Should(Equal("root"))
+				Ω(nodes[0].AdminPass).Should(Equal("be1c552a2d1bd1b8994a"))
+			})
+		})
+	})
+})
@@ -0,0 +1,264 @@
+package pmysql_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/fakes"
+	. "github.com/pivotalservices/cfbackup/tiles/pmysql"
+	"github.com/pivotalservices/gtils/command"
+)
+
+type multiNodeSettings struct {
+	*cfbackup.InstallationSettings
+	ips []string
+}
+
+func (s *multiNodeSettings) FindIPsByProductAndJob(productName string, jobName string) ([]string, error) {
+	return s.ips, nil
+}
+
+func (s *multiNodeSettings) adminPass() string {
+	properties, _ := s.FindPropertyValues(PMysqlProduct, PMysqlJob, PMysqlAdminCredentials)
+	return properties["password"]
+}
+
+type galeraCluster struct {
+	sync.Mutex
+	states   map[string]string
+	commands []string
+}
+
+func (s *galeraCluster) record(ip, cmd string) {
+	s.Lock()
+	defer s.Unlock()
+	s.commands = append(s.commands, ip+": "+cmd)
+}
+
+func (s *galeraCluster) ran(ip, fragment string) bool {
+	for _, cmd := range s.commands {
+		if strings.HasPrefix(cmd, ip+": ") && strings.Contains(cmd, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+type galeraNode struct {
+	ip      string
+	cluster *galeraCluster
+}
+
+func (s *galeraNode) Execute(dest io.Writer, cmd string) (err error) {
+	s.cluster.record(s.ip, cmd)
+
+	switch {
+	case strings.Contains(cmd, "wsrep_local_state_comment"):
+		fmt.Fprintf(dest, "wsrep_local_state_comment\t%s\n", s.cluster.states[s.ip])
+	case strings.Contains(cmd, "monit stop"):
+		s.cluster.states[s.ip] = ""
+	case strings.Contains(cmd, "monit start"):
+		s.cluster.states[s.ip] = PMysqlSyncedState
+	case strings.Contains(cmd, PMysqlDumpBin):
+		io.WriteString(dest, fakes.MysqlDumpContents)
+	}
+	return
+}
+
+type mockImporter struct {
+	node     Node
+	imported string
+}
+
+func (s *mockImporter) Dump(io.Writer) error {
+	return nil
+}
+
+func (s *mockImporter) Import(src io.Reader) error {
+	contents, _ := ioutil.ReadAll(src)
+	s.imported = string(contents)
+	return nil
+}
+
+var _ = Describe("PMysql", func() {
+	var (
+		tile                      *PMysql
+		dir                       string
+		cluster                   *galeraCluster
+		importer                  *mockImporter
+		controlIPs                = []string{"10.0.16.69", "10.0.16.70", "10.0.16.71"}
+		originalNewRemoteExecuter = NewRemoteExecuter
+		originalNewMysqlDump      = NewMysqlDump
+		originalSyncPollInterval  = SyncPollInterval
+	)
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "pmysql")
+		config := cfbackup.NewConfigurationParser("../../fixtures/installation-settings-1-6-aws.json")
+		tile = NewPMysql(&multiNodeSettings{InstallationSettings: &config.InstallationSettings, ips: controlIPs}, dir, "")
+		cluster = &galeraCluster{states: map[string]string{
+			"10.0.16.69": "Joining",
+			"10.0.16.70": PMysqlSyncedState,
+			"10.0.16.71": PMysqlSyncedState,
+		}}
+		importer = new(mockImporter)
+		SyncPollInterval = 0
+		NewRemoteExecuter = func(config command.SshConfig) (command.Executer, error) {
+			return &galeraNode{ip: config.Host, cluster: cluster}, nil
+		}
+		NewMysqlDump = func(node Node) (cfbackup.PersistanceBackup, error) {
+			importer.node = node
+			return importer, nil
+		}
+	})
+
+	AfterEach(func() {
+		NewRemoteExecuter = originalNewRemoteExecuter
+		NewMysqlDump = originalNewMysqlDump
+		SyncPollInterval = originalSyncPollInterval
+		os.RemoveAll(dir)
+	})
+
+	Describe("given a Nodes() method", func() {
+		It("then it should return a node per ip with the admin and vm credentials", func() {
+			nodes, err := tile.Nodes()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(nodes).Should(HaveLen(3))
+			Ω(nodes[0].AdminUser).Should(Equal("root"))
+			Ω(nodes[0].SSHConfig.Username).Should(Equal("vcap"))
+			Ω(nodes[2].SSHConfig.Host).Should(Equal("10.0.16.71"))
+		})
+	})
+
+	Describe("given a Backup() method", func() {
+		Context("when the cluster has a synced node", func() {
+			var err error
+
+			BeforeEach(func() {
+				err = tile.Backup()
+			})
+
+			It("then it should dump from the first synced node", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(cluster.ran("10.0.16.70", PMysqlDumpBin)).Should(BeTrue())
+				Ω(cluster.ran("10.0.16.69", PMysqlDumpBin)).Should(BeFalse())
+			})
+
+			It("then it should take a consistent dump of every database", func() {
+				Ω(cluster.ran("10.0.16.70", "--single-transaction --all-databases")).Should(BeTrue())
+				contents, _ := ioutil.ReadFile(path.Join(dir, PMysqlBackupDir, PMysqlArchive))
+				Ω(string(contents)).Should(Equal(fakes.MysqlDumpContents))
+			})
+
+			It("then it should hand mysqldump the quoted password through the environment", func() {
+				Ω(cluster.ran("10.0.16.70", "MYSQL_PWD='"+tile.InstallationSettings.(*multiNodeSettings).adminPass()+"' "+PMysqlDumpBin)).Should(BeTrue())
+				Ω(cluster.ran("10.0.16.70", "--password")).Should(BeFalse())
+			})
+
+			It("then it should produce a backup set which passes verification", func() {
+				report, verifyErr := tile.Verify()
+				Ω(verifyErr).ShouldNot(HaveOccurred())
+				Ω(report.Artifacts).Should(HaveLen(1))
+			})
+		})
+
+		Context("when no node is synced", func() {
+			It("then it should return an error", func() {
+				for ip := range cluster.states {
+					cluster.states[ip] = "Donor/Desynced"
+				}
+				Ω(tile.Backup()).Should(Equal(ErrNoHealthyNode))
+			})
+		})
+	})
+
+	Describe("given a Restore() method", func() {
+		BeforeEach(func() {
+			os.MkdirAll(path.Join(dir, PMysqlBackupDir), 0755)
+			ioutil.WriteFile(path.Join(dir, PMysqlBackupDir, PMysqlArchive), []byte(fakes.MysqlDumpContents), 0644)
+		})
+
+		Context("when the cluster has a synced node", func() {
+			var err error
+
+			BeforeEach(func() {
+				err = tile.Restore()
+			})
+
+			It("then it should load the dump onto the synced node only", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(importer.node.IP).Should(Equal("10.0.16.70"))
+				Ω(importer.imported).Should(Equal(fakes.MysqlDumpContents))
+			})
+
+			It("then it should stop and rejoin the other nodes", func() {
+				for _, ip := range []string{"10.0.16.69", "10.0.16.71"} {
+					Ω(cluster.ran(ip, "monit stop "+PMysqlProcess)).Should(BeTrue())
+					Ω(cluster.ran(ip, "monit start "+PMysqlProcess)).Should(BeTrue())
+					Ω(cluster.states[ip]).Should(Equal(PMysqlSyncedState))
+				}
+				Ω(cluster.ran("10.0.16.70", "monit stop")).Should(BeFalse())
+			})
+		})
+
+		Context("when no node is synced", func() {
+			var err error
+
+			BeforeEach(func() {
+				for ip := range cluster.states {
+					cluster.states[ip] = ""
+				}
+				err = tile.Restore()
+			})
+
+			It("then it should bootstrap the cluster from the first node", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(cluster.ran("10.0.16.69", PMysqlNeedsBootstrap)).Should(BeTrue())
+				Ω(importer.node.IP).Should(Equal("10.0.16.69"))
+			})
+		})
+
+		Context("when another node cannot be stopped", func() {
+			var err error
+
+			BeforeEach(func() {
+				NewRemoteExecuter = func(config command.SshConfig) (command.Executer, error) {
+					if config.Host == "10.0.16.71" {
+						return new(fakes.FailExecuter), nil
+					}
+					return &galeraNode{ip: config.Host, cluster: cluster}, nil
+				}
+				err = tile.Restore()
+			})
+
+			It("then it should return an error without loading the dump", func() {
+				Ω(err).Should(HaveOccurred())
+				Ω(importer.imported).Should(BeEmpty())
+			})
+
+			It("then it should rejoin the nodes it stopped", func() {
+				Ω(cluster.ran("10.0.16.69", "monit start "+PMysqlProcess)).Should(BeTrue())
+			})
+		})
+	})
+
+	Describe("given a Verify() method", func() {
+		Context("when the backup set holds a truncated dump", func() {
+			It("then it should fail verification", func() {
+				os.MkdirAll(path.Join(dir, PMysqlBackupDir), 0755)
+				ioutil.WriteFile(path.Join(dir, PMysqlBackupDir, PMysqlArchive), []byte("-- MySQL dump"), 0644)
+				report, err := tile.Verify()
+				Ω(err).Should(Equal(cfbackup.ErrVerificationFailed))
+				Ω(report.Failures()).Should(HaveLen(1))
+			})
+		})
+	})
+})
@@ -0,0 +1,26 @@
+package pmysql
+
+import (
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/gtils/command"
+)
+
+type (
+	//PMysql - the mysql for pcf service tile
+	PMysql struct {
+		cfbackup.BackupContext
+		InstallationSettings cfbackup.InstallationInfo
+	}
+
+	//Node - a single galera node in the p-mysql cluster
+	Node struct {
+		IP        string
+		SSHConfig command.SshConfig
+		AdminUser string
+		AdminPass string
+		Executer  command.Executer
+	}
+
+	//PMysqlBuilder -- an object that can build a p-mysql tile pre-initialized
+	PMysqlBuilder struct{}
+)
@@ -0,0 +1,37 @@
+package prabbitmq
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	//PRabbitMQProduct -- product identifier of the rabbitmq service tile in installation settings
+	PRabbitMQProduct = "p-rabbitmq"
+	//PRabbitMQJob -- job identifier of the rabbitmq server nodes
+	PRabbitMQJob = "rabbitmq-server"
+	//PRabbitMQAdminCredentials -- property identifier of the management api admin credentials
+	PRabbitMQAdminCredentials = "server_admin_credentials"
+	//PRabbitMQBackupDir -- directory in the backup set holding the p-rabbitmq archives
+	PRabbitMQBackupDir = "p-rabbitmq"
+	//PRabbitMQDefinitionsArchive -- filename of the exported definitions
+	PRabbitMQDefinitionsArchive = "definitions.json"
+	//PRabbitMQManagementPort -- port of the management http api on the server nodes
+	PRabbitMQManagementPort = 15672
+	//PRabbitMQManagementURL -- base url format of the management api for a node ip and port
+	PRabbitMQManagementURL = "http://%s:%d/api"
+	//PRabbitMQRequestTimeout -- how long a single management api request may take
+	PRabbitMQRequestTimeout = 5 * time.Minute
+
+	//ErrNoManagementAPIMsg -- error message for a cluster where no management api answered
+	ErrNoManagementAPIMsg = "no rabbitmq management api could be reached"
+	//ErrInvalidDefinitionsMsg -- error message for an archive which is not a definitions export
+	ErrInvalidDefinitionsMsg = "archive is not a rabbitmq definitions export"
+)
+
+var (
+	//ErrNoManagementAPI - error for a cluster where no management api answered
+	ErrNoManagementAPI = errors.New(ErrNoManagementAPIMsg)
+	//ErrInvalidDefinitions - error for an archive which is not a definitions export
+	ErrInvalidDefinitions = errors.New(ErrInvalidDefinitionsMsg)
+)
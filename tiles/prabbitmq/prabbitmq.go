@@ -0,0 +1,352 @@
+package prabbitmq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/cloudfoundry-community/go-cfenv"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/gtils/log"
+	"github.com/xchapter7x/lo"
+)
+
+//NewPRabbitMQ - initializes a p-rabbitmq tile from the given installation settings
+func NewPRabbitMQ(installationSettings cfbackup.InstallationInfo, target string, cryptKey string, merge bool) *PRabbitMQ {
+	return &PRabbitMQ{
+		InstallationSettings: installationSettings,
+		ManagementPort:       PRabbitMQManagementPort,
+		Merge:                merge,
+		HTTPClient:           &http.Client{Timeout: PRabbitMQRequestTimeout},
+		BackupContext:        cfbackup.NewBackupContext(target, cfenv.CurrentEnv(), cryptKey),
+	}
+}
+
+// Backup exports the broker definitions (vhosts, users, permissions, policies, exchanges, queues and bindings) into the backup set
+func (s *PRabbitMQ) Backup() error {
+	return s.BackupWithContext(context.Background())
+}
+
+// BackupWithContext exports the broker definitions like Backup, abandoning the management api requests once ctx is done
+func (s *PRabbitMQ) BackupWithContext(ctx context.Context) (err error) {
+	var (
+		api          *managementClient
+		definitions  []byte
+		backupWriter io.WriteCloser
+	)
+
+	if api, err = s.connect(ctx); err != nil {
+		return
+	}
+
+	if definitions, err = api.getDefinitions(); err != nil {
+		return
+	}
+
+	if err = VerifyDefinitions(bytes.NewReader(definitions)); err != nil {
+		return
+	}
+
+	if backupWriter, err = s.Writer(s.archivePath()); err == nil {
+		defer backupWriter.Close()
+		_, err = backupWriter.Write(definitions)
+		lo.G.Debug("Done exporting rabbitmq definitions from ", api.baseURL, err)
+	}
+	return
+}
+
+// Restore imports the definitions from the backup set. Unless merging, anything on the
+// broker which is not in the backup is removed first so the topology matches the backup.
+func (s *PRabbitMQ) Restore() error {
+	return s.RestoreWithContext(context.Background())
+}
+
+// RestoreWithContext imports the definitions like Restore, abandoning the management api requests once ctx is done
+func (s *PRabbitMQ) RestoreWithContext(ctx context.Context) (err error) {
+	var (
+		api          *managementClient
+		backupReader io.ReadCloser
+		definitions  []byte
+	)
+
+	if backupReader, err = s.Reader(s.archivePath()); err != nil {
+		return
+	}
+	defer backupReader.Close()
+
+	if definitions, err = ioutil.ReadAll(backupReader); err != nil {
+		return
+	}
+
+	if api, err = s.connect(ctx); err != nil {
+		return
+	}
+
+	if !s.Merge {
+		var current []byte
+
+		if current, err = api.getDefinitions(); err == nil {
+			err = api.removeStale(current, definitions)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+	lo.G.Info("Importing rabbitmq definitions into ", api.baseURL)
+	return api.do("POST", "/definitions", bytes.NewReader(definitions), nil)
+}
+
+// Verify checks the definitions export in the backup set can be read back, without restoring it
+func (s *PRabbitMQ) Verify() (report cfbackup.VerificationReport, err error) {
+	report.Record(PRabbitMQDefinitionsArchive, cfbackup.VerifyArtifact(s, VerifyDefinitions, s.archivePath()))
+
+	if !report.Passed() {
+		lo.G.Error("p-rabbitmq backup set failed verification", log.Data{"failures": report.Failures()})
+		err = cfbackup.ErrVerificationFailed
+	}
+	return
+}
+
+//VerifyDefinitions - checks that an artifact is a rabbitmq definitions export
+func VerifyDefinitions(src io.Reader) (err error) {
+	var definitions Definitions
+
+	if err = json.NewDecoder(src).Decode(&definitions); err == nil && definitions.RabbitVersion == "" {
+		err = ErrInvalidDefinitions
+	}
+	return
+}
+
+//connect - returns a client, bound to ctx, for the first rabbitmq server node whose management api answers
+func (s *PRabbitMQ) connect(ctx context.Context) (api *managementClient, err error) {
+	var (
+		ips      []string
+		adminMap map[string]string
+	)
+
+	if ips, err = s.InstallationSettings.FindIPsByProductAndJob(PRabbitMQProduct, PRabbitMQJob); err != nil {
+		return
+	}
+
+	if adminMap, err = s.InstallationSettings.FindPropertyValues(PRabbitMQProduct, PRabbitMQJob, PRabbitMQAdminCredentials); err != nil {
+		return
+	}
+
+	for _, ip := range ips {
+		api = &managementClient{
+			baseURL:  fmt.Sprintf(PRabbitMQManagementURL, ip, s.ManagementPort),
+			username: adminMap["identity"],
+			password: adminMap["password"],
+			client:   s.HTTPClient,
+			ctx:      ctx,
+		}
+
+		if err = api.do("GET", "/overview", nil, nil); err == nil {
+			return
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		lo.G.Debug("rabbitmq management api not available on ", ip, err)
+	}
+	return nil, ErrNoManagementAPI
+}
+
+func (s *PRabbitMQ) archivePath() string {
+	return path.Join(s.TargetDir, PRabbitMQBackupDir, PRabbitMQDefinitionsArchive)
+}
+
+func (s *managementClient) getDefinitions() (definitions []byte, err error) {
+	var buffer bytes.Buffer
+
+	if err = s.do("GET", "/definitions", nil, &buffer); err == nil {
+		definitions = buffer.Bytes()
+	}
+	return
+}
+
+func (s *managementClient) removeStale(current []byte, backup []byte) (err error) {
+	var currentDefs, backupDefs Definitions
+
+	if err = json.Unmarshal(current, &currentDefs); err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(backup, &backupDefs); err != nil {
+		return
+	}
+	keep := make(map[string]bool)
+	removedVhosts := make(map[string]bool)
+
+	for _, vhost := range backupDefs.Vhosts {
+		keep["vhost:"+vhost.Name] = true
+	}
+
+	for _, user := range backupDefs.Users {
+		keep["user:"+user.Name] = true
+	}
+
+	for _, permission := range backupDefs.Permissions {
+		keep["permission:"+permission.Vhost+"/"+permission.User] = true
+	}
+
+	for _, policy := range backupDefs.Policies {
+		keep["policy:"+policy.Vhost+"/"+policy.Name] = true
+	}
+
+	for _, queue := range backupDefs.Queues {
+		keep["queue:"+queue.Vhost+"/"+queue.Name] = true
+	}
+
+	for _, exchange := range backupDefs.Exchanges {
+		keep["exchange:"+exchange.Vhost+"/"+exchange.Name] = true
+	}
+
+	for _, binding := range backupDefs.Bindings {
+		keep[binding.key()] = true
+	}
+	var (
+		stale            []string
+		currentBindings  []Binding
+		removedQueues    = make(map[string]bool)
+		removedExchanges = make(map[string]bool)
+	)
+
+	if currentBindings, err = s.getBindings(); err != nil {
+		return
+	}
+
+	for _, vhost := range currentDefs.Vhosts {
+		if !keep["vhost:"+vhost.Name] {
+			removedVhosts[vhost.Name] = true
+			stale = append(stale, "/vhosts/"+escape(vhost.Name))
+		}
+	}
+
+	for _, user := range currentDefs.Users {
+		if !keep["user:"+user.Name] && user.Name != s.username {
+			stale = append(stale, "/users/"+escape(user.Name))
+		}
+	}
+
+	for _, permission := range currentDefs.Permissions {
+		if !keep["permission:"+permission.Vhost+"/"+permission.User] && !removedVhosts[permission.Vhost] && permission.User != s.username {
+			stale = append(stale, "/permissions/"+escape(permission.Vhost)+"/"+escape(permission.User))
+		}
+	}
+
+	for _, policy := range currentDefs.Policies {
+		if !keep["policy:"+policy.Vhost+"/"+policy.Name] && !removedVhosts[policy.Vhost] {
+			stale = append(stale, "/policies/"+escape(policy.Vhost)+"/"+escape(policy.Name))
+		}
+	}
+
+	for _, queue := range currentDefs.Queues {
+		if !keep["queue:"+queue.Vhost+"/"+queue.Name] && !removedVhosts[queue.Vhost] {
+			removedQueues[queue.Vhost+"/"+queue.Name] = true
+			stale = append(stale, "/queues/"+escape(queue.Vhost)+"/"+escape(queue.Name))
+		}
+	}
+
+	for _, exchange := range currentDefs.Exchanges {
+		if !keep["exchange:"+exchange.Vhost+"/"+exchange.Name] && !removedVhosts[exchange.Vhost] && !isBuiltinExchange(exchange.Name) {
+			removedExchanges[exchange.Vhost+"/"+exchange.Name] = true
+			stale = append(stale, "/exchanges/"+escape(exchange.Vhost)+"/"+escape(exchange.Name))
+		}
+	}
+
+	for _, binding := range currentBindings {
+		removedDestinations := removedQueues
+
+		if binding.DestinationType == "exchange" {
+			removedDestinations = removedExchanges
+		}
+
+		if binding.Source == "" || keep[binding.key()] || removedVhosts[binding.Vhost] || removedExchanges[binding.Vhost+"/"+binding.Source] || removedDestinations[binding.Vhost+"/"+binding.Destination] {
+			continue
+		}
+		stale = append(stale, binding.resource())
+	}
+
+	for _, resource := range stale {
+		lo.G.Debug("removing rabbitmq definition which is not in the backup: ", resource)
+
+		if err = s.do("DELETE", resource, nil, nil); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (s *managementClient) do(method string, resource string, body io.Reader, dest io.Writer) (err error) {
+	var (
+		req  *http.Request
+		resp *http.Response
+	)
+
+	if req, err = http.NewRequestWithContext(s.ctx, method, s.baseURL+resource, body); err != nil {
+		return
+	}
+	req.SetBasicAuth(s.username, s.password)
+	req.Header.Set("Content-Type", "application/json")
+
+	if resp, err = s.client.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("rabbitmq management api %s %s failed with %s: %s", method, resource, resp.Status, message)
+	}
+
+	if dest != nil {
+		_, err = io.Copy(dest, resp.Body)
+	}
+	return
+}
+
+//getBindings - the broker's bindings, which unlike the definitions export carry the properties key they are deleted by
+func (s *managementClient) getBindings() (bindings []Binding, err error) {
+	var buffer bytes.Buffer
+
+	if err = s.do("GET", "/bindings", nil, &buffer); err == nil {
+		err = json.Unmarshal(buffer.Bytes(), &bindings)
+	}
+	return
+}
+
+func (s Binding) key() string {
+	arguments, _ := json.Marshal(s.Arguments)
+
+	if len(s.Arguments) == 0 {
+		arguments = []byte("{}")
+	}
+	return "binding:" + strings.Join([]string{s.Vhost, s.Source, s.DestinationType, s.Destination, s.RoutingKey, string(arguments)}, "/")
+}
+
+func (s Binding) resource() string {
+	destinationType := "q"
+
+	if s.DestinationType == "exchange" {
+		destinationType = "e"
+	}
+	return "/bindings/" + escape(s.Vhost) + "/e/" + escape(s.Source) + "/" + destinationType + "/" + escape(s.Destination) + "/" + escape(s.PropertiesKey)
+}
+
+func escape(name string) string {
+	return strings.Replace(url.QueryEscape(name), "+", "%20", -1)
+}
+
+func isBuiltinExchange(name string) bool {
+	return name == "" || strings.HasPrefix(name, "amq.")
+}
@@ -0,0 +1,32 @@
+package prabbitmq
+
+import (
+	"io"
+
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	"github.com/pivotalservices/cfbackup/tiles/opsmanager"
+)
+
+//New -- builds a new p-rabbitmq tile pre initialized from the ops manager installation settings
+func (s *PRabbitMQBuilder) New(tileSpec tileregistry.TileSpec) (prabbitmqCloser tileregistry.TileCloser, err error) {
+	var installationSettings io.Reader
+
+	if tileSpec, err = tileSpec.ResolveSecrets(); err != nil {
+		return
+	}
+
+	if installationSettings, err = opsmanager.GetInstallationSettings(tileSpec); err == nil {
+		config := cfbackup.NewConfigurationParserFromReader(installationSettings)
+		prabbitmq := NewPRabbitMQ(&config.InstallationSettings, tileSpec.ArchiveDirectory, tileSpec.CryptKey, tileSpec.MergeOnRestore)
+		tileSpec.ApplyBackupContext(&prabbitmq.BackupContext)
+		prabbitmqCloser = struct {
+			*PRabbitMQ
+			tileregistry.Closer
+		}{
+			prabbitmq,
+			new(tileregistry.DoNothingCloser),
+		}
+	}
+	return
+}
@@ -0,0 +1,66 @@
+package prabbitmq_test
+
+import (
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup/fakes"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	. "github.com/pivotalservices/cfbackup/tiles/prabbitmq"
+)
+
+type unreachableNodes struct {
+	requests []*http.Request
+}
+
+func (s *unreachableNodes) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.requests = append(s.requests, req)
+	return nil, errors.New("connection refused")
+}
+
+var _ = Describe("PRabbitMQBuilder", func() {
+	Describe("given a New() method", func() {
+		fixture := fakes.NewTileBuilderFixture("../../fixtures/installation-settings-1-6-aws.json", nil)
+		fakes.ItBuildsTilesFromOpsManager(fixture, new(PRabbitMQBuilder))
+
+		Context("when ops manager returns the installation settings", func() {
+			var (
+				prabbitmq *PRabbitMQ
+				nodes     *unreachableNodes
+			)
+
+			BeforeEach(func() {
+				spec := fakes.BuilderSpec
+				spec.MergeOnRestore = true
+				tile, err := new(PRabbitMQBuilder).New(spec)
+				Ω(err).ShouldNot(HaveOccurred())
+				prabbitmq = tile.(struct {
+					*PRabbitMQ
+					tileregistry.Closer
+				}).PRabbitMQ
+				nodes = new(unreachableNodes)
+				prabbitmq.HTTPClient = &http.Client{Transport: nodes}
+			})
+
+			It("then it should archive into the tileSpec's archive directory and honour the merge setting", func() {
+				Ω(prabbitmq.TargetDir).Should(Equal(fakes.BuilderSpec.ArchiveDirectory))
+				Ω(prabbitmq.Merge).Should(BeTrue())
+				Ω(prabbitmq.ManagementPort).Should(Equal(PRabbitMQManagementPort))
+			})
+
+			It("then it should try the management api of every rabbitmq server with the admin credentials", func() {
+				Ω(prabbitmq.Backup()).Should(MatchError(ErrNoManagementAPI))
+				Ω(nodes.requests).Should(HaveLen(2))
+
+				for i, host := range []string{"10.0.16.17:15672", "10.0.16.25:15672"} {
+					username, password, _ := nodes.requests[i].BasicAuth()
+					Ω(nodes.requests[i].URL.Host).Should(Equal(host))
+					Ω(username).Should(Equal("srao"))
+					Ω(password).Should(Equal("srao"))
+				}
+			})
+		})
+	})
+})
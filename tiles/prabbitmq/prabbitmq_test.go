@@ -0,0 +1,252 @@
+package prabbitmq_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
+	. "github.com/pivotalservices/cfbackup/tiles/prabbitmq"
+)
+
+const (
+	currentDefinitions = `{
+		"rabbit_version": "3.5.6",
+		"users": [{"name": "srao"}, {"name": "stale-user"}, {"name": "app-user"}],
+		"vhosts": [{"name": "/"}, {"name": "stale vhost"}],
+		"permissions": [{"user": "app-user", "vhost": "/"}, {"user": "stale-user", "vhost": "/"}],
+		"policies": [{"name": "stale-policy", "vhost": "/"}, {"name": "stale-vhost-policy", "vhost": "stale vhost"}],
+		"queues": [{"name": "orders", "vhost": "/"}, {"name": "stale-queue", "vhost": "/"}],
+		"exchanges": [{"name": "amq.direct", "vhost": "/"}, {"name": "stale-exchange", "vhost": "/"}]
+	}`
+	backupDefinitions = `{
+		"rabbit_version": "3.5.6",
+		"users": [{"name": "app-user"}],
+		"vhosts": [{"name": "/"}],
+		"permissions": [{"user": "app-user", "vhost": "/"}],
+		"policies": [],
+		"queues": [{"name": "orders", "vhost": "/"}],
+		"exchanges": [],
+		"bindings": [{"source": "amq.direct", "vhost": "/", "destination": "orders", "destination_type": "queue", "routing_key": "orders", "arguments": {}}]
+	}`
+	currentBindings = `[
+		{"source": "", "vhost": "/", "destination": "orders", "destination_type": "queue", "routing_key": "orders", "arguments": {}, "properties_key": "orders"},
+		{"source": "amq.direct", "vhost": "/", "destination": "orders", "destination_type": "queue", "routing_key": "orders", "arguments": {}, "properties_key": "orders"},
+		{"source": "amq.direct", "vhost": "/", "destination": "orders", "destination_type": "queue", "routing_key": "legacy orders", "arguments": {}, "properties_key": "legacy orders"},
+		{"source": "amq.headers", "vhost": "/", "destination": "amq.direct", "destination_type": "exchange", "routing_key": "", "arguments": {"x-match": "all"}, "properties_key": "~VW0O3A"},
+		{"source": "amq.direct", "vhost": "/", "destination": "stale-queue", "destination_type": "queue", "routing_key": "stale", "arguments": {}, "properties_key": "stale"},
+		{"source": "amq.direct", "vhost": "stale vhost", "destination": "jobs", "destination_type": "queue", "routing_key": "jobs", "arguments": {}, "properties_key": "jobs"}
+	]`
+)
+
+type managementAPI struct {
+	definitions string
+	bindings    string
+	imported    string
+	deleted     []string
+	username    string
+	password    string
+}
+
+func (s *managementAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if username, password, ok := r.BasicAuth(); !ok || username != s.username || password != s.password {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/api/overview":
+		w.Write([]byte(`{"rabbitmq_version": "3.5.6"}`))
+	case r.Method == "GET" && r.URL.Path == "/api/definitions":
+		w.Write([]byte(s.definitions))
+	case r.Method == "GET" && r.URL.Path == "/api/bindings":
+		w.Write([]byte(s.bindings))
+	case r.Method == "POST" && r.URL.Path == "/api/definitions":
+		body, _ := ioutil.ReadAll(r.Body)
+		s.imported = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "DELETE":
+		s.deleted = append(s.deleted, r.URL.EscapedPath())
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+type localSettings struct {
+	*cfbackup.InstallationSettings
+}
+
+func (s *localSettings) FindIPsByProductAndJob(productName string, jobName string) ([]string, error) {
+	return []string{"127.0.0.1"}, nil
+}
+
+var _ = Describe("PRabbitMQ", func() {
+	var (
+		tile   *PRabbitMQ
+		dir    string
+		api    *managementAPI
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "prabbitmq")
+		config := cfbackup.NewConfigurationParser("../../fixtures/installation-settings-1-6-aws.json")
+		adminMap, _ := config.InstallationSettings.FindPropertyValues(PRabbitMQProduct, PRabbitMQJob, PRabbitMQAdminCredentials)
+		api = &managementAPI{
+			definitions: currentDefinitions,
+			bindings:    currentBindings,
+			username:    adminMap["identity"],
+			password:    adminMap["password"],
+		}
+		server = httptest.NewServer(api)
+		_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+		tile = NewPRabbitMQ(&localSettings{&config.InstallationSettings}, dir, "", false)
+		tile.ManagementPort, _ = strconv.Atoi(port)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	Describe("given a Backup() method", func() {
+		Context("when the management api is available", func() {
+			It("then it should export the definitions into the backup set", func() {
+				Ω(tile.Backup()).Should(Succeed())
+				contents, err := ioutil.ReadFile(path.Join(dir, PRabbitMQBackupDir, PRabbitMQDefinitionsArchive))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(string(contents)).Should(Equal(currentDefinitions))
+			})
+
+			It("then it should produce a backup set which passes verification", func() {
+				tile.Backup()
+				report, err := tile.Verify()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(report.Passed()).Should(BeTrue())
+			})
+		})
+
+		Context("when the admin credentials are rejected", func() {
+			It("then it should return an error", func() {
+				api.password = "rotated"
+				Ω(tile.Backup()).Should(Equal(ErrNoManagementAPI))
+			})
+		})
+
+		Context("when the management api does not return definitions", func() {
+			It("then it should return an error", func() {
+				api.definitions = `{"error": "not_authorised"}`
+				Ω(tile.Backup()).Should(Equal(ErrInvalidDefinitions))
+			})
+		})
+	})
+
+	Describe("given a BackupWithContext() method", func() {
+		Context("when the context is cancelled", func() {
+			It("then it should return the context error without trying the other nodes", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				Ω(tile.BackupWithContext(ctx)).Should(Equal(context.Canceled))
+				_, err := os.Stat(path.Join(dir, PRabbitMQBackupDir, PRabbitMQDefinitionsArchive))
+				Ω(os.IsNotExist(err)).Should(BeTrue())
+			})
+		})
+	})
+
+	Describe("given a RestoreWithContext() method", func() {
+		Context("when the context is cancelled", func() {
+			It("then it should return the context error without importing", func() {
+				os.MkdirAll(path.Join(dir, PRabbitMQBackupDir), 0755)
+				ioutil.WriteFile(path.Join(dir, PRabbitMQBackupDir, PRabbitMQDefinitionsArchive), []byte(backupDefinitions), 0644)
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				Ω(tile.RestoreWithContext(ctx)).Should(Equal(context.Canceled))
+				Ω(api.imported).Should(BeEmpty())
+				Ω(api.deleted).Should(BeEmpty())
+			})
+		})
+	})
+
+	Describe("given a Restore() method", func() {
+		BeforeEach(func() {
+			os.MkdirAll(path.Join(dir, PRabbitMQBackupDir), 0755)
+			ioutil.WriteFile(path.Join(dir, PRabbitMQBackupDir, PRabbitMQDefinitionsArchive), []byte(backupDefinitions), 0644)
+		})
+
+		Context("when replacing the broker definitions", func() {
+			BeforeEach(func() {
+				Ω(tile.Restore()).Should(Succeed())
+			})
+
+			It("then it should import the definitions from the backup set", func() {
+				Ω(api.imported).Should(Equal(backupDefinitions))
+			})
+
+			It("then it should remove definitions which are not in the backup", func() {
+				Ω(api.deleted).Should(ConsistOf(
+					"/api/vhosts/stale%20vhost",
+					"/api/users/stale-user",
+					"/api/permissions/%2F/stale-user",
+					"/api/policies/%2F/stale-policy",
+					"/api/queues/%2F/stale-queue",
+					"/api/exchanges/%2F/stale-exchange",
+					"/api/bindings/%2F/e/amq.direct/q/orders/legacy%20orders",
+					"/api/bindings/%2F/e/amq.headers/e/amq.direct/~VW0O3A",
+				))
+			})
+
+			It("then it should keep the bindings in the backup and those removed along with their queue or vhost", func() {
+				Ω(api.deleted).ShouldNot(ContainElement("/api/bindings/%2F/e/amq.direct/q/orders/orders"))
+				Ω(api.deleted).ShouldNot(ContainElement(ContainSubstring("stale-queue/stale")))
+				Ω(api.deleted).ShouldNot(ContainElement(ContainSubstring("/api/bindings/stale%20vhost")))
+			})
+
+			It("then it should keep the admin user and built in exchanges", func() {
+				Ω(api.deleted).ShouldNot(ContainElement("/api/users/" + api.username))
+				Ω(api.deleted).ShouldNot(ContainElement("/api/exchanges/%2F/amq.direct"))
+			})
+		})
+
+		Context("when merging into the broker definitions", func() {
+			It("then it should import the definitions without removing anything", func() {
+				tile.Merge = true
+				Ω(tile.Restore()).Should(Succeed())
+				Ω(api.imported).Should(Equal(backupDefinitions))
+				Ω(api.deleted).Should(BeEmpty())
+			})
+		})
+
+		Context("when the broker bindings can not be listed", func() {
+			It("then it should return an error without importing", func() {
+				api.bindings = "not json"
+				Ω(tile.Restore()).ShouldNot(Succeed())
+				Ω(api.imported).Should(BeEmpty())
+			})
+		})
+
+		Context("when the backup set has no definitions", func() {
+			It("then it should return an error", func() {
+				os.RemoveAll(path.Join(dir, PRabbitMQBackupDir))
+				Ω(tile.Restore()).ShouldNot(Succeed())
+				Ω(api.imported).Should(BeEmpty())
+			})
+		})
+	})
+
+	Describe("given a VerifyDefinitions() method", func() {
+		It("then it should reject archives which are not definitions exports", func() {
+			Ω(VerifyDefinitions(strings.NewReader("not json"))).ShouldNot(Succeed())
+			Ω(VerifyDefinitions(strings.NewReader(`{"users": []}`))).Should(Equal(ErrInvalidDefinitions))
+			Ω(VerifyDefinitions(strings.NewReader(backupDefinitions))).Should(Succeed())
+		})
+	})
+})
@@ -0,0 +1,87 @@
+package prabbitmq
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pivotalservices/cfbackup"
+)
+
+type (
+	//PRabbitMQ - the rabbitmq service tile, whose topology is backed up through the management api
+	PRabbitMQ struct {
+		cfbackup.BackupContext
+		InstallationSettings cfbackup.InstallationInfo
+		ManagementPort       int
+		Merge                bool
+		HTTPClient           *http.Client
+	}
+
+	//PRabbitMQBuilder -- an object that can build a p-rabbitmq tile pre-initialized
+	PRabbitMQBuilder struct{}
+
+	//Definitions - the parts of a definitions export which a replacing restore reconciles
+	Definitions struct {
+		RabbitVersion string       `json:"rabbit_version"`
+		Users         []User       `json:"users"`
+		Vhosts        []Vhost      `json:"vhosts"`
+		Permissions   []Permission `json:"permissions"`
+		Policies      []Policy     `json:"policies"`
+		Queues        []Queue      `json:"queues"`
+		Exchanges     []Exchange   `json:"exchanges"`
+		Bindings      []Binding    `json:"bindings"`
+	}
+
+	//User - a user in a definitions export
+	User struct {
+		Name string `json:"name"`
+	}
+
+	//Vhost - a vhost in a definitions export
+	Vhost struct {
+		Name string `json:"name"`
+	}
+
+	//Permission - a user's permissions on a vhost in a definitions export
+	Permission struct {
+		User  string `json:"user"`
+		Vhost string `json:"vhost"`
+	}
+
+	//Policy - a policy in a definitions export
+	Policy struct {
+		Name  string `json:"name"`
+		Vhost string `json:"vhost"`
+	}
+
+	//Queue - a queue in a definitions export
+	Queue struct {
+		Name  string `json:"name"`
+		Vhost string `json:"vhost"`
+	}
+
+	//Exchange - an exchange in a definitions export
+	Exchange struct {
+		Name  string `json:"name"`
+		Vhost string `json:"vhost"`
+	}
+
+	//Binding - a binding of an exchange to a queue or exchange, the properties key is only set on the broker's bindings
+	Binding struct {
+		Source          string                 `json:"source"`
+		Vhost           string                 `json:"vhost"`
+		Destination     string                 `json:"destination"`
+		DestinationType string                 `json:"destination_type"`
+		RoutingKey      string                 `json:"routing_key"`
+		Arguments       map[string]interface{} `json:"arguments"`
+		PropertiesKey   string                 `json:"properties_key,omitempty"`
+	}
+
+	managementClient struct {
+		baseURL  string
+		username string
+		password string
+		client   *http.Client
+		ctx      context.Context
+	}
+)
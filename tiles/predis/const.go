@@ -0,0 +1,76 @@
+package predis
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	//PRedisProduct -- product identifier of the redis service tile in installation settings
+	PRedisProduct = "p-redis"
+	//PRedisDedicatedJob -- job identifier of the dedicated plan vms, each running a single redis
+	PRedisDedicatedJob = "dedicated-node"
+	//PRedisSharedJob -- job identifier of the broker vm, which also hosts the shared-vm plan instances
+	PRedisSharedJob = "cf-redis-broker"
+	//PRedisBGSaveCommand -- property identifier of the secret name BGSAVE is renamed to
+	PRedisBGSaveCommand = "redis_bg_save_command"
+	//PRedisBackupDir -- directory in the backup set holding the p-redis archives
+	PRedisBackupDir = "p-redis"
+	//PRedisArchiveFormat -- filename format of an instance archive
+	PRedisArchiveFormat = "%s.tar.gz"
+	//PRedisRemoteArchivePath -- where an instance archive is uploaded during a restore
+	PRedisRemoteArchivePath = "/var/vcap/store/redis-restore.backup"
+
+	//PRedisCLIBin -- redis-cli on the redis vms
+	PRedisCLIBin = "/var/vcap/packages/redis/bin/redis-cli"
+	//PRedisMonitBin -- monit on the redis vms
+	PRedisMonitBin = "/var/vcap/bosh/bin/monit"
+	//PRedisConfigFile -- name of the config file in each instance's data dir
+	PRedisConfigFile = "redis.conf"
+	//PRedisRunningCommand -- lists the pid of the instance's redis-server, nothing once it has exited
+	PRedisRunningCommand = "pgrep -f '[r]edis-server .*:$PORT( |$)' || true"
+
+	//ErrBGSaveTimeoutMsg -- error message for a BGSAVE which did not finish in time
+	ErrBGSaveTimeoutMsg = "redis BGSAVE did not complete"
+	//ErrNoInstancesMsg -- error message for a tile without any redis instances
+	ErrNoInstancesMsg = "no redis instances found in the p-redis deployment"
+	//ErrStopTimeoutMsg -- error message for a redis which was still running after being stopped
+	ErrStopTimeoutMsg = "redis did not exit after being stopped"
+	//ErrNoArchiveMsg -- error message for an instance without an archive in the backup set
+	ErrNoArchiveMsg = "no archive for the redis instance in the backup set"
+)
+
+var (
+	//ErrBGSaveTimeout - error for a BGSAVE which did not finish in time
+	ErrBGSaveTimeout = errors.New(ErrBGSaveTimeoutMsg)
+	//ErrNoInstances - error for a tile without any redis instances
+	ErrNoInstances = errors.New(ErrNoInstancesMsg)
+	//ErrStopTimeout - error for a redis which was still running after being stopped
+	ErrStopTimeout = errors.New(ErrStopTimeoutMsg)
+	//ErrNoArchive - error for an instance without an archive in the backup set
+	ErrNoArchive = errors.New(ErrNoArchiveMsg)
+
+	//BGSavePollInterval - how long to wait between LASTSAVE checks while a BGSAVE runs
+	BGSavePollInterval = 2 * time.Second
+	//BGSavePollAttempts - how many LASTSAVE checks to make before giving up on a BGSAVE
+	BGSavePollAttempts = 150
+	//StopPollInterval - how long to wait between checks for the redis process while it exits
+	StopPollInterval = time.Second
+	//StopPollAttempts - how many checks for the redis process to make before giving up on a stop
+	StopPollAttempts = 60
+
+	//JobLayouts - where each redis job keeps its instances and how to stop and start them.
+	//$PORT and $PASSWORD in the commands are replaced with the instance's port and password.
+	JobLayouts = map[string]JobLayout{
+		PRedisDedicatedJob: {
+			DataDirGlob:  "/var/vcap/store/redis",
+			StopCommand:  PRedisMonitBin + " stop redis",
+			StartCommand: PRedisMonitBin + " start redis",
+		},
+		PRedisSharedJob: {
+			DataDirGlob:  "/var/vcap/store/cf-redis-broker/redis-data/*",
+			StopCommand:  PRedisCLIBin + " -p $PORT -a '$PASSWORD' SHUTDOWN NOSAVE",
+			StartCommand: PRedisMonitBin + " restart process-watcher",
+		},
+	}
+)
@@ -0,0 +1,341 @@
+package predis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-community/go-cfenv"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/gtils/command"
+	"github.com/pivotalservices/gtils/log"
+	"github.com/pivotalservices/gtils/osutils"
+	"github.com/xchapter7x/lo"
+)
+
+var (
+	//NewRemoteExecuter - creates the executer used to run commands on a redis vm
+	NewRemoteExecuter = command.NewRemoteExecutor
+
+	//NewRemoteOperations - creates the remote operations used to upload an instance archive to a redis vm
+	NewRemoteOperations = func(config command.SshConfig, remotePath string) RemoteOperations {
+		return osutils.NewRemoteOperationsWithPath(config, remotePath)
+	}
+)
+
+//NewPRedis - initializes a p-redis tile from the given installation settings
+func NewPRedis(installationSettings cfbackup.InstallationInfo, target string, cryptKey string) *PRedis {
+	return &PRedis{
+		InstallationSettings: installationSettings,
+		BackupContext:        cfbackup.NewBackupContext(target, cfenv.CurrentEnv(), cryptKey),
+	}
+}
+
+// Backup snapshots and archives every redis instance, on both the dedicated and shared-vm plans
+func (s *PRedis) Backup() error {
+	return s.BackupWithContext(context.Background())
+}
+
+// BackupWithContext snapshots and archives every redis instance like Backup, stopping when ctx is done
+func (s *PRedis) BackupWithContext(ctx context.Context) (err error) {
+	var instances []Instance
+
+	if instances, err = s.Instances(); err == nil {
+		for _, instance := range instances {
+			if err = s.BackupInstance(ctx, instance); err != nil {
+				break
+			}
+		}
+	}
+	return
+}
+
+// Restore replaces the data of every redis instance with its archive from the backup set. Instances without
+// an archive, such as those created after the backup, are left as they are, it fails when none has one
+func (s *PRedis) Restore() error {
+	return s.RestoreWithContext(context.Background())
+}
+
+// RestoreWithContext restores every redis instance like Restore, not starting on further instances once ctx is done
+func (s *PRedis) RestoreWithContext(ctx context.Context) (err error) {
+	var (
+		instances []Instance
+		skipped   []string
+	)
+
+	if instances, err = s.Instances(); err != nil {
+		return
+	}
+
+	for _, instance := range instances {
+		if err = s.RestoreInstance(ctx, instance); err == ErrNoArchive {
+			skipped = append(skipped, instance.archiveName())
+			err = nil
+
+		} else if err != nil {
+			return
+		}
+	}
+
+	if len(skipped) == len(instances) {
+		return ErrNoArchive
+	}
+
+	if len(skipped) > 0 {
+		lo.G.Warning("redis instances without an archive in the backup set were not restored", log.Data{"instances": skipped})
+	}
+	return
+}
+
+// Verify checks the archives of every redis instance can be read back, without restoring them
+func (s *PRedis) Verify() (report cfbackup.VerificationReport, err error) {
+	var instances []Instance
+
+	if instances, err = s.Instances(); err != nil {
+		return
+	}
+
+	for _, instance := range instances {
+		report.Record(instance.archiveName(), cfbackup.VerifyArtifact(s, cfbackup.VerifyTarGz, s.archivePath(instance)))
+	}
+
+	if !report.Passed() {
+		lo.G.Error("p-redis backup set failed verification", log.Data{"failures": report.Failures()})
+		err = cfbackup.ErrVerificationFailed
+	}
+	return
+}
+
+//BackupInstance - triggers a BGSAVE on the instance, waits for it to finish and streams a tarball of its rdb and aof files into the backup set
+func (s *PRedis) BackupInstance(ctx context.Context, instance Instance) (err error) {
+	var backupWriter io.WriteCloser
+	lo.G.Info("Exporting redis instance ", instance.archiveName())
+
+	if err = instance.BGSave(ctx); err != nil {
+		return
+	}
+
+	if backupWriter, err = s.Writer(s.archivePath(instance)); err == nil {
+		defer backupWriter.Close()
+		err = cfbackup.ExecuteWithContext(ctx, instance.Caller, backupWriter, instance.getDumpCommand())
+		lo.G.Debug("Done backing up ", instance.archiveName(), err)
+	}
+	return
+}
+
+//RestoreInstance - stops the instance, replaces its data files with those in the backup set and starts it again.
+//It returns ErrNoArchive, without touching the instance, when the backup set has no archive for it. Once the
+//instance is stopped its files are replaced and it is started again even when ctx is done, so it is not left half restored
+func (s *PRedis) RestoreInstance(ctx context.Context, instance Instance) (err error) {
+	var backupReader io.ReadCloser
+
+	if backupReader, err = s.Reader(s.archivePath(instance)); err != nil {
+		lo.G.Debug("no archive for redis instance ", instance.archiveName(), err)
+		return ErrNoArchive
+	}
+	defer backupReader.Close()
+	lo.G.Info("Restoring redis instance ", instance.archiveName())
+
+	if err = instance.RemoteOps.UploadFile(cfbackup.NewContextReader(ctx, backupReader)); err != nil {
+		return
+	}
+	defer instance.RemoteOps.RemoveRemoteFile()
+
+	if err = instance.Stop(ctx); err != nil {
+		return
+	}
+	restoreErr := instance.Caller.Execute(ioutil.Discard, instance.getRestoreCommand())
+
+	if err = instance.Start(); restoreErr != nil {
+		err = restoreErr
+	}
+	return
+}
+
+//Instances - discovers the redis instances on every vm of the dedicated and shared-vm jobs
+func (s *PRedis) Instances() (instances []Instance, err error) {
+	bgSaveCommand := "BGSAVE"
+
+	if secretMap, secretErr := s.InstallationSettings.FindPropertyValues(PRedisProduct, PRedisSharedJob, PRedisBGSaveCommand); secretErr == nil && secretMap["secret"] != "" {
+		bgSaveCommand = secretMap["secret"]
+	}
+
+	for _, job := range []string{PRedisDedicatedJob, PRedisSharedJob} {
+		var jobInstances []Instance
+
+		if jobInstances, err = s.jobInstances(job, bgSaveCommand); err != nil {
+			return
+		}
+		instances = append(instances, jobInstances...)
+	}
+
+	if len(instances) == 0 {
+		err = ErrNoInstances
+	}
+	return
+}
+
+func (s *PRedis) jobInstances(job string, bgSaveCommand string) (instances []Instance, err error) {
+	var (
+		ips           []string
+		vmCredentials cfbackup.VMCredentials
+	)
+
+	if ips, err = s.InstallationSettings.FindIPsByProductAndJob(PRedisProduct, job); err != nil {
+		return
+	}
+
+	if vmCredentials, err = s.InstallationSettings.FindVMCredentialsByProductAndJob(PRedisProduct, job); err != nil {
+		return
+	}
+
+	for _, ip := range ips {
+		var (
+			caller command.Executer
+			output bytes.Buffer
+		)
+		config := command.SshConfig{
+			Username: vmCredentials.UserID,
+			Password: vmCredentials.Password,
+			Host:     ip,
+			Port:     22,
+			SSLKey:   vmCredentials.SSLKey,
+		}
+
+		if caller, err = NewRemoteExecuter(config); err != nil {
+			return
+		}
+
+		if err = caller.Execute(&output, getListCommand(JobLayouts[job])); err != nil {
+			return
+		}
+
+		for _, line := range strings.Split(output.String(), "\n") {
+			if fields := strings.Fields(line); len(fields) >= 2 {
+				instance := Instance{
+					Job:           job,
+					IP:            ip,
+					ID:            path.Base(fields[0]),
+					DataDir:       fields[0],
+					Port:          fields[1],
+					BGSaveCommand: bgSaveCommand,
+					VcapPass:      vmCredentials.Password,
+					Caller:        caller,
+					RemoteOps:     NewRemoteOperations(config, PRedisRemoteArchivePath),
+				}
+
+				if len(fields) > 2 {
+					instance.Password = fields[2]
+				}
+				instances = append(instances, instance)
+			}
+		}
+	}
+	return
+}
+
+//BGSave - triggers a background save and waits until LASTSAVE reports it has finished
+func (s Instance) BGSave(ctx context.Context) (err error) {
+	var before, after string
+
+	if before, err = s.cli("LASTSAVE"); err != nil {
+		return
+	}
+
+	if _, err = s.cli(s.BGSaveCommand); err != nil {
+		return
+	}
+
+	for attempt := 0; attempt < BGSavePollAttempts; attempt++ {
+		if after, err = s.cli("LASTSAVE"); err != nil || after != before {
+			return
+		}
+
+		if err = sleep(ctx, BGSavePollInterval); err != nil {
+			return
+		}
+	}
+	return ErrBGSaveTimeout
+}
+
+//Stop - stops the redis process of the instance and waits for it to exit, monit and SHUTDOWN both return
+//while redis may still be writing its files
+func (s Instance) Stop(ctx context.Context) (err error) {
+	if err = s.sudo(s.expand(JobLayouts[s.Job].StopCommand)); err != nil {
+		return
+	}
+
+	for attempt := 0; attempt < StopPollAttempts; attempt++ {
+		var output bytes.Buffer
+
+		if err = s.Caller.Execute(&output, s.expand(PRedisRunningCommand)); err != nil || strings.TrimSpace(output.String()) == "" {
+			return
+		}
+
+		if err = sleep(ctx, StopPollInterval); err != nil {
+			return
+		}
+	}
+	return ErrStopTimeout
+}
+
+//Start - starts the redis process of the instance
+func (s Instance) Start() error {
+	return s.sudo(s.expand(JobLayouts[s.Job].StartCommand))
+}
+
+func (s Instance) cli(redisCommand string) (output string, err error) {
+	var buffer bytes.Buffer
+	cmd := fmt.Sprintf("%s -p %s", PRedisCLIBin, s.Port)
+
+	if s.Password != "" {
+		cmd = fmt.Sprintf("%s -a '%s'", cmd, s.Password)
+	}
+
+	if err = s.Caller.Execute(&buffer, fmt.Sprintf("%s %s", cmd, redisCommand)); err == nil {
+		output = strings.TrimSpace(buffer.String())
+	}
+	return
+}
+
+func (s Instance) expand(cmd string) string {
+	return strings.NewReplacer("$PORT", s.Port, "$PASSWORD", s.Password).Replace(cmd)
+}
+
+func (s Instance) sudo(cmd string) error {
+	return s.Caller.Execute(ioutil.Discard, fmt.Sprintf("echo '%s' | sudo -S %s", s.VcapPass, cmd))
+}
+
+func (s Instance) getDumpCommand() string {
+	return fmt.Sprintf("cd %s && (find . -maxdepth 1 -name '*.rdb' -o -maxdepth 1 -name '*.aof' | tar cz -T -)", s.DataDir)
+}
+
+func (s Instance) getRestoreCommand() string {
+	return fmt.Sprintf("echo '%s' | sudo -S sh -c 'rm -f %s/*.rdb %s/*.aof && tar zxf %s -C %s'", s.VcapPass, s.DataDir, s.DataDir, s.RemoteOps.Path(), s.DataDir)
+}
+
+func (s Instance) archiveName() string {
+	return path.Join(s.Job, s.IP, fmt.Sprintf(PRedisArchiveFormat, s.ID))
+}
+
+func (s *PRedis) archivePath(instance Instance) string {
+	return path.Join(s.TargetDir, PRedisBackupDir, instance.archiveName())
+}
+
+func sleep(ctx context.Context, interval time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(interval):
+		return nil
+	}
+}
+
+func getListCommand(layout JobLayout) string {
+	return fmt.Sprintf(`for d in %s; do [ -f "$d/%s" ] && echo "$d $(awk '/^port /{p=$2} /^requirepass /{a=$2} END{print p, a}' "$d/%s")"; done; true`, layout.DataDirGlob, PRedisConfigFile, PRedisConfigFile)
+}
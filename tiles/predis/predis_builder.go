@@ -0,0 +1,32 @@
+package predis
+
+import (
+	"io"
+
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	"github.com/pivotalservices/cfbackup/tiles/opsmanager"
+)
+
+//New -- builds a new p-redis tile pre initialized from the ops manager installation settings
+func (s *PRedisBuilder) New(tileSpec tileregistry.TileSpec) (predisCloser tileregistry.TileCloser, err error) {
+	var installationSettings io.Reader
+
+	if tileSpec, err = tileSpec.ResolveSecrets(); err != nil {
+		return
+	}
+
+	if installationSettings, err = opsmanager.GetInstallationSettings(tileSpec); err == nil {
+		config := cfbackup.NewConfigurationParserFromReader(installationSettings)
+		predis := NewPRedis(&config.InstallationSettings, tileSpec.ArchiveDirectory, tileSpec.CryptKey)
+		tileSpec.ApplyBackupContext(&predis.BackupContext)
+		predisCloser = struct {
+			*PRedis
+			tileregistry.Closer
+		}{
+			predis,
+			new(tileregistry.DoNothingCloser),
+		}
+	}
+	return
+}
@@ -0,0 +1,57 @@
+package predis_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup/fakes"
+	"github.com/pivotalservices/cfbackup/tileregistry"
+	. "github.com/pivotalservices/cfbackup/tiles/predis"
+	"github.com/pivotalservices/gtils/command"
+)
+
+var _ = Describe("PRedisBuilder", func() {
+	Describe("given a New() method", func() {
+		fixture := fakes.NewTileBuilderFixture("../../fixtures/installation-settings-1-6-aws.json", &NewRemoteExecuter)
+		fixture.NewExecuter = func(config command.SshConfig) command.Executer {
+			return &redisVM{ip: config.Host, instances: "/var/vcap/store/redis 6379 secret\n"}
+		}
+		fakes.ItBuildsTilesFromOpsManager(fixture, new(PRedisBuilder))
+
+		Context("when ops manager returns the installation settings", func() {
+			var predis *PRedis
+
+			BeforeEach(func() {
+				tile, err := new(PRedisBuilder).New(fakes.BuilderSpec)
+				Ω(err).ShouldNot(HaveOccurred())
+				predis = tile.(struct {
+					*PRedis
+					tileregistry.Closer
+				}).PRedis
+			})
+
+			It("then it should archive into the tileSpec's archive directory", func() {
+				Ω(predis.TargetDir).Should(Equal(fakes.BuilderSpec.ArchiveDirectory))
+			})
+
+			It("then it should reach the dedicated and shared redis vms with their own vm credentials", func() {
+				instances, err := predis.Instances()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(fixture.SSHConfigs).Should(HaveLen(3))
+				Ω(instances).Should(HaveLen(3))
+
+				for i, expected := range []struct{ job, ip, pass string }{
+					{PRedisDedicatedJob, "10.0.16.59", "1325c85173332f08"},
+					{PRedisDedicatedJob, "10.0.16.60", "1325c85173332f08"},
+					{PRedisSharedJob, "10.0.16.58", "1d488973551431ac"},
+				} {
+					Ω(fixture.SSHConfigs[i].Host).Should(Equal(expected.ip))
+					Ω(fixture.SSHConfigs[i].Username).Should(Equal("vcap"))
+					Ω(fixture.SSHConfigs[i].Password).Should(Equal(expected.pass))
+					Ω(instances[i].Job).Should(Equal(expected.job))
+					Ω(instances[i].IP).Should(Equal(expected.ip))
+					Ω(instances[i].VcapPass).Should(Equal(expected.pass))
+				}
+			})
+		})
+	})
+})
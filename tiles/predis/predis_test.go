@@ -0,0 +1,296 @@
+package predis_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/fakes"
+	. "github.com/pivotalservices/cfbackup/tiles/predis"
+	"github.com/pivotalservices/gtils/command"
+)
+
+const controlBGSaveCommand = "cf31002b6d8da5b0fc89"
+
+type redisVM struct {
+	ip        string
+	instances string
+	lastSave  int
+	saving    bool
+	failSave  bool
+	lingering int
+	commands  []string
+}
+
+func (s *redisVM) Execute(dest io.Writer, cmd string) (err error) {
+	s.commands = append(s.commands, cmd)
+
+	switch {
+	case strings.Contains(cmd, "for d in"):
+		io.WriteString(dest, s.instances)
+	case strings.HasSuffix(cmd, "LASTSAVE"):
+		if s.saving && !s.failSave {
+			s.lastSave++
+			s.saving = false
+		}
+		fmt.Fprintf(dest, "%d\n", s.lastSave)
+	case strings.HasSuffix(cmd, controlBGSaveCommand):
+		s.saving = true
+		io.WriteString(dest, "Background saving started\n")
+	case strings.HasPrefix(cmd, "pgrep"):
+		if s.lingering > 0 {
+			s.lingering--
+			io.WriteString(dest, "4242\n")
+		}
+	case strings.Contains(cmd, "tar cz"):
+		dest.Write(fakes.NewTarGz(map[string]string{"dump.rdb": "REDIS0006"}))
+	}
+	return
+}
+
+func (s *redisVM) ran(fragment string) bool {
+	return s.lastRan(fragment) >= 0
+}
+
+func (s *redisVM) lastRan(fragment string) (index int) {
+	index = -1
+
+	for i, cmd := range s.commands {
+		if strings.Contains(cmd, fragment) {
+			index = i
+		}
+	}
+	return
+}
+
+type mockRemoteOps struct {
+	uploaded string
+	removed  bool
+}
+
+func (s *mockRemoteOps) UploadFile(lfile io.Reader) error {
+	contents, err := ioutil.ReadAll(lfile)
+	s.uploaded = string(contents)
+	return err
+}
+
+func (s *mockRemoteOps) Path() string {
+	return PRedisRemoteArchivePath
+}
+
+func (s *mockRemoteOps) RemoveRemoteFile() error {
+	s.removed = true
+	return nil
+}
+
+var _ = Describe("PRedis", func() {
+	var (
+		tile                        *PRedis
+		dir                         string
+		vms                         map[string]*redisVM
+		originalNewRemoteExecuter   = NewRemoteExecuter
+		originalNewRemoteOperations = NewRemoteOperations
+		originalBGSavePollInterval  = BGSavePollInterval
+		originalStopPollInterval    = StopPollInterval
+	)
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "predis")
+		config := cfbackup.NewConfigurationParser("../../fixtures/installation-settings-1-6-aws.json")
+		tile = NewPRedis(&config.InstallationSettings, dir, "")
+		vms = map[string]*redisVM{
+			"10.0.16.58": {instances: "/var/vcap/store/cf-redis-broker/redis-data/instance-a 32768 pass-a\n/var/vcap/store/cf-redis-broker/redis-data/instance-b 32769 pass-b\n"},
+			"10.0.16.59": {instances: "/var/vcap/store/redis 6379 dedicated-pass\n"},
+			"10.0.16.60": {instances: "/var/vcap/store/redis 6379 other-pass\n"},
+		}
+		BGSavePollInterval = 0
+		StopPollInterval = 0
+		NewRemoteExecuter = func(config command.SshConfig) (command.Executer, error) {
+			return vms[config.Host], nil
+		}
+		NewRemoteOperations = func(config command.SshConfig, remotePath string) RemoteOperations {
+			return new(mockRemoteOps)
+		}
+	})
+
+	AfterEach(func() {
+		NewRemoteExecuter = originalNewRemoteExecuter
+		NewRemoteOperations = originalNewRemoteOperations
+		BGSavePollInterval = originalBGSavePollInterval
+		StopPollInterval = originalStopPollInterval
+		os.RemoveAll(dir)
+	})
+
+	Describe("given an Instances() method", func() {
+		It("then it should find the instances on the dedicated and shared vms", func() {
+			instances, err := tile.Instances()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(instances).Should(HaveLen(4))
+			Ω(instances[0].Job).Should(Equal(PRedisDedicatedJob))
+			Ω(instances[0].Password).Should(Equal("dedicated-pass"))
+			Ω(instances[3].ID).Should(Equal("instance-b"))
+			Ω(instances[3].Port).Should(Equal("32769"))
+			Ω(instances[3].BGSaveCommand).Should(Equal(controlBGSaveCommand))
+		})
+	})
+
+	Describe("given a Backup() method", func() {
+		Context("when every BGSAVE completes", func() {
+			var err error
+
+			BeforeEach(func() {
+				err = tile.Backup()
+			})
+
+			It("then it should trigger the renamed BGSAVE before archiving", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(vms["10.0.16.59"].ran("-p 6379 -a 'dedicated-pass' " + controlBGSaveCommand)).Should(BeTrue())
+				Ω(vms["10.0.16.58"].ran("-p 32769 -a 'pass-b' " + controlBGSaveCommand)).Should(BeTrue())
+			})
+
+			It("then it should stream a tarball of each instance into the backup set", func() {
+				for _, archive := range []string{
+					"dedicated-node/10.0.16.59/redis.tar.gz",
+					"dedicated-node/10.0.16.60/redis.tar.gz",
+					"cf-redis-broker/10.0.16.58/instance-a.tar.gz",
+					"cf-redis-broker/10.0.16.58/instance-b.tar.gz",
+				} {
+					_, statErr := os.Stat(path.Join(dir, PRedisBackupDir, archive))
+					Ω(statErr).ShouldNot(HaveOccurred())
+				}
+				Ω(vms["10.0.16.58"].ran("cd /var/vcap/store/cf-redis-broker/redis-data/instance-a && ")).Should(BeTrue())
+			})
+
+			It("then it should produce a backup set which passes verification", func() {
+				report, verifyErr := tile.Verify()
+				Ω(verifyErr).ShouldNot(HaveOccurred())
+				Ω(report.Artifacts).Should(HaveLen(4))
+			})
+		})
+
+		Context("when a BGSAVE never completes", func() {
+			It("then it should return an error", func() {
+				originalAttempts := BGSavePollAttempts
+				BGSavePollAttempts = 2
+				defer func() { BGSavePollAttempts = originalAttempts }()
+				vms["10.0.16.59"].failSave = true
+				Ω(tile.Backup()).Should(Equal(ErrBGSaveTimeout))
+			})
+		})
+	})
+
+	Describe("given a RestoreInstance() method", func() {
+		var instance Instance
+
+		writeArchive := func(instance Instance) {
+			archive := path.Join(dir, PRedisBackupDir, instance.Job, instance.IP, instance.ID+".tar.gz")
+			os.MkdirAll(path.Dir(archive), 0755)
+			ioutil.WriteFile(archive, []byte("archive"), 0644)
+		}
+
+		BeforeEach(func() {
+			instances, _ := tile.Instances()
+			instance = instances[2]
+		})
+
+		Context("when the backup set has the instance archive", func() {
+			var err error
+
+			BeforeEach(func() {
+				writeArchive(instance)
+				vms["10.0.16.58"].lingering = 2
+				err = tile.RestoreInstance(context.Background(), instance)
+			})
+
+			It("then it should upload the instance archive", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				ops := instance.RemoteOps.(*mockRemoteOps)
+				Ω(ops.uploaded).Should(Equal("archive"))
+				Ω(ops.removed).Should(BeTrue())
+			})
+
+			It("then it should stop the instance, replace its files and restart it", func() {
+				vm := vms["10.0.16.58"]
+				Ω(vm.ran("-p 32768 -a 'pass-a' SHUTDOWN NOSAVE")).Should(BeTrue())
+				Ω(vm.ran("tar zxf " + PRedisRemoteArchivePath + " -C /var/vcap/store/cf-redis-broker/redis-data/instance-a")).Should(BeTrue())
+				Ω(vm.ran("monit restart process-watcher")).Should(BeTrue())
+				Ω(vm.ran("instance-b")).Should(BeFalse())
+			})
+
+			It("then it should wait for redis to exit before replacing its files", func() {
+				vm := vms["10.0.16.58"]
+				Ω(vm.ran("pgrep -f '[r]edis-server .*:32768( |$)'")).Should(BeTrue())
+				Ω(vm.lastRan("pgrep")).Should(BeNumerically(">", vm.lastRan("SHUTDOWN NOSAVE")))
+				Ω(vm.lastRan("pgrep")).Should(BeNumerically("<", vm.lastRan("tar zxf")))
+				Ω(vm.lingering).Should(Equal(0))
+			})
+		})
+
+		Context("when redis does not exit after being stopped", func() {
+			It("then it should return an error without replacing its files", func() {
+				originalAttempts := StopPollAttempts
+				StopPollAttempts = 2
+				defer func() { StopPollAttempts = originalAttempts }()
+				writeArchive(instance)
+				vms["10.0.16.58"].lingering = 10
+				Ω(tile.RestoreInstance(context.Background(), instance)).Should(Equal(ErrStopTimeout))
+				Ω(vms["10.0.16.58"].ran("tar zxf")).Should(BeFalse())
+			})
+		})
+
+		Context("when the backup set has no archive for the instance", func() {
+			It("then it should leave the instance untouched", func() {
+				Ω(tile.RestoreInstance(context.Background(), instance)).Should(Equal(ErrNoArchive))
+				Ω(vms["10.0.16.58"].ran("SHUTDOWN")).Should(BeFalse())
+			})
+		})
+	})
+
+	Describe("given a Restore() method", func() {
+		var instances []Instance
+
+		BeforeEach(func() {
+			instances, _ = tile.Instances()
+		})
+
+		writeArchives := func(instances []Instance) {
+			for _, instance := range instances {
+				archive := path.Join(dir, PRedisBackupDir, instance.Job, instance.IP, instance.ID+".tar.gz")
+				os.MkdirAll(path.Dir(archive), 0755)
+				ioutil.WriteFile(archive, []byte("archive"), 0644)
+			}
+		}
+
+		Context("when an instance has no archive in the backup set", func() {
+			It("then it should skip it and restore the others", func() {
+				writeArchives(instances[1:])
+				Ω(tile.Restore()).Should(Succeed())
+				Ω(vms["10.0.16.59"].ran("monit stop redis")).Should(BeFalse())
+				Ω(vms["10.0.16.60"].ran("monit stop redis")).Should(BeTrue())
+				Ω(vms["10.0.16.58"].ran("tar zxf")).Should(BeTrue())
+			})
+		})
+
+		Context("when no instance has an archive in the backup set", func() {
+			It("then it should return an error", func() {
+				Ω(tile.Restore()).Should(Equal(ErrNoArchive))
+			})
+		})
+
+		Context("when every instance has an archive", func() {
+			It("then it should restore each dedicated node through monit", func() {
+				writeArchives(instances)
+				Ω(tile.Restore()).Should(Succeed())
+				Ω(vms["10.0.16.59"].ran("monit stop redis")).Should(BeTrue())
+				Ω(vms["10.0.16.60"].ran("monit start redis")).Should(BeTrue())
+			})
+		})
+	})
+})
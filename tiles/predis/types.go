@@ -0,0 +1,47 @@
+package predis
+
+import (
+	"io"
+
+	"github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/gtils/command"
+)
+
+type (
+	//PRedis - the redis service tile, whose instances' rdb and aof files are backed up over ssh
+	PRedis struct {
+		cfbackup.BackupContext
+		InstallationSettings cfbackup.InstallationInfo
+	}
+
+	//JobLayout - where a redis job keeps its instances' data and how to stop and start them
+	JobLayout struct {
+		DataDirGlob  string
+		StopCommand  string
+		StartCommand string
+	}
+
+	//Instance - a single redis server on one of the tile's vms
+	Instance struct {
+		Job           string
+		IP            string
+		ID            string
+		DataDir       string
+		Port          string
+		Password      string
+		BGSaveCommand string
+		VcapPass      string
+		Caller        command.Executer
+		RemoteOps     RemoteOperations
+	}
+
+	//PRedisBuilder -- an object that can build a p-redis tile pre-initialized
+	PRedisBuilder struct{}
+
+	//RemoteOperations - uploads an archive to a redis vm for a restore
+	RemoteOperations interface {
+		UploadFile(lfile io.Reader) (err error)
+		Path() string
+		RemoveRemoteFile() (err error)
+	}
+)
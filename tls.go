@@ -0,0 +1,363 @@
+package cfbackup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	ghttp "github.com/pivotalservices/gtils/http"
+	"github.com/xchapter7x/lo"
+)
+
+var (
+	systemTLSClients     *TLSClients
+	systemTLSClientsOnce sync.Once
+)
+
+//NewTLSClients - the clients verifying ops manager, director and uaa servers with the given config, for the tile or
+//gateway they are handed to alone. An error when one of the config's CAs or pins can not be read
+func NewTLSClients(config TLSConfig) (clients *TLSClients, err error) {
+	clients = &TLSClients{clients: make(map[string]*http.Client)}
+
+	for _, target := range TLSTargets {
+		var tlsConfig *tls.Config
+
+		if tlsConfig, err = config.ClientTLS(target); err != nil {
+			return nil, err
+		}
+		clients.clients[target] = newTLSClient(tlsConfig)
+	}
+
+	if config.Insecure {
+		lo.G.Warning("tls verification is turned off, ops manager, director and uaa servers are not verified")
+	}
+	return
+}
+
+//Client - the client calls to the target, one of TLSTargets, are made with. Nil clients verify the server against
+//the system roots. No client follows redirects, callers read the Location themselves
+func (s *TLSClients) Client(target string) *http.Client {
+	if s == nil {
+		systemTLSClientsOnce.Do(func() {
+			systemTLSClients, _ = NewTLSClients(TLSConfig{})
+		})
+		s = systemTLSClients
+	}
+	return s.clients[target]
+}
+
+//Validate - checks every CA of the config can be read and every pin is a base64 sha256 digest
+func (s TLSConfig) Validate() error {
+	for _, target := range TLSTargets {
+		if _, err := s.ClientTLS(target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//ClientTLS - the tls config calls to the target are made with. The target's CAs are trusted on top of the system roots,
+//uaa servers are trusted with any of the CAs as they usually share ops manager's or the director's. With pins set a
+//server is only trusted when its verified chain carries one of them, for a target without a CA the server's own
+//certificate has to be pinned and the pin alone verifies it
+func (s TLSConfig) ClientTLS(target string) (config *tls.Config, err error) {
+	config = &tls.Config{InsecureSkipVerify: s.Insecure}
+	cas := s.cas(target)
+
+	if len(cas) > 0 {
+		if config.RootCAs, err = x509.SystemCertPool(); err != nil || config.RootCAs == nil {
+			config.RootCAs, err = x509.NewCertPool(), nil
+		}
+
+		for _, ca := range cas {
+			var pem []byte
+
+			if pem, err = readPEM(ca); err != nil {
+				return nil, fmt.Errorf("%w for %s: %s", ErrTLSInvalidCA, target, err)
+			}
+
+			if !config.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("%w for %s: no certificate found", ErrTLSInvalidCA, target)
+			}
+		}
+	}
+
+	if len(s.Pins) > 0 {
+		var pins map[string]bool
+
+		if pins, err = decodePins(s.Pins); err != nil {
+			return nil, err
+		}
+		config.VerifyPeerCertificate = verifyPins(pins)
+
+		if len(cas) == 0 {
+			config.InsecureSkipVerify = true
+		}
+	}
+	return
+}
+
+//CertificatePin - the pin of the certificate, the base64 sha256 digest of its public key
+func CertificatePin(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+func (s TLSConfig) cas(target string) (cas []string) {
+	candidates := map[string][]string{
+		TLSTargetOpsManager: {s.OpsManagerCA},
+		TLSTargetDirector:   {s.DirectorCA},
+		TLSTargetUAA:        {s.UAACA, s.OpsManagerCA, s.DirectorCA},
+	}[target]
+
+	for _, ca := range candidates {
+		if ca != "" {
+			cas = append(cas, ca)
+		}
+	}
+	return
+}
+
+func readPEM(ca string) ([]byte, error) {
+	if strings.Contains(ca, "-----BEGIN") {
+		return []byte(ca), nil
+	}
+	return ioutil.ReadFile(ca)
+}
+
+func decodePins(pins []string) (decoded map[string]bool, err error) {
+	decoded = make(map[string]bool)
+
+	for _, pin := range pins {
+		pin = strings.TrimPrefix(pin, TLSPinPrefix)
+
+		if digest, err := base64.StdEncoding.DecodeString(pin); err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("%w: %q is not a base64 sha256 digest", ErrTLSInvalidPin, pin)
+		}
+		decoded[pin] = true
+	}
+	return
+}
+
+//verifyPins - a server is trusted when a verified chain carries one of the pins. Without verified chains, no CA or
+//insecure, only the leaf is checked: it is the one certificate the server proved it holds the key of, anyone can
+//append other certificates to the chain it sends
+func verifyPins(pins map[string]bool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if len(verifiedChains) == 0 {
+			if len(rawCerts) > 0 {
+				if cert, err := x509.ParseCertificate(rawCerts[0]); err == nil && pins[CertificatePin(cert)] {
+					return nil
+				}
+			}
+			return ErrTLSPinMismatch
+		}
+
+		for _, chain := range verifiedChains {
+			for _, cert := range chain {
+				if pins[CertificatePin(cert)] {
+					return nil
+				}
+			}
+		}
+		return ErrTLSPinMismatch
+	}
+}
+
+func newTLSClient(config *tls.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+//NewTLSGateway - an HttpGateway to the target, one of TLSTargets, verifying the server with the given clients
+func NewTLSGateway(target string, clients *TLSClients) *TLSGateway {
+	return &TLSGateway{Target: target, Clients: clients}
+}
+
+//WithContext - a copy of the gateway whose requests are cancelled once the context is done
+func (s *TLSGateway) WithContext(ctx context.Context) ghttp.HttpGateway {
+	gateway := *s
+	gateway.ctx = ctx
+	return &gateway
+}
+
+//Get - a get request to the entity's url
+func (s *TLSGateway) Get(entity ghttp.HttpRequestEntity) ghttp.RequestAdaptor {
+	return s.request("GET", entity, nil)
+}
+
+//Post - a post request of the body to the entity's url
+func (s *TLSGateway) Post(entity ghttp.HttpRequestEntity, body io.Reader) ghttp.RequestAdaptor {
+	return s.request("POST", entity, body)
+}
+
+//Put - a put request of the body to the entity's url
+func (s *TLSGateway) Put(entity ghttp.HttpRequestEntity, body io.Reader) ghttp.RequestAdaptor {
+	return s.request("PUT", entity, body)
+}
+
+func (s *TLSGateway) request(method string, entity ghttp.HttpRequestEntity, body io.Reader) ghttp.RequestAdaptor {
+	return func() (resp *http.Response, err error) {
+		var req *http.Request
+
+		if req, err = http.NewRequestWithContext(s.context(), method, entity.Url, body); err != nil {
+			return
+		}
+
+		if entity.Authorization != "" {
+			req.Header.Set("Authorization", entity.Authorization)
+
+		} else if entity.Username != "" {
+			req.SetBasicAuth(entity.Username, entity.Password)
+		}
+
+		if entity.ContentType != "" {
+			req.Header.Set("Content-Type", entity.ContentType)
+		}
+		return s.Clients.Client(s.Target).Do(req)
+	}
+}
+
+func (s *TLSGateway) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+//GatewayTLSClients - the clients a TLSGateway verifies its servers with, so calls made alongside it, like
+//requesting its uaa tokens, verify theirs alike. Nil, the system roots, for any other gateway
+func GatewayTLSClients(gateway ghttp.HttpGateway) *TLSClients {
+	if tlsGateway, ok := gateway.(*TLSGateway); ok {
+		return tlsGateway.Clients
+	}
+	return nil
+}
+
+//NewTLSUploader - a multipart uploader to the target verifying the server with the given clients. The file is
+//streamed from fileRef as it is sent, so archives of any size are uploaded from disk and s3 alike. With a fileSize
+//of 0 or more the request carries its length, otherwise it is sent chunked. When fileRef was made with
+//NewContextReader the request is cancelled along with its context
+func NewTLSUploader(target string, clients *TLSClients) httpUploader {
+	return func(conn ghttp.ConnAuth, paramName, filename string, fileSize int64, fileRef io.Reader, params map[string]string) (res *http.Response, err error) {
+		var (
+			req         *http.Request
+			head, tail  []byte
+			contentType string
+		)
+
+		if head, tail, contentType, err = multipartEnvelope(paramName, filename, params); err != nil {
+			return
+		}
+		body := io.MultiReader(bytes.NewReader(head), fileRef, bytes.NewReader(tail))
+
+		if req, err = http.NewRequestWithContext(readerContext(fileRef), "POST", conn.Url, body); err != nil {
+			return
+		}
+		req.ContentLength = -1
+
+		if fileSize >= 0 {
+			req.ContentLength = int64(len(head)) + fileSize + int64(len(tail))
+		}
+		req.SetBasicAuth(conn.Username, conn.Password)
+		req.Header.Set("Content-Type", contentType)
+		return clients.Client(target).Do(req)
+	}
+}
+
+func readerContext(reader io.Reader) context.Context {
+	if contextReader, ok := reader.(interface{ Context() context.Context }); ok {
+		return contextReader.Context()
+	}
+	return context.Background()
+}
+
+//multipartEnvelope - the form data sent before and after the file: the params, sorted by name, and the file's part
+//header, then the closing boundary. Knowing both, the length of the request follows from the file's size
+func multipartEnvelope(paramName, filename string, params map[string]string) (head, tail []byte, contentType string, err error) {
+	var (
+		names    []string
+		envelope bytes.Buffer
+	)
+	writer := multipart.NewWriter(&envelope)
+	contentType = writer.FormDataContentType()
+
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err = writer.WriteField(name, params[name]); err != nil {
+			return
+		}
+	}
+
+	if _, err = writer.CreateFormFile(paramName, path.Base(filename)); err != nil {
+		return
+	}
+	headLen := envelope.Len()
+
+	if err = writer.Close(); err == nil {
+		head, tail = envelope.Bytes()[:headLen], envelope.Bytes()[headLen:]
+	}
+	return
+}
+
+//RequestUAAToken - requests a token from the uaa server, verifying it with the given clients.
+//The client is sent in the form, the username and password only for the password grant
+func RequestUAAToken(clients *TLSClients, uaaURL, username, password, clientID, clientSecret, grantType string) (token string, err error) {
+	var (
+		req  *http.Request
+		resp *http.Response
+		body struct {
+			AccessToken string `json:"access_token"`
+		}
+	)
+	form := url.Values{"grant_type": {grantType}, "response_type": {"token"}, "client_id": {clientID}, "client_secret": {clientSecret}}
+
+	if grantType == UAAPasswordGrantType {
+		form.Set("username", username)
+		form.Set("password", password)
+	}
+
+	if req, err = http.NewRequest("POST", strings.TrimRight(uaaURL, "/")+"/oauth/token", strings.NewReader(form.Encode())); err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if resp, err = clients.Client(TLSTargetUAA).Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("uaa responded %s", resp.Status)
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&body); err == nil {
+		token = body.AccessToken
+	}
+	return
+}
@@ -0,0 +1,331 @@
+package cfbackup_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotalservices/cfbackup"
+	ghttp "github.com/pivotalservices/gtils/http"
+)
+
+var _ = Describe("TLS", func() {
+	var (
+		server   *httptest.Server
+		serverCA string
+		requests []*http.Request
+		bodies   []string
+		clients  *TLSClients
+	)
+
+	BeforeEach(func() {
+		requests, bodies, clients = nil, nil, nil
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			requests = append(requests, r)
+			bodies = append(bodies, string(body))
+
+			switch r.URL.Path {
+			case "/redirect":
+				w.Header().Set("Location", "/tasks/42")
+				w.WriteHeader(http.StatusFound)
+			case "/oauth/token":
+				fmt.Fprint(w, `{"access_token":"uaa-token"}`)
+			default:
+				fmt.Fprint(w, "ok")
+			}
+		}))
+		serverCA = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	trust := func(config TLSConfig) {
+		var err error
+		clients, err = NewTLSClients(config)
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+
+	get := func(target string) error {
+		resp, err := clients.Client(target).Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	Describe("given a NewTLSClients() function", func() {
+		Context("when there are no clients", func() {
+			It("then it should reject a server signed by an unknown CA", func() {
+				Ω(get(TLSTargetOpsManager)).Should(MatchError(ContainSubstring("certificate")))
+				Ω(get(TLSTargetDirector)).Should(HaveOccurred())
+				Ω(get(TLSTargetUAA)).Should(HaveOccurred())
+			})
+		})
+
+		Context("when the target's CA is given as pem", func() {
+			It("then it should trust servers signed by it for that target only", func() {
+				trust(TLSConfig{DirectorCA: serverCA})
+				Ω(get(TLSTargetDirector)).Should(Succeed())
+				Ω(get(TLSTargetOpsManager)).Should(HaveOccurred())
+			})
+		})
+
+		Context("when the target's CA is given as a file", func() {
+			It("then it should read and trust it", func() {
+				caFile, _ := ioutil.TempFile("", "ca")
+				defer os.Remove(caFile.Name())
+				caFile.WriteString(serverCA)
+				caFile.Close()
+				trust(TLSConfig{OpsManagerCA: caFile.Name()})
+				Ω(get(TLSTargetOpsManager)).Should(Succeed())
+			})
+		})
+
+		Context("when only the ops manager CA is given", func() {
+			It("then it should trust uaa servers signed by it as well", func() {
+				trust(TLSConfig{OpsManagerCA: serverCA})
+				Ω(get(TLSTargetUAA)).Should(Succeed())
+			})
+		})
+
+		Context("when insecure is opted into", func() {
+			It("then it should skip verification", func() {
+				trust(TLSConfig{Insecure: true})
+				Ω(get(TLSTargetDirector)).Should(Succeed())
+			})
+		})
+
+		Context("when the server's key is pinned", func() {
+			It("then it should trust the server without a CA", func() {
+				trust(TLSConfig{Pins: []string{TLSPinPrefix + CertificatePin(server.Certificate())}})
+				Ω(get(TLSTargetDirector)).Should(Succeed())
+			})
+		})
+
+		Context("when the server's key is pinned along with its CA", func() {
+			It("then it should trust the server through its verified chain", func() {
+				trust(TLSConfig{OpsManagerCA: serverCA, Pins: []string{CertificatePin(server.Certificate())}})
+				Ω(get(TLSTargetOpsManager)).Should(Succeed())
+			})
+		})
+
+		Context("when a server appends the pinned certificate to its own chain", func() {
+			var mitm *httptest.Server
+
+			BeforeEach(func() {
+				key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				Ω(err).ShouldNot(HaveOccurred())
+				template := &x509.Certificate{
+					SerialNumber: big.NewInt(1),
+					Subject:      pkix.Name{CommonName: "mitm"},
+					IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+					NotBefore:    time.Now().Add(-time.Hour),
+					NotAfter:     time.Now().Add(time.Hour),
+				}
+				leaf, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+				Ω(err).ShouldNot(HaveOccurred())
+				mitm = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests = append(requests, r)
+				}))
+				mitm.TLS = &tls.Config{Certificates: []tls.Certificate{{
+					Certificate: [][]byte{leaf, server.Certificate().Raw},
+					PrivateKey:  key,
+				}}}
+				mitm.StartTLS()
+			})
+
+			AfterEach(func() {
+				mitm.Close()
+			})
+
+			It("then it should reject the server, whose own key is not pinned", func() {
+				trust(TLSConfig{Pins: []string{CertificatePin(server.Certificate())}})
+				_, err := clients.Client(TLSTargetDirector).Get(mitm.URL)
+				Ω(err).Should(MatchError(ContainSubstring(ErrTLSPinMismatchMsg)))
+				Ω(requests).Should(BeEmpty())
+			})
+		})
+
+		Context("when a different key is pinned", func() {
+			It("then it should reject the server even when its CA is trusted", func() {
+				otherPin := strings.Repeat("A", 43) + "="
+				trust(TLSConfig{OpsManagerCA: serverCA, Pins: []string{otherPin}})
+				Ω(get(TLSTargetOpsManager)).Should(MatchError(ContainSubstring(ErrTLSPinMismatchMsg)))
+				Ω(get(TLSTargetDirector)).Should(MatchError(ContainSubstring(ErrTLSPinMismatchMsg)))
+			})
+		})
+
+		Context("when the config can not be read", func() {
+			It("then it should reject it", func() {
+				for config, expected := range map[*TLSConfig]error{
+					{DirectorCA: "/does/not/exist.pem"}:         ErrTLSInvalidCA,
+					{DirectorCA: "-----BEGIN CERTIFICATE-----"}: ErrTLSInvalidCA,
+					{Pins: []string{"not-a-pin"}}:               ErrTLSInvalidPin,
+				} {
+					invalid, err := NewTLSClients(*config)
+					Ω(err).Should(MatchError(expected))
+					Ω(invalid).Should(BeNil())
+				}
+			})
+		})
+
+		Context("when clients are made with different configs", func() {
+			It("then each should verify servers with its own config alone", func() {
+				trusting, err := NewTLSClients(TLSConfig{DirectorCA: serverCA})
+				Ω(err).ShouldNot(HaveOccurred())
+				_, err = NewTLSGateway(TLSTargetDirector, trusting).Get(ghttp.HttpRequestEntity{Url: server.URL})()
+				Ω(err).ShouldNot(HaveOccurred())
+				_, err = NewTLSGateway(TLSTargetDirector, nil).Get(ghttp.HttpRequestEntity{Url: server.URL})()
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("given a TLSGateway", func() {
+		BeforeEach(func() {
+			trust(TLSConfig{DirectorCA: serverCA})
+		})
+
+		It("then it should send the entity's credentials and content type", func() {
+			_, err := NewTLSGateway(TLSTargetDirector, clients).Put(ghttp.HttpRequestEntity{
+				Url:         server.URL + "/jobs",
+				Username:    "director",
+				Password:    "secret",
+				ContentType: "text/yaml",
+			}, strings.NewReader("manifest"))()
+			Ω(err).ShouldNot(HaveOccurred())
+			username, password, _ := requests[0].BasicAuth()
+			Ω(username).Should(Equal("director"))
+			Ω(password).Should(Equal("secret"))
+			Ω(requests[0].Header.Get("Content-Type")).Should(Equal("text/yaml"))
+			Ω(bodies[0]).Should(Equal("manifest"))
+		})
+
+		It("then it should prefer the entity's authorization over basic auth", func() {
+			NewTLSGateway(TLSTargetDirector, clients).Get(ghttp.HttpRequestEntity{Url: server.URL, Username: "director", Authorization: "Bearer token"})()
+			Ω(requests[0].Header.Get("Authorization")).Should(Equal("Bearer token"))
+		})
+
+		It("then it should leave redirects to the caller", func() {
+			resp, err := NewTLSGateway(TLSTargetDirector, clients).Get(ghttp.HttpRequestEntity{Url: server.URL + "/redirect"})()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp.StatusCode).Should(Equal(http.StatusFound))
+			Ω(resp.Header.Get("Location")).Should(Equal("/tasks/42"))
+			Ω(requests).Should(HaveLen(1))
+		})
+
+		It("then it should cancel requests once the context it is bound to is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := NewTLSGateway(TLSTargetDirector, clients).WithContext(ctx).Get(ghttp.HttpRequestEntity{Url: server.URL})()
+			Ω(err).Should(MatchError(ContainSubstring(context.Canceled.Error())))
+			Ω(requests).Should(BeEmpty())
+		})
+
+		It("then it should verify the server of its own target", func() {
+			_, err := NewTLSGateway(TLSTargetOpsManager, clients).Get(ghttp.HttpRequestEntity{Url: server.URL})()
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Describe("given a NewTLSUploader() function", func() {
+		It("then it should stream the file and params as multipart form data", func() {
+			trust(TLSConfig{OpsManagerCA: serverCA})
+			upload := NewTLSUploader(TLSTargetOpsManager, clients)
+			resp, err := upload(ghttp.ConnAuth{Url: server.URL, Username: "admin", Password: "pass"}, "installation[file]", "/backups/installation.zip", -1, strings.NewReader("archive"), map[string]string{"passphrase": "phrase"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp.StatusCode).Should(Equal(http.StatusOK))
+			Ω(requests[0].Header.Get("Content-Type")).Should(HavePrefix("multipart/form-data"))
+			Ω(bodies[0]).Should(ContainSubstring(`name="passphrase"`))
+			Ω(bodies[0]).Should(ContainSubstring(`name="installation[file]"; filename="installation.zip"`))
+			Ω(bodies[0]).Should(ContainSubstring("archive"))
+			Ω(requests[0].TransferEncoding).Should(Equal([]string{"chunked"}))
+		})
+
+		It("then it should send the length of the request when the file's size is known", func() {
+			trust(TLSConfig{OpsManagerCA: serverCA})
+			upload := NewTLSUploader(TLSTargetOpsManager, clients)
+			_, err := upload(ghttp.ConnAuth{Url: server.URL}, "installation[file]", "installation.zip", int64(len("archive")), strings.NewReader("archive"), map[string]string{"passphrase": "phrase"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(requests[0].TransferEncoding).Should(BeEmpty())
+			Ω(requests[0].ContentLength).Should(Equal(int64(len(bodies[0]))))
+			Ω(bodies[0]).Should(ContainSubstring("archive"))
+		})
+
+		It("then it should fail when the file is not of the size given", func() {
+			trust(TLSConfig{OpsManagerCA: serverCA})
+			upload := NewTLSUploader(TLSTargetOpsManager, clients)
+			_, err := upload(ghttp.ConnAuth{Url: server.URL}, "installation[file]", "installation.zip", 100, strings.NewReader("archive"), nil)
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("then it should cancel the upload along with the context of its file", func() {
+			trust(TLSConfig{OpsManagerCA: serverCA})
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			upload := NewTLSUploader(TLSTargetOpsManager, clients)
+			_, err := upload(ghttp.ConnAuth{Url: server.URL}, "installation[file]", "installation.zip", -1, NewContextReader(ctx, strings.NewReader("archive")), nil)
+			Ω(err).Should(MatchError(ContainSubstring(context.Canceled.Error())))
+			Ω(requests).Should(BeEmpty())
+		})
+
+		It("then it should fail against a server it does not trust", func() {
+			upload := NewTLSUploader(TLSTargetOpsManager, clients)
+			_, err := upload(ghttp.ConnAuth{Url: server.URL}, "installation[file]", "installation.zip", -1, strings.NewReader("archive"), nil)
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Describe("given a GatewayTLSClients() function", func() {
+		It("then it should return the clients of a TLSGateway", func() {
+			trust(TLSConfig{DirectorCA: serverCA})
+			Ω(GatewayTLSClients(NewTLSGateway(TLSTargetDirector, clients))).Should(BeIdenticalTo(clients))
+			Ω(GatewayTLSClients(NewTLSGateway(TLSTargetDirector, clients).WithContext(context.Background()))).Should(BeIdenticalTo(clients))
+		})
+
+		It("then it should return nil for any other gateway", func() {
+			Ω(GatewayTLSClients(ghttp.NewHttpGateway())).Should(BeNil())
+		})
+	})
+
+	Describe("given a RequestUAAToken() function", func() {
+		BeforeEach(func() {
+			trust(TLSConfig{UAACA: serverCA})
+		})
+
+		It("then it should request the token with the client and user in the form", func() {
+			token, err := RequestUAAToken(clients, server.URL, "admin", "pass", "opsman", "", UAAPasswordGrantType)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(token).Should(Equal("uaa-token"))
+			Ω(bodies[0]).Should(ContainSubstring("grant_type=password"))
+			Ω(bodies[0]).Should(ContainSubstring("client_id=opsman"))
+			Ω(bodies[0]).Should(ContainSubstring("username=admin"))
+		})
+
+		It("then it should only send the client for the client credentials grant", func() {
+			_, err := RequestUAAToken(clients, server.URL, "admin", "pass", "backup", "secret", DirectorUAAGrantType)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(bodies[0]).ShouldNot(ContainSubstring("username"))
+			Ω(bodies[0]).Should(ContainSubstring("client_secret=secret"))
+		})
+	})
+})
@@ -1,61 +1,164 @@
 package cfbackup
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pivotalservices/gtils/bosh"
 	. "github.com/pivotalservices/gtils/http"
+	"github.com/xchapter7x/lo"
 )
 
 // Not ping server so frequently and exausted the resources
 var TaskPingFreq = 1000 * time.Millisecond
 
-//CloudControllerJobs - array storing a list of CCJobs
+// TaskPingMaxFreq - the longest the polling of a bosh task backs off to
+var TaskPingMaxFreq = 15 * time.Second
+
+// TaskTimeout - how long a bosh task may stay queued or processing before it is given up on, zero waits forever
+var TaskTimeout = 30 * time.Minute
+
+// CCLockRetryDelay - how long to wait before changing the state of a job again after its task failed on the deployment lock
+var CCLockRetryDelay = 5 * time.Second
+
+// CCLockRetries - how many more times a job whose task failed on the deployment lock is toggled
+var CCLockRetries = 60
+
+// CloudControllerJobs - array storing a list of CCJobs
 type CloudControllerJobs []CCJob
 
-//CloudController - a struct representing a cloud controller
+// CloudController - a struct representing a cloud controller
 type CloudController struct {
 	deploymentName   string
 	director         bosh.Bosh
 	cloudControllers CloudControllerJobs
 	manifest         string
+	directorIP       string
+	username         string
+	password         string
+	Concurrency      int
+	HTTPGateway      HttpGateway
+	Tasks            []CCTaskReport
+	mutex            sync.Mutex
 }
 
-//NewDirector - a function representing a constructor for a director object
-var NewDirector = func(ip, username, password string, port int) bosh.Bosh {
-	return bosh.NewBoshDirector(ip, username, password, port, NewHttpGateway())
+// String - the jobs as logged and reported
+func (s CloudControllerJobs) String() string {
+	jobs := make([]string, len(s))
+
+	for i, ccjob := range s {
+		jobs[i] = fmt.Sprintf("%s/%d", ccjob.Job, ccjob.Index)
+	}
+	return strings.Join(jobs, ", ")
+}
+
+// NewDirector - a function representing a constructor for a director object, authenticating with uaa tokens when the director takes them
+// and verifying the director with the given clients
+var NewDirector = func(ip, username, password string, port int, clients *TLSClients) bosh.Bosh {
+	return bosh.NewBoshDirector(ip, username, password, port, NewDirectorGateway(NewTLSGateway(TLSTargetDirector, clients), DirectorAuthFor(ip, username, password)))
 }
 
-//NewCloudController - a function representing a constructor for a cloud controller
-func NewCloudController(ip, username, password, deploymentName, manifest string, cloudControllers CloudControllerJobs) *CloudController {
-	director := NewDirector(ip, username, password, 25555)
+// NewCloudController - a function representing a constructor for a cloud controller, verifying the director with the given clients
+func NewCloudController(ip, username, password, deploymentName, manifest string, cloudControllers CloudControllerJobs, clients *TLSClients) *CloudController {
+	director := NewDirector(ip, username, password, 25555, clients)
 	return &CloudController{
 		deploymentName:   deploymentName,
 		director:         director,
 		cloudControllers: cloudControllers,
 		manifest:         manifest,
+		directorIP:       ip,
+		username:         username,
+		password:         password,
+		HTTPGateway:      NewDirectorGateway(NewTLSGateway(TLSTargetDirector, clients), DirectorAuthFor(ip, username, password)),
 	}
 }
 
-//Start - a method to execute a start event on a cloud controller
+// Start - a method to execute a start event on a cloud controller
 func (c *CloudController) Start() error {
-	return c.toggleController("started")
+	return c.StartWithContext(context.Background())
 }
 
-//Stop - a method which executes a stop against a cloud controller
+// Stop - a method which executes a stop against a cloud controller
 func (c *CloudController) Stop() error {
-	return c.toggleController("stopped")
+	return c.StopWithContext(context.Background())
 }
 
-func (c *CloudController) toggleController(state string) error {
-	for _, ccjob := range c.cloudControllers {
-		taskID, err := c.director.ChangeJobState(c.deploymentName, ccjob.Job, state, ccjob.Index, strings.NewReader(c.manifest))
-		if err != nil {
-			return err
+// StartWithContext - starts the cloud controllers, giving up on the bosh tasks once the context is done
+func (c *CloudController) StartWithContext(ctx context.Context) error {
+	return c.toggleController(ctx, CCJobStarted)
+}
+
+// StopWithContext - stops the cloud controllers, giving up on the bosh tasks once the context is done
+func (c *CloudController) StopWithContext(ctx context.Context) error {
+	return c.toggleController(ctx, CCJobStopped)
+}
+
+// Jobs - the cloud controller jobs this controller stops and starts
+func (c *CloudController) Jobs() CloudControllerJobs {
+	return c.cloudControllers
+}
+
+// ToggleJobWithContext - changes the state of a single cloud controller job and waits for the bosh task.
+// Bosh fails a task which can not take the deployment lock while another job's task holds it, so jobs toggled
+// concurrently are toggled again, after CCLockRetryDelay, up to CCLockRetries times.
+// issued is true once bosh accepted a task, even when waiting for it fails, safe for concurrent use
+func (c *CloudController) ToggleJobWithContext(ctx context.Context, ccjob CCJob, state string) (issued bool, err error) {
+	for attempt := 0; ; attempt++ {
+		var taskIssued bool
+		taskIssued, err = c.toggleJob(ctx, ccjob, state)
+		issued = issued || taskIssued
+
+		if err == nil || !strings.Contains(err.Error(), CCDeploymentLockedMsg) || attempt >= CCLockRetries {
+			return
 		}
-		err = c.waitUntilDone(taskID)
+		lo.G.Info(fmt.Sprintf("deployment %s is locked by another task, toggling %s again", c.deploymentName, CloudControllerJobs{ccjob}))
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-time.After(CCLockRetryDelay):
+		}
+	}
+}
+
+func (c *CloudController) toggleJob(ctx context.Context, ccjob CCJob, state string) (issued bool, err error) {
+	var taskID int
+	err = RunWithContext(ctx, func() (err error) {
+		taskID, err = c.director.ChangeJobState(c.deploymentName, ccjob.Job, state, ccjob.Index, strings.NewReader(c.manifest))
+		return
+	})
+	if err != nil {
+		return
+	}
+	issued = true
+	err = c.waitUntilDone(ctx, taskID)
+	task := CCTaskReport{Job: ccjob.Job, Index: ccjob.Index, State: state, TaskID: taskID, Finished: time.Now()}
+	task.Result, _ = outcome(err)
+	c.mutex.Lock()
+	c.Tasks = append(c.Tasks, task)
+	c.mutex.Unlock()
+	return
+}
+
+// toggleController - changes the state of the jobs, up to Concurrency at a time. No further jobs are
+// toggled once one fails, and the first failure in job order is returned
+func (c *CloudController) toggleController(ctx context.Context, state string) error {
+	errs := ToggleJobs(c.cloudControllers, c.Concurrency, true, func(ccjob CCJob) (err error) {
+		_, err = c.ToggleJobWithContext(ctx, ccjob, state)
+		return
+	})
+
+	return firstJobError(errs)
+}
+
+func firstJobError(errs []error) error {
+	for _, err := range errs {
 		if err != nil {
 			return err
 		}
@@ -63,26 +166,127 @@ func (c *CloudController) toggleController(state string) error {
 	return nil
 }
 
-func (c *CloudController) waitUntilDone(taskID int) (err error) {
-	time.Sleep(TaskPingFreq)
-	result, err := c.director.RetrieveTaskStatus(taskID)
+// ToggleJobs - runs toggle for each job, up to concurrency at a time in job order. With failFast, once a toggle
+// fails no further ones are started and their errors are ErrCCToggleSkipped. The errors are returned in job order
+func ToggleJobs(jobs CloudControllerJobs, concurrency int, failFast bool, toggle func(CCJob) error) (errs []error) {
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		failed bool
+	)
+	errs = make([]error, len(jobs))
+	workers := make(chan struct{}, maxInt(concurrency, 1))
+
+	for i, ccjob := range jobs {
+		workers <- struct{}{}
+		mutex.Lock()
+		skip := failed
+		mutex.Unlock()
+
+		if skip {
+			<-workers
+			errs[i] = ErrCCToggleSkipped
+			continue
+		}
+		wg.Add(1)
+
+		go func(i int, ccjob CCJob) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+
+			if errs[i] = toggle(ccjob); errs[i] != nil && failFast {
+				mutex.Lock()
+				failed = true
+				mutex.Unlock()
+			}
+		}(i, ccjob)
+	}
+	wg.Wait()
+	return
+}
+
+// waitUntilDone - polls the bosh task, backing off from TaskPingFreq to TaskPingMaxFreq, until it
+// finishes, ctx is done or TaskTimeout passes. A failed task's error carries its bosh event output
+func (c *CloudController) waitUntilDone(ctx context.Context, taskID int) (err error) {
+	var (
+		result *bosh.Task
+		state  = "queued"
+		delay  = TaskPingFreq
+		cancel = func() {}
+	)
+	taskCtx := ctx
+
+	if TaskTimeout > 0 {
+		taskCtx, cancel = context.WithTimeout(ctx, TaskTimeout)
+	}
+	defer cancel()
+
+	for {
+		select {
+		case <-taskCtx.Done():
+			if err = ctx.Err(); err == nil {
+				err = fmt.Errorf("%w: task %d still %s after %s", ErrCCTaskTimeout, taskID, state, TaskTimeout)
+			}
+			return
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > TaskPingMaxFreq {
+			delay = TaskPingMaxFreq
+		}
+		err = RunWithContext(taskCtx, func() (err error) {
+			result, err = c.director.RetrieveTaskStatus(taskID)
+			return
+		})
+		if err != nil {
+			return
+		}
+		state = result.State
+
+		switch bosh.TASKRESULT[result.State] {
+		case bosh.ERROR:
+			return fmt.Errorf("Task %d process failed: %s", taskID, c.taskOutput(taskID, result))
+		case bosh.QUEUED, bosh.PROCESSING:
+			continue
+		case bosh.DONE:
+			return nil
+		default:
+			return bosh.ErrorTaskResultUnknown
+		}
+	}
+}
+
+// taskOutput - the result of a failed task, followed by its event output up to CCTaskOutputLimit when the director returns it
+func (c *CloudController) taskOutput(taskID int, task *bosh.Task) string {
+	output := task.Result
+
+	if c.HTTPGateway == nil || c.directorIP == "" {
+		return output
+	}
+	resp, err := c.HTTPGateway.Get(HttpRequestEntity{
+		Url:         fmt.Sprintf(ERTaskOutputURL, c.directorIP, taskID),
+		Username:    c.username,
+		Password:    c.password,
+		ContentType: "application/json",
+	})()
+
 	if err != nil {
-		return
+		return output
 	}
-	switch bosh.TASKRESULT[result.State] {
-	case bosh.ERROR:
-		err = fmt.Errorf("Task %d process failed", taskID)
-		return
-	case bosh.QUEUED:
-		err = c.waitUntilDone(taskID)
-		return
-	case bosh.PROCESSING:
-		err = c.waitUntilDone(taskID)
-		return
-	case bosh.DONE:
-		return
-	default:
-		err = bosh.ErrorTaskResultUnknown
-		return
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, CCTaskOutputLimit))
+
+	if events := strings.TrimSpace(string(body)); events != "" {
+		output = strings.TrimSpace(output + "\n" + events)
+	}
+	return output
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
 	}
+	return b
 }
@@ -4,13 +4,16 @@ import (
 	"errors"
 	"io"
 	"strings"
+	"sync"
 	"time"
 
 	. "github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/fakes"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/pivotalservices/gtils/bosh"
+	ghttp "github.com/pivotalservices/gtils/http"
 )
 
 var (
@@ -61,13 +64,67 @@ func (director *mockDirector) RetrieveTaskStatus(int) (*bosh.Task, error) {
 	return &task, nil
 }
 
+// lockingDirector - a director which, like bosh, runs a single task per deployment at a time and fails
+// a task which finds the deployment lock taken by another
+type lockingDirector struct {
+	mockDirector
+	mutex   sync.Mutex
+	tasks   map[int]*lockingTask
+	holder  int
+	changes int
+}
+
+type lockingTask struct {
+	job   string
+	state string
+	polls int
+}
+
+func (director *lockingDirector) ChangeJobState(deploymentName, jobName, state string, index int, manifest io.Reader) (int, error) {
+	director.mutex.Lock()
+	defer director.mutex.Unlock()
+	director.changes++
+	director.tasks[director.changes] = &lockingTask{job: jobName, state: "queued"}
+	return director.changes, nil
+}
+
+func (director *lockingDirector) RetrieveTaskStatus(taskID int) (*bosh.Task, error) {
+	director.mutex.Lock()
+	defer director.mutex.Unlock()
+	task := director.tasks[taskID]
+
+	switch {
+	case task.state == "queued" && director.holder != 0:
+		task.state = "error"
+		return &bosh.Task{State: task.state, Result: "Failed to acquire lock for lock:deployment:deployment uid: 1"}, nil
+	case task.state == "queued":
+		director.holder, task.state = taskID, "processing"
+	case task.state == "processing":
+		if task.polls++; task.polls >= 3 {
+			director.holder, task.state = 0, "done"
+		}
+	}
+	return &bosh.Task{State: task.state}, nil
+}
+
+func (director *lockingDirector) doneJobs() (jobs []string) {
+	director.mutex.Lock()
+	defer director.mutex.Unlock()
+	for _, task := range director.tasks {
+		if task.state == "done" {
+			jobs = append(jobs, task.job)
+		}
+	}
+	return
+}
+
 var _ = Describe("ToggleCcJob", func() {
-	NewDirector = func(ip, username, password string, port int) bosh.Bosh {
+	NewDirector = func(ip, username, password string, port int, clients *TLSClients) bosh.Bosh {
 		return &mockDirector{}
 	}
 	TaskPingFreq = time.Millisecond
 	var (
-		cloudController *CloudController = NewCloudController(ip, username, password, deploymentName, "manifest", ccjobs)
+		cloudController *CloudController = NewCloudController(ip, username, password, deploymentName, "manifest", ccjobs, nil)
 	)
 	Describe("Toggle All jobs", func() {
 		Context("Change Job State failed", func() {
@@ -102,12 +159,147 @@ var _ = Describe("ToggleCcJob", func() {
 		Context("Task status is error", func() {
 			BeforeEach(func() {
 				changeJobState = true
-				task = bosh.Task{State: "error"}
+				task = bosh.Task{State: "error", Result: "Action Failed get_task"}
+				cloudController.HTTPGateway = &fakes.MockHTTPGateway{CheckFailureCondition: true, State: "monit start failed\n"}
+			})
+			AfterEach(func() {
+				cloudController.HTTPGateway = ghttp.NewHttpGateway()
 			})
 			It("Should return error", func() {
 				err := cloudController.Start()
 				Ω(err).ShouldNot(BeNil())
 			})
+			It("Should include the task result and event output in the error", func() {
+				err := cloudController.Start()
+				Ω(err).Should(MatchError("Task 1 process failed: Action Failed get_task\nmonit start failed"))
+			})
+		})
+		Context("Task never leaves the queue", func() {
+			var taskTimeoutOrig = TaskTimeout
+
+			BeforeEach(func() {
+				changeJobState = true
+				task = bosh.Task{State: "queued"}
+				TaskTimeout = 20 * time.Millisecond
+			})
+			AfterEach(func() {
+				TaskTimeout = taskTimeoutOrig
+			})
+			It("Should give up once the task timeout passes", func() {
+				err := cloudController.Start()
+				Ω(err).Should(MatchError(ErrCCTaskTimeout))
+				Ω(err.Error()).Should(ContainSubstring("task 1 still queued"))
+			})
+		})
+	})
+
+	Describe("Toggle jobs concurrently on a locked deployment", func() {
+		var (
+			director            *lockingDirector
+			lockingController   *CloudController
+			ccLockRetryDelay    = CCLockRetryDelay
+			ccLockRetries       = CCLockRetries
+			newDirectorOrig     = NewDirector
+			taskPingMaxFreqOrig = TaskPingMaxFreq
+		)
+
+		BeforeEach(func() {
+			director = &lockingDirector{tasks: map[int]*lockingTask{}}
+			NewDirector = func(ip, username, password string, port int, clients *TLSClients) bosh.Bosh {
+				return director
+			}
+			lockingController = NewCloudController(ip, username, password, deploymentName, "manifest", ccjobs, nil)
+			lockingController.HTTPGateway = nil
+			lockingController.Concurrency = len(ccjobs)
+			CCLockRetryDelay = time.Millisecond
+			TaskPingMaxFreq = 2 * time.Millisecond
+		})
+
+		AfterEach(func() {
+			NewDirector = newDirectorOrig
+			CCLockRetryDelay = ccLockRetryDelay
+			CCLockRetries = ccLockRetries
+			TaskPingMaxFreq = taskPingMaxFreqOrig
+		})
+
+		It("Should toggle every job again until its task gets the deployment lock", func() {
+			err := lockingController.Stop()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(director.doneJobs()).Should(ConsistOf("job1", "job2", "job3"))
+			Ω(director.changes).Should(BeNumerically(">", len(ccjobs)))
+		})
+
+		It("Should toggle one job at a time without contending for the lock by default", func() {
+			lockingController.Concurrency = DefaultCCToggleConcurrency
+			err := lockingController.Stop()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(director.doneJobs()).Should(ConsistOf("job1", "job2", "job3"))
+			Ω(director.changes).Should(Equal(len(ccjobs)))
+		})
+
+		It("Should fail on the deployment lock once the retries are used up", func() {
+			CCLockRetries = 0
+			err := lockingController.Stop()
+			Ω(err).Should(MatchError(ContainSubstring(CCDeploymentLockedMsg)))
+		})
+	})
+
+	Describe("ToggleJobs", func() {
+		var (
+			jobs     = CloudControllerJobs{{Job: "job1"}, {Job: "job2"}, {Job: "job3"}, {Job: "job4"}}
+			mutex    sync.Mutex
+			inFlight int
+			peak     int
+			toggled  []string
+		)
+		toggle := func(fail string) func(CCJob) error {
+			return func(ccjob CCJob) error {
+				mutex.Lock()
+				inFlight++
+				if inFlight > peak {
+					peak = inFlight
+				}
+				toggled = append(toggled, ccjob.Job)
+				mutex.Unlock()
+				time.Sleep(5 * time.Millisecond)
+				mutex.Lock()
+				inFlight--
+				mutex.Unlock()
+
+				if ccjob.Job == fail {
+					return errors.New("toggle failed")
+				}
+				return nil
+			}
+		}
+
+		BeforeEach(func() {
+			inFlight, peak, toggled = 0, 0, nil
+		})
+
+		Context("when every toggle succeeds", func() {
+			It("Should toggle every job, no more than concurrency at a time", func() {
+				errs := ToggleJobs(jobs, 2, true, toggle(""))
+				Ω(errs).Should(Equal([]error{nil, nil, nil, nil}))
+				Ω(toggled).Should(ConsistOf("job1", "job2", "job3", "job4"))
+				Ω(peak).Should(Equal(2))
+			})
+		})
+
+		Context("when a toggle fails", func() {
+			It("Should skip the jobs not yet started when failing fast", func() {
+				errs := ToggleJobs(jobs, 1, true, toggle("job2"))
+				Ω(errs[1]).Should(MatchError("toggle failed"))
+				Ω(errs[2]).Should(Equal(ErrCCToggleSkipped))
+				Ω(errs[3]).Should(Equal(ErrCCToggleSkipped))
+				Ω(toggled).Should(Equal([]string{"job1", "job2"}))
+			})
+
+			It("Should toggle the remaining jobs when not failing fast", func() {
+				errs := ToggleJobs(jobs, 1, false, toggle("job2"))
+				Ω(errs).Should(Equal([]error{nil, errors.New("toggle failed"), nil, nil}))
+				Ω(toggled).Should(Equal([]string{"job1", "job2", "job3", "job4"}))
+			})
 		})
 	})
 })
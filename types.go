@@ -1,10 +1,19 @@
 package cfbackup
 
 import (
+	"bufio"
+	"context"
 	"crypto/cipher"
+	"hash"
 	"io"
 	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
+	logging "github.com/op/go-logging"
 	"github.com/pivotalservices/gtils/command"
 	ghttp "github.com/pivotalservices/gtils/http"
 	"github.com/xchapter7x/goutil"
@@ -31,6 +40,14 @@ type (
 		BackupType string
 	}
 
+	//RemoteCommandBackup - a persistence backup which runs configurable dump and restore commands on a remote vm
+	RemoteCommandBackup struct {
+		Caller         command.Executer
+		RemoteOps      remoteOpsInterface
+		DumpCommand    string
+		RestoreCommand string
+	}
+
 	//BackupContext - stores the base context information for a backup/restore
 	BackupContext struct {
 		TargetDir string
@@ -61,6 +78,22 @@ type (
 	Tile interface {
 		Backup() error
 		Restore() error
+		Verify() (VerificationReport, error)
+	}
+
+	//ArtifactCheck - a function which validates the contents of a single backup artifact
+	ArtifactCheck func(io.Reader) error
+
+	//ArtifactVerification - the pass/fail outcome of checking a single backup artifact
+	ArtifactVerification struct {
+		Artifact string `json:"artifact"`
+		Passed   bool   `json:"passed"`
+		Reason   string `json:"reason,omitempty"`
+	}
+
+	//VerificationReport - the pass/fail outcome for every artifact in a backup set
+	VerificationReport struct {
+		Artifacts []ArtifactVerification `json:"artifacts"`
 	}
 
 	//InstallationSettings - an object to house installationsettings elements from the json
@@ -175,6 +208,228 @@ type (
 		Import(io.Reader) error
 	}
 
+	//ContextPersistanceBackup - a persistence backup whose dump and import stop when the given context is done
+	ContextPersistanceBackup interface {
+		PersistanceBackup
+		DumpWithContext(context.Context, io.Writer) error
+		ImportWithContext(context.Context, io.Reader) error
+	}
+
+	//PhaseTimeouts - deadlines for the long running phases of a backup or restore, a zero value means no deadline
+	PhaseTimeouts struct {
+		CCStop  time.Duration `json:"cc_stop"`
+		DBDump  time.Duration `json:"db_dump"`
+		NFSDump time.Duration `json:"nfs_dump"`
+		Upload  time.Duration `json:"upload"`
+	}
+
+	//RunReport - the machine readable outcome of a single backup or restore of a tile
+	RunReport struct {
+		Tile       string             `json:"tile"`
+		Action     string             `json:"action"`
+		Mode       string             `json:"mode,omitempty"`
+		Status     string             `json:"status"`
+		Started    time.Time          `json:"started"`
+		Finished   time.Time          `json:"finished"`
+		Components []*ComponentReport `json:"components"`
+		CCTasks    []CCTaskReport     `json:"cc_tasks,omitempty"`
+		Remaps     []*RoleRemap       `json:"remaps,omitempty"`
+		Warnings   []string           `json:"warnings,omitempty"`
+		Errors     []string           `json:"errors,omitempty"`
+	}
+
+	//ComponentReport - the outcome of backing up or restoring a single component of a tile
+	ComponentReport struct {
+		Name     string    `json:"name"`
+		Status   string    `json:"status"`
+		Artifact string    `json:"artifact"`
+		Started  time.Time `json:"started"`
+		Finished time.Time `json:"finished"`
+		Bytes    int64     `json:"bytes"`
+		SHA256   string    `json:"sha256,omitempty"`
+		Errors   []string  `json:"errors,omitempty"`
+		Reused   bool      `json:"reused,omitempty"`
+		hash     hash.Hash
+		mutex    sync.Mutex
+	}
+
+	//Checkpoint - the components a backup run completed, kept in the backup set so a failed run can be resumed
+	Checkpoint struct {
+		Started    time.Time                       `json:"started"`
+		Finished   bool                            `json:"finished"`
+		Components map[string]*ComponentCheckpoint `json:"components"`
+		mutex      sync.Mutex
+	}
+
+	//ComponentCheckpoint - a component artifact which was completely written
+	ComponentCheckpoint struct {
+		Artifact  string    `json:"artifact"`
+		Bytes     int64     `json:"bytes"`
+		SHA256    string    `json:"sha256"`
+		Completed time.Time `json:"completed"`
+	}
+
+	//CCTaskReport - a bosh task which stopped or started a cloud controller job
+	CCTaskReport struct {
+		Job      string    `json:"job"`
+		Index    int       `json:"index"`
+		State    string    `json:"state"`
+		TaskID   int       `json:"task_id"`
+		Result   string    `json:"result,omitempty"`
+		Finished time.Time `json:"finished"`
+	}
+
+	//HookEvent - the json description of the run handed to every hook
+	HookEvent struct {
+		Point     string    `json:"point"`
+		Action    string    `json:"action"`
+		Tile      string    `json:"tile"`
+		Component string    `json:"component,omitempty"`
+		TargetDir string    `json:"target_dir"`
+		Time      time.Time `json:"time"`
+		Error     string    `json:"error,omitempty"`
+	}
+
+	//Hook - an action run around a tile or one of its components
+	Hook interface {
+		Run(ctx context.Context, event HookEvent) error
+	}
+
+	//HookFunc - a go callback used as a hook
+	HookFunc func(ctx context.Context, event HookEvent) error
+
+	//ExecutableHook - a local executable used as a hook, given the event as json on stdin and in its environment
+	ExecutableHook struct {
+		Path string
+		Args []string
+	}
+
+	//HookSet - the hooks run at each hook point of a tile, a failing pre hook aborts the run
+	HookSet struct {
+		PreTile       []Hook
+		PostTile      []Hook
+		PreComponent  []Hook
+		PostComponent []Hook
+	}
+
+	//Lease - the contents of a run lock, naming who holds it and until when
+	Lease struct {
+		Key      string        `json:"key"`
+		Owner    string        `json:"owner"`
+		Token    string        `json:"token"`
+		Acquired time.Time     `json:"acquired"`
+		Renewed  time.Time     `json:"renewed"`
+		TTL      time.Duration `json:"ttl"`
+		Released bool          `json:"released"`
+	}
+
+	//LeaseLock - a lock on a foundation, held as a lease stored through a StorageProvider and kept alive by heartbeats
+	LeaseLock struct {
+		StorageProvider StorageProvider
+		Path            []string
+		Key             string
+		Owner           string
+		TTL             time.Duration
+		Heartbeat       time.Duration
+		Force           bool
+		lease           *Lease
+		lost            chan struct{}
+		stop            chan struct{}
+		stopped         chan struct{}
+		mutex           sync.Mutex
+	}
+
+	//RoleRemap - a database role of the source foundation renamed to its target counterpart while importing a component
+	RoleRemap struct {
+		Component string `json:"component"`
+		Source    string `json:"source"`
+		Target    string `json:"target"`
+		Rewrites  int    `json:"rewrites"`
+	}
+
+	//DirectorAuth - authenticates requests to a bosh director, with a uaa token when the director's info says it
+	//takes them and basic auth with the director credentials otherwise
+	DirectorAuth struct {
+		IP           string
+		Username     string
+		Password     string
+		ClientID     string
+		ClientSecret string
+		uaaURL       string
+		token        string
+		issued       time.Time
+		detected     bool
+		mutex        sync.Mutex
+	}
+
+	//DirectorGateway - an HttpGateway to a bosh director, authenticating every request through its DirectorAuth
+	DirectorGateway struct {
+		Gateway ghttp.HttpGateway
+		Auth    *DirectorAuth
+	}
+
+	//TLSConfig - how ops manager, director and uaa servers are verified. A CA is a pem file path or the pem itself,
+	//a pin the base64 sha256 digest of a public key in the server's verified chain, or of the server's own key when
+	//there is no CA. Verification is only turned off with Insecure
+	TLSConfig struct {
+		OpsManagerCA string   `json:"ops_manager_ca"`
+		DirectorCA   string   `json:"director_ca"`
+		UAACA        string   `json:"uaa_ca"`
+		Pins         []string `json:"pins"`
+		Insecure     bool     `json:"insecure"`
+	}
+
+	//TLSClients - the http clients of a tile or gateway, one per TLSTargets, verifying their servers with its TLSConfig
+	TLSClients struct {
+		clients map[string]*http.Client
+	}
+
+	//TLSGateway - an HttpGateway verifying the servers of its target with its clients, the system roots when nil
+	TLSGateway struct {
+		Target  string
+		Clients *TLSClients
+		ctx     context.Context
+	}
+
+	//ContextHTTPGateway - an http gateway whose requests can be bound to a context
+	ContextHTTPGateway interface {
+		ghttp.HttpGateway
+		WithContext(ctx context.Context) ghttp.HttpGateway
+	}
+
+	directorInfo struct {
+		UserAuthentication struct {
+			Type    string `json:"type"`
+			Options struct {
+				URL string `json:"url"`
+			} `json:"options"`
+		} `json:"user_authentication"`
+	}
+
+	roleRemappingReader struct {
+		reader   *bufio.Reader
+		remaps   []*RoleRemap
+		patterns []*regexp.Regexp
+		pending  []byte
+		err      error
+	}
+
+	reportingStream struct {
+		component *ComponentReport
+		reader    io.Reader
+		writer    io.Writer
+	}
+
+	contextWriter struct {
+		ctx    context.Context
+		writer io.Writer
+	}
+
+	contextReader struct {
+		ctx    context.Context
+		reader io.Reader
+	}
+
 	stringGetterSetter interface {
 		Get(string) string
 		Set(string, string)
@@ -208,7 +463,8 @@ type (
 	//MysqlInfo - a struct representing a mysql systemdump implementation
 	MysqlInfo struct {
 		SystemInfo
-		Database string
+		Database          string
+		SingleTransaction bool
 	}
 	//NfsInfo - a struct representing a nfs systemdump implementation
 	NfsInfo struct {
@@ -220,6 +476,13 @@ type (
 		SystemInfo
 		Database string
 	}
+	//RemoteCommandInfo - a struct representing a systemdump whose dump and restore commands are templated against its SystemInfo
+	RemoteCommandInfo struct {
+		SystemInfo
+		DumpCommand    string
+		RestoreCommand string
+		Format         string
+	}
 	//SystemsInfo holds the values for all the supported SystemDump used by an installation
 	SystemsInfo struct {
 		SystemDumps map[string]SystemDump
@@ -251,4 +514,46 @@ type (
 		Path() string
 		RemoveRemoteFile() (err error)
 	}
+
+	//Redactor - masks the secrets resolved during a run, and the values of secret looking properties and headers, in log output
+	Redactor struct {
+		secrets  map[string]struct{}
+		replacer *strings.Replacer
+		mutex    sync.RWMutex
+	}
+
+	//RedactingBackend - a logging backend which redacts every record before passing it to the wrapped backend.
+	//Levels are left to the module levels set on the logging package
+	RedactingBackend struct {
+		Backend  logging.Backend
+		Redactor *Redactor
+	}
+
+	redactedArg struct {
+		value    interface{}
+		redactor *Redactor
+	}
+
+	//CCToggler - stops and starts single cloud controller jobs
+	CCToggler interface {
+		Jobs() CloudControllerJobs
+		ToggleJobWithContext(ctx context.Context, ccjob CCJob, state string) (issued bool, err error)
+	}
+
+	//CCSupervisor - records which cloud controller jobs a run actually stopped, and starts exactly those
+	//again on every exit path of the run: returning, panicking or the process being signalled
+	CCSupervisor struct {
+		Toggler     CCToggler
+		Concurrency int
+		Retries     int
+		RetryDelay  time.Duration
+		Timeout     time.Duration
+		Signals     []os.Signal
+		stopped     CloudControllerJobs
+		restarting  bool
+		signals     chan os.Signal
+		done        chan struct{}
+		mutex       sync.Mutex
+		restart     sync.Mutex
+	}
 )
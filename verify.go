@@ -0,0 +1,318 @@
+package cfbackup
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+const (
+	pgDumpMagic              = "PGDMP"
+	pgDumpCustomFormat       = 1
+	pgDumpPlainHeader        = "-- PostgreSQL database dump"
+	pgDumpPlainTrailer       = "-- PostgreSQL database dump complete"
+	mysqlDumpTrailer         = "-- Dump completed"
+	dumpTrailerSearchSize    = 4096
+	verifyTempFilePrefix     = "cfbackup-verify"
+	pgDumpMaxHeaderFieldSize = 8
+)
+
+//Record - adds the outcome of an artifact check to the report
+func (s *VerificationReport) Record(artifact string, err error) {
+	verification := ArtifactVerification{
+		Artifact: artifact,
+		Passed:   err == nil,
+	}
+
+	if err != nil {
+		verification.Reason = err.Error()
+	}
+	s.Artifacts = append(s.Artifacts, verification)
+}
+
+//Passed - true when every artifact in the report passed its check
+func (s VerificationReport) Passed() bool {
+	return len(s.Failures()) == 0
+}
+
+//Failures - returns the artifacts which did not pass their check
+func (s VerificationReport) Failures() (failures []ArtifactVerification) {
+	for _, artifact := range s.Artifacts {
+		if !artifact.Passed {
+			failures = append(failures, artifact)
+		}
+	}
+	return
+}
+
+//VerifyArtifact - reads the artifact at the given path from the storage provider and runs the check against it
+func VerifyArtifact(storageProvider StorageProvider, check ArtifactCheck, filepath ...string) (err error) {
+	var reader io.ReadCloser
+
+	if reader, err = storageProvider.Reader(filepath...); err == nil {
+		defer reader.Close()
+		err = check(reader)
+	}
+	return
+}
+
+//GetArtifactCheck - returns the check matching the kind of archive the given systemdump produces
+func GetArtifactCheck(dump SystemDump) ArtifactCheck {
+	switch info := dump.(type) {
+	case *PgInfo, *DirectorInfo:
+		return VerifyPgDump
+	case *MysqlInfo:
+		return VerifyMysqlDump
+	case *NfsInfo:
+		return VerifyTarGz
+	case *RemoteCommandInfo:
+		return GetArtifactCheckForFormat(info.Format)
+	default:
+		return VerifyReadable
+	}
+}
+
+//GetArtifactCheckForFormat - returns the check for a named archive format, defaulting to a readability check
+func GetArtifactCheckForFormat(format string) ArtifactCheck {
+	switch format {
+	case ArtifactFormatPgDump:
+		return VerifyPgDump
+	case ArtifactFormatMysqlDump:
+		return VerifyMysqlDump
+	case ArtifactFormatTarGz:
+		return VerifyTarGz
+	default:
+		return VerifyReadable
+	}
+}
+
+//VerifyReadable - checks that an artifact is non-empty and can be read end-to-end
+func VerifyReadable(src io.Reader) (err error) {
+	var n int64
+
+	if n, err = io.Copy(ioutil.Discard, src); err == nil && n == 0 {
+		err = fmt.Errorf("artifact is empty")
+	}
+	return
+}
+
+//VerifyTarGz - checks that an artifact is a gzipped tarball which can be read end-to-end
+func VerifyTarGz(src io.Reader) (err error) {
+	var gzipReader *gzip.Reader
+
+	if gzipReader, err = gzip.NewReader(src); err != nil {
+		return fmt.Errorf("not a valid gzip stream: %s", err)
+	}
+	defer gzipReader.Close()
+	tarReader := tar.NewReader(gzipReader)
+
+	for {
+		var header *tar.Header
+
+		if header, err = tarReader.Next(); err == io.EOF {
+			return nil
+
+		} else if err != nil {
+			return fmt.Errorf("not a valid tar archive: %s", err)
+		}
+
+		if _, err = io.Copy(ioutil.Discard, tarReader); err != nil {
+			return fmt.Errorf("unable to read %s from archive: %s", header.Name, err)
+		}
+	}
+}
+
+//VerifyZipContains - returns a check that the artifact is a zip archive holding all of the given files
+func VerifyZipContains(filenames ...string) ArtifactCheck {
+	return func(src io.Reader) (err error) {
+		var tmpfile *os.File
+
+		if tmpfile, err = ioutil.TempFile("", verifyTempFilePrefix); err != nil {
+			return
+		}
+		defer os.Remove(tmpfile.Name())
+		defer tmpfile.Close()
+
+		if _, err = io.Copy(tmpfile, src); err == nil {
+			var zipReader *zip.ReadCloser
+
+			if zipReader, err = zip.OpenReader(tmpfile.Name()); err != nil {
+				return fmt.Errorf("not a valid zip archive: %s", err)
+			}
+			defer zipReader.Close()
+			err = zipContains(zipReader.File, filenames)
+		}
+		return
+	}
+}
+
+func zipContains(files []*zip.File, filenames []string) error {
+	found := make(map[string]bool)
+
+	for _, file := range files {
+		found[path.Base(file.Name)] = true
+	}
+
+	for _, filename := range filenames {
+		if !found[filename] {
+			return fmt.Errorf("zip archive does not contain %s", filename)
+		}
+	}
+	return nil
+}
+
+//VerifyMysqlDump - checks that a mysqldump ran to completion by looking for its trailer
+func VerifyMysqlDump(src io.Reader) (err error) {
+	var tail []byte
+
+	if tail, err = readTail(src, dumpTrailerSearchSize); err == nil && !bytes.Contains(tail, []byte(mysqlDumpTrailer)) {
+		err = fmt.Errorf("mysql dump is incomplete: trailer %q not found", mysqlDumpTrailer)
+	}
+	return
+}
+
+//VerifyPgDump - checks the header and table of contents of a custom format pg_dump archive,
+//or the header and trailer of a plain format dump
+func VerifyPgDump(src io.Reader) (err error) {
+	bufferedReader := bufio.NewReader(src)
+	var magic []byte
+
+	if magic, err = bufferedReader.Peek(len(pgDumpMagic)); err != nil {
+		return fmt.Errorf("pg dump is too short to hold a header: %s", err)
+	}
+
+	if string(magic) == pgDumpMagic {
+		if err = readPgDumpHeader(bufferedReader); err == nil {
+			_, err = io.Copy(ioutil.Discard, bufferedReader)
+		}
+		return
+	}
+	return verifyPlainPgDump(bufferedReader)
+}
+
+func verifyPlainPgDump(src *bufio.Reader) (err error) {
+	var head []byte
+	head, _ = src.Peek(len(pgDumpPlainHeader) + 4)
+
+	if !bytes.Contains(head, []byte(pgDumpPlainHeader)) {
+		return fmt.Errorf("not a pg dump: neither a custom format archive nor a plain format dump")
+	}
+	var tail []byte
+
+	if tail, err = readTail(src, dumpTrailerSearchSize); err == nil && !bytes.Contains(tail, []byte(pgDumpPlainTrailer)) {
+		err = fmt.Errorf("pg dump is incomplete: trailer %q not found", pgDumpPlainTrailer)
+	}
+	return
+}
+
+type pgDumpHeaderReader struct {
+	src     io.Reader
+	intSize int
+	err     error
+}
+
+// readPgDumpHeader walks the archive header written by pg_dump -Fc far enough
+// to read the table of contents entry count
+func readPgDumpHeader(src io.Reader) error {
+	var fixed [len(pgDumpMagic) + 6]byte
+
+	if _, err := io.ReadFull(src, fixed[:]); err != nil {
+		return fmt.Errorf("pg dump header is truncated: %s", err)
+	}
+	vmaj, vmin := fixed[5], fixed[6]
+	intSize, offSize, format := int(fixed[8]), int(fixed[9]), fixed[10]
+
+	switch {
+	case vmaj != 1:
+		return fmt.Errorf("unsupported pg dump archive version %d.%d", vmaj, vmin)
+	case intSize < 1 || intSize > pgDumpMaxHeaderFieldSize || offSize < 1 || offSize > pgDumpMaxHeaderFieldSize:
+		return fmt.Errorf("pg dump header has invalid integer sizes int=%d offset=%d", intSize, offSize)
+	case format != pgDumpCustomFormat:
+		return fmt.Errorf("pg dump is not in custom archive format: format %d", format)
+	}
+	header := &pgDumpHeaderReader{src: src, intSize: intSize}
+
+	if vmin >= 15 {
+		header.readBytes(1)
+	} else {
+		header.readInt()
+	}
+
+	for i := 0; i < 7; i++ {
+		header.readInt()
+	}
+	header.readString()
+
+	if vmin >= 10 {
+		header.readString()
+		header.readString()
+	}
+
+	if tocCount := header.readInt(); header.err != nil {
+		return fmt.Errorf("pg dump header is truncated: %s", header.err)
+
+	} else if tocCount <= 0 {
+		return fmt.Errorf("pg dump table of contents is empty")
+	}
+	return nil
+}
+
+func (s *pgDumpHeaderReader) readBytes(n int) (b []byte) {
+	if s.err == nil {
+		b = make([]byte, n)
+		_, s.err = io.ReadFull(s.src, b)
+	}
+	return
+}
+
+func (s *pgDumpHeaderReader) readInt() (value int64) {
+	signAndValue := s.readBytes(s.intSize + 1)
+
+	if s.err == nil {
+		var buf [8]byte
+		copy(buf[:], signAndValue[1:])
+		value = int64(binary.LittleEndian.Uint64(buf[:]))
+
+		if signAndValue[0] != 0 {
+			value = -value
+		}
+	}
+	return
+}
+
+func (s *pgDumpHeaderReader) readString() {
+	if length := s.readInt(); length > 0 {
+		s.readBytes(int(length))
+	}
+}
+
+func readTail(src io.Reader, size int) (tail []byte, err error) {
+	buf := make([]byte, 2*size)
+	filled := 0
+
+	for {
+		var n int
+		n, err = src.Read(buf[filled:])
+		filled += n
+
+		if filled == len(buf) {
+			copy(buf, buf[size:])
+			filled = size
+		}
+
+		if err == io.EOF {
+			return buf[:filled], nil
+
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package cfbackup_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotalservices/cfbackup"
+	"github.com/pivotalservices/cfbackup/fakes"
+)
+
+var _ = Describe("Verify", func() {
+	Describe("given a VerificationReport", func() {
+		Context("when every recorded artifact passed", func() {
+			var report VerificationReport
+			BeforeEach(func() {
+				report = VerificationReport{}
+				report.Record("a.backup", nil)
+				report.Record("b.backup", nil)
+			})
+			It("then it should pass", func() {
+				Ω(report.Passed()).Should(BeTrue())
+				Ω(report.Failures()).Should(BeEmpty())
+				Ω(report.Artifacts).Should(HaveLen(2))
+			})
+		})
+		Context("when one recorded artifact failed", func() {
+			var report VerificationReport
+			BeforeEach(func() {
+				report = VerificationReport{}
+				report.Record("a.backup", nil)
+				report.Record("b.backup", io.ErrUnexpectedEOF)
+			})
+			It("then it should fail and list the failing artifact with its reason", func() {
+				Ω(report.Passed()).Should(BeFalse())
+				Ω(report.Failures()).Should(Equal([]ArtifactVerification{
+					{Artifact: "b.backup", Passed: false, Reason: io.ErrUnexpectedEOF.Error()},
+				}))
+			})
+		})
+	})
+
+	Describe("given a VerifyArtifact function", func() {
+		Context("when the storage provider can not open the artifact", func() {
+			It("then it should return the storage error", func() {
+				msp := fakes.NewMockStringStorageProvider()
+				msp.ErrFakeResponse = io.ErrClosedPipe
+				Ω(VerifyArtifact(msp, VerifyReadable, "some", "path")).Should(Equal(io.ErrClosedPipe))
+			})
+		})
+		Context("when the artifact is readable", func() {
+			It("then it should run the check against the artifact contents", func() {
+				msp := fakes.NewMockStringStorageProvider()
+				msp.WriteString("contents")
+				Ω(VerifyArtifact(msp, VerifyReadable, "some", "path")).Should(Succeed())
+			})
+		})
+	})
+
+	Describe("given a VerifyTarGz check", func() {
+		It("then it should pass a complete gzipped tarball", func() {
+			archive := fakes.NewTarGz(map[string]string{"shared/cc-buildpacks/bp.zip": "buildpack"})
+			Ω(VerifyTarGz(bytes.NewReader(archive))).Should(Succeed())
+		})
+		It("then it should fail a truncated gzipped tarball", func() {
+			archive := fakes.NewTarGz(map[string]string{"shared/cc-buildpacks/bp.zip": strings.Repeat("buildpack", 1000)})
+			Ω(VerifyTarGz(bytes.NewReader(archive[:len(archive)/2]))).ShouldNot(Succeed())
+		})
+		It("then it should fail something which is not gzipped", func() {
+			Ω(VerifyTarGz(strings.NewReader("plain text"))).ShouldNot(Succeed())
+		})
+	})
+
+	Describe("given a VerifyZipContains check", func() {
+		It("then it should pass a zip holding the required files", func() {
+			archive := fakes.NewZip(map[string]string{"installation.yml": "---", "metadata/cf.yml": "---"})
+			Ω(VerifyZipContains("installation.yml")(bytes.NewReader(archive))).Should(Succeed())
+		})
+		It("then it should fail a zip missing a required file", func() {
+			archive := fakes.NewZip(map[string]string{"metadata/cf.yml": "---"})
+			Ω(VerifyZipContains("installation.yml")(bytes.NewReader(archive))).ShouldNot(Succeed())
+		})
+		It("then it should fail something which is not a zip", func() {
+			Ω(VerifyZipContains("installation.yml")(strings.NewReader("plain text"))).ShouldNot(Succeed())
+		})
+	})
+
+	Describe("given a VerifyMysqlDump check", func() {
+		It("then it should pass a dump with a completion trailer", func() {
+			Ω(VerifyMysqlDump(strings.NewReader(fakes.MysqlDumpContents))).Should(Succeed())
+		})
+		It("then it should pass a large dump with a completion trailer", func() {
+			dump := strings.Repeat("INSERT INTO `t` VALUES (1);\n", 10000) + fakes.MysqlDumpContents
+			Ω(VerifyMysqlDump(strings.NewReader(dump))).Should(Succeed())
+		})
+		It("then it should fail a dump without a completion trailer", func() {
+			dump := fakes.MysqlDumpContents[:len(fakes.MysqlDumpContents)-40]
+			Ω(VerifyMysqlDump(strings.NewReader(dump))).ShouldNot(Succeed())
+		})
+	})
+
+	Describe("given a VerifyPgDump check", func() {
+		It("then it should pass a custom format archive with a table of contents", func() {
+			Ω(VerifyPgDump(bytes.NewReader(fakes.NewPgCustomDump(12)))).Should(Succeed())
+		})
+		It("then it should fail a custom format archive with an empty table of contents", func() {
+			Ω(VerifyPgDump(bytes.NewReader(fakes.NewPgCustomDump(0)))).ShouldNot(Succeed())
+		})
+		It("then it should fail a custom format archive with a truncated header", func() {
+			Ω(VerifyPgDump(bytes.NewReader(fakes.NewPgCustomDump(12)[:20]))).ShouldNot(Succeed())
+		})
+		It("then it should pass a complete plain format dump", func() {
+			Ω(VerifyPgDump(strings.NewReader(fakes.PgPlainDumpContents))).Should(Succeed())
+		})
+		It("then it should fail something which is not a pg dump", func() {
+			Ω(VerifyPgDump(strings.NewReader("plain text that is not a dump"))).ShouldNot(Succeed())
+		})
+	})
+
+	Describe("given a GetArtifactCheck function", func() {
+		It("then it should pick a check based on the kind of systemdump", func() {
+			Ω(GetArtifactCheck(&MysqlInfo{})(strings.NewReader(fakes.MysqlDumpContents))).Should(Succeed())
+			Ω(GetArtifactCheck(&PgInfo{})(strings.NewReader(fakes.MysqlDumpContents))).ShouldNot(Succeed())
+			Ω(GetArtifactCheck(&NfsInfo{})(strings.NewReader(fakes.MysqlDumpContents))).ShouldNot(Succeed())
+			Ω(GetArtifactCheck(&SystemInfo{})(strings.NewReader(fakes.MysqlDumpContents))).Should(Succeed())
+		})
+	})
+})